@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/digest"
+	"github.com/LaulauChau/sws/internal/notify"
+)
+
+func runDigest(args []string) error {
+	fs := flag.NewFlagSet("digest", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "print the digest instead of sending it")
+	html := fs.Bool("html", false, "print the HTML version with --dry-run")
+	date := fs.String("date", "", "day of the digest as YYYY-MM-DD (default today)")
+	opts := addCommonFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := opts.load()
+	location, err := time.LoadLocation(cfg.DigestTimezone)
+	if err != nil {
+		return fmt.Errorf("invalid digest timezone: %v", err)
+	}
+
+	day := time.Now().In(location)
+	if *date != "" {
+		if day, err = time.ParseInLocation("2006-01-02", *date, location); err != nil {
+			return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", *date)
+		}
+	}
+
+	courses, err := fetchCourses(cfg)
+	if err != nil {
+		return err
+	}
+	d := digest.Compile(courses, day)
+
+	if *dryRun {
+		if !*html {
+			fmt.Print(d.Text())
+			return nil
+		}
+		body, err := d.HTML(context.Background())
+		if err != nil {
+			return err
+		}
+		fmt.Println(body)
+		return nil
+	}
+
+	notifier := notify.FromConfig(cfg.Notifiers)
+	if notifier == nil {
+		return fmt.Errorf("no notifier configured, use --dry-run to preview the digest")
+	}
+	msg, err := d.Message(context.Background())
+	if err != nil {
+		return err
+	}
+	return notifier.Notify(context.Background(), msg)
+}
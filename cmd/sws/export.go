@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/client"
+	"github.com/LaulauChau/sws/internal/config"
+	"github.com/LaulauChau/sws/internal/export"
+	"github.com/LaulauChau/sws/internal/models"
+)
+
+func runExport(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing export format, expected one of: ics, csv, jsonl")
+	}
+	format, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("export "+format, flag.ExitOnError)
+	output := fs.String("o", "-", "output file, - for standard output")
+	delimiter := fs.String("d", ",", "CSV delimiter, e.g. ';' for French spreadsheets or 'tab'")
+	bom := fs.Bool("bom", false, "prefix CSV output with a UTF-8 byte order mark")
+	opts := addCommonFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var write func(io.Writer, []models.Course) error
+	switch format {
+	case "ics":
+		write = func(w io.Writer, courses []models.Course) error {
+			return export.WriteICS(w, courses, time.Now())
+		}
+	case "csv":
+		d, err := export.ParseDelimiter(*delimiter)
+		if err != nil {
+			return err
+		}
+		write = func(w io.Writer, courses []models.Course) error {
+			return export.WriteCSV(w, courses, export.CSVOptions{Delimiter: d, BOM: *bom})
+		}
+	case "jsonl":
+		write = export.WriteJSONLines
+	default:
+		return fmt.Errorf("unknown export format %q, expected one of: ics, csv, jsonl", format)
+	}
+
+	courses, err := fetchCourses(opts.load())
+	if err != nil {
+		return err
+	}
+
+	if *output == "-" {
+		return write(os.Stdout, courses)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", *output, err)
+	}
+	if err := write(f, courses); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// fetchCourses retrieves the upcoming courses of the configured account
+func fetchCourses(cfg config.Config) ([]models.Course, error) {
+	return client.NewClient(cfg, nil).LoadNextCourses()
+}
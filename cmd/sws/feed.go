@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/LaulauChau/sws/internal/store"
+)
+
+func runFeedToken(args []string) error {
+	fs := flag.NewFlagSet("feed-token", flag.ExitOnError)
+	opts := addCommonFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := opts.load()
+	if cfg.Profile == "" {
+		return fmt.Errorf("no profile is configured, visitors sign in with their own credentials and have no feed")
+	}
+
+	token := cfg.FeedToken
+	if token == "" {
+		repo, err := store.OpenSQLite(cfg.ProfileDatabasePath())
+		if err != nil {
+			return fmt.Errorf("failed to open history database of profile %s: %v", cfg.Profile, err)
+		}
+		defer repo.Close()
+
+		if token, err = repo.FeedToken(context.Background()); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("/calendar.ics?token=%s\n", token)
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/LaulauChau/sws/internal/client"
+	"github.com/LaulauChau/sws/internal/config"
+)
+
+func runLogin(args []string) error {
+	fs := flag.NewFlagSet("login", flag.ExitOnError)
+	opts := addCommonFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	name := *opts.profile
+	if name == "" {
+		name = config.DefaultProfile
+	}
+
+	path, err := config.CredentialsPath(config.Options{Path: *opts.config})
+	if err != nil {
+		return err
+	}
+
+	// Unlock the existing credentials to keep the other profiles, or choose
+	// the passphrase of a new credentials file
+	var stored config.Config
+	passphrase := os.Getenv("SWS_PASSPHRASE")
+	if _, err := os.Stat(path); err == nil {
+		if passphrase == "" {
+			if passphrase, err = promptSecret("Passphrase: "); err != nil {
+				return err
+			}
+		}
+		if stored.Profiles, err = config.ReadCredentials(path, passphrase); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read credentials: %v", err)
+	} else if passphrase == "" {
+		if passphrase, err = promptNewPassphrase(); err != nil {
+			return err
+		}
+	}
+
+	profile := config.Profile{Name: name}
+	if profile.CodeEtablissement, err = prompt("Code établissement: "); err != nil {
+		return err
+	}
+	if profile.Identifiant, err = prompt("Identifiant: "); err != nil {
+		return err
+	}
+	if profile.PIN, err = promptSecret("PIN: "); err != nil {
+		return err
+	}
+
+	// Check the credentials against Sowesign before storing them
+	c := client.NewClient(config.Config{
+		CodeEtablissement: profile.CodeEtablissement,
+		Identifiant:       profile.Identifiant,
+		PIN:               profile.PIN,
+	}, nil)
+	if err := c.GetToken(); err != nil {
+		return fmt.Errorf("invalid credentials: %v", err)
+	}
+
+	stored.MergeProfile(profile)
+	if err := config.WriteCredentials(path, passphrase, stored.Profiles); err != nil {
+		return err
+	}
+
+	fmt.Printf("Credentials of profile %s saved to %s\n", name, path)
+	return nil
+}
+
+// promptNewPassphrase asks for the passphrase of a new credentials file twice
+func promptNewPassphrase() (string, error) {
+	passphrase, err := promptSecret("New passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("passphrase is required")
+	}
+
+	confirm, err := promptSecret("Confirm passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if confirm != passphrase {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+	return passphrase, nil
+}
@@ -1,38 +1,113 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"log"
-	"net/http"
 	"os"
+	"strings"
 
 	"github.com/LaulauChau/sws/internal/config"
-	"github.com/LaulauChau/sws/internal/handler"
 )
 
+const usage = `Usage: sws [command] [flags]
+
+Commands:
+  serve        start the web server (default)
+  export ics   write the upcoming courses as an iCalendar file
+  export csv   write the upcoming courses as CSV
+  export jsonl write the upcoming courses as JSON lines
+  feed-token   show the path of the calendar feed, including its secret token
+  digest       send the agenda of the day, or preview it with --dry-run
+  config print show the effective configuration with secrets redacted
+  login        store Sowesign credentials in the encrypted credentials file
+  hash-password
+               hash a password for the users of the web UI
+  help         show this help
+
+Every command accepts --config to read a config file other than sws.yaml and
+--profile to select a configured profile.
+`
+
 func main() {
-	cfg, err := config.NewConfig()
+	cmd, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	var err error
+	switch cmd {
+	case "serve":
+		err = runServe(args)
+	case "export":
+		err = runExport(args)
+	case "feed-token":
+		err = runFeedToken(args)
+	case "digest":
+		err = runDigest(args)
+	case "config":
+		err = runConfig(args)
+	case "login":
+		err = runLogin(args)
+	case "hash-password":
+		err = runHashPassword(args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+
 	if err != nil {
-		fmt.Printf("Error loading config: %v\n", err)
-		fmt.Println("\nMake sure you have set up your .env file with the following variables:")
-		fmt.Println("SOWESIGN_CODE_ETABLISSEMENT")
-		fmt.Println("SOWESIGN_IDENTIFIANT")
-		fmt.Println("SOWESIGN_PIN")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+}
+
+// commonFlags holds the flags shared by every command
+type commonFlags struct {
+	config  *string
+	profile *string
+}
 
-	webHandler := handler.NewWebHandler(cfg)
+// addCommonFlags registers the --config and --profile flags
+func addCommonFlags(fs *flag.FlagSet) commonFlags {
+	return commonFlags{
+		config:  fs.String("config", "", "config file (default $SWS_CONFIG or sws.yaml)"),
+		profile: fs.String("profile", "", "profile to use (default the first configured profile)"),
+	}
+}
 
-	// Serve static files
-	fs := http.FileServer(http.Dir("web/static"))
-	http.Handle("/static/", http.StripPrefix("/static/", fs))
+// load loads the configuration with the credentials of the selected
+// profile, explaining the required settings on failure
+func (f commonFlags) load() config.Config {
+	cfg, _ := f.loadWithOptions()
+	return cfg
+}
 
-	// Register routes
-	http.HandleFunc("/", webHandler.HandleIndex)
-	http.HandleFunc("/refresh", webHandler.HandleRefresh)
+// loadWithOptions is like load but also returns the options, including a
+// prompted passphrase, needed to load the configuration again
+func (f commonFlags) loadWithOptions() (config.Config, config.Options) {
+	opts := config.Options{Path: *f.config}
+	cfg, err := config.Load(opts)
+	if errors.Is(err, config.ErrCredentialsLocked) && isTerminal() {
+		if opts.Passphrase, err = promptSecret("Passphrase: "); err == nil {
+			cfg, err = config.Load(opts)
+		}
+	}
+	if err != nil {
+		fmt.Printf("Error loading config:\n%v\n", err)
+		fmt.Println("\nMake sure you have set up your sws.yaml or .env file, see sws.example.yaml and .env.example")
+		os.Exit(1)
+	}
+	if *f.profile == "" {
+		return cfg, opts
+	}
 
-	fmt.Println("Server starting on http://localhost:8080")
-	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Fatal(err)
+	cfg, err = cfg.WithProfile(*f.profile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
+	return cfg, opts
 }
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func runHashPassword(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("hash-password takes no arguments, the password is prompted for")
+	}
+
+	password, err := promptSecret("Password: ")
+	if err != nil {
+		return err
+	}
+	if password == "" {
+		return fmt.Errorf("password is required")
+	}
+	if isTerminal() {
+		confirm, err := promptSecret("Confirm password: ")
+		if err != nil {
+			return err
+		}
+		if confirm != password {
+			return fmt.Errorf("passwords do not match")
+		}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %v", err)
+	}
+	fmt.Println(string(hash))
+	return nil
+}
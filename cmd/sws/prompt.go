@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// stdin is shared by the prompts so that buffered input is not lost
+var stdin = bufio.NewReader(os.Stdin)
+
+// isTerminal reports whether the standard input is interactive
+func isTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// prompt asks for a line of input
+func prompt(label string) (string, error) {
+	fmt.Fprint(os.Stderr, label)
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read input: %v", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// promptSecret asks for a line of input without echoing it when the
+// standard input is a terminal
+func promptSecret(label string) (string, error) {
+	if !isTerminal() {
+		return prompt(label)
+	}
+
+	fmt.Fprint(os.Stderr, label)
+	secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read input: %v", err)
+	}
+	return strings.TrimSpace(string(secret)), nil
+}
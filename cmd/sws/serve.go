@@ -0,0 +1,385 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/account"
+	"github.com/LaulauChau/sws/internal/assets"
+	"github.com/LaulauChau/sws/internal/auth"
+	"github.com/LaulauChau/sws/internal/client"
+	"github.com/LaulauChau/sws/internal/config"
+	"github.com/LaulauChau/sws/internal/diff"
+	"github.com/LaulauChau/sws/internal/digest"
+	"github.com/LaulauChau/sws/internal/handler"
+	"github.com/LaulauChau/sws/internal/health"
+	"github.com/LaulauChau/sws/internal/metrics"
+	"github.com/LaulauChau/sws/internal/middleware"
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/notify"
+	"github.com/LaulauChau/sws/internal/poller"
+	"github.com/LaulauChau/sws/internal/ratelimit"
+	"github.com/LaulauChau/sws/internal/reload"
+	"github.com/LaulauChau/sws/internal/reminder"
+	"github.com/LaulauChau/sws/internal/session"
+	"github.com/LaulauChau/sws/internal/store"
+	"github.com/LaulauChau/sws/internal/tracing"
+	"github.com/LaulauChau/sws/web"
+	"github.com/LaulauChau/sws/web/templates"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "", "address to listen on (default from config, :8080)")
+	dev := fs.Bool("dev", false, "serve static files from web/static without caching, for hot reload")
+	opts := addCommonFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, loadOpts := opts.loadWithOptions()
+	if *addr != "" {
+		cfg.ListenAddr = *addr
+	}
+	names := cfg.ProfileNames()
+	restricted := *opts.profile != ""
+	if restricted {
+		// Serve a single profile
+		names = []string{cfg.Profile}
+	}
+
+	// Calls to Sowesign of every profile and visitor share one bucket, limited
+	// further for the profiles overriding the upstream rate limit
+	upstream := ratelimit.NewLimiter(cfg.RateLimits.Upstream)
+
+	var accounts []*account.Account
+	for _, name := range names {
+		profileCfg, err := cfg.WithProfile(name)
+		if err != nil {
+			return err
+		}
+
+		repo, err := store.OpenSQLite(profileCfg.ProfileDatabasePath())
+		if err != nil {
+			return fmt.Errorf("failed to open history database of profile %s: %v", name, err)
+		}
+		defer repo.Close()
+
+		acc := account.New(profileCfg, repo, upstream)
+		if err := acc.LoadFeedToken(context.Background()); err != nil {
+			return fmt.Errorf("failed to load feed token of profile %s: %v", name, err)
+		}
+		accounts = append(accounts, acc)
+	}
+
+	registry := account.NewRegistry(accounts...)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer shutdownTracing(context.Background())
+
+	promRegistry := prometheus.NewRegistry()
+	promRegistry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	m := metrics.New(promRegistry)
+	for _, acc := range registry.All() {
+		acc.Client.SetMetrics(m)
+		m.AddCache(acc.Name, acc.Client.CacheStats)
+	}
+	authenticator, err := auth.New(cfg.Auth)
+	if err != nil {
+		return err
+	}
+	if !authenticator.Enabled() {
+		fmt.Println("Warning: authentication is disabled, anyone reaching the server sees your courses (see auth in sws.example.yaml)")
+	}
+	webHandler := handler.NewWebHandler(registry, authenticator)
+	limiter := ratelimit.NewKeyed()
+	webHandler.SetRateLimiter(limiter)
+	go limiter.Run(context.Background(), 10*time.Minute)
+
+	// Visitors may sign in with their own credentials, which are only kept in
+	// memory until they log out or stay idle
+	var sessions *session.Manager
+	if cfg.Auth.SowesignSessions() {
+		sessions = session.NewManager(cfg, upstream)
+		sessions.SetMetrics(m)
+		authenticator.SetSessionValidator(func(s auth.Session) bool {
+			_, ok := sessions.Get(s.ID)
+			return ok
+		})
+		webHandler.SetSessions(sessions)
+		go sessions.Run(context.Background(), time.Minute)
+	}
+
+	jobs := newBackgroundJobs(webHandler)
+	for _, acc := range registry.All() {
+		if err := jobs.start(acc); err != nil {
+			return err
+		}
+	}
+
+	// Apply changes of the config and credentials files, or on SIGHUP
+	reloader := reload.NewReloader(cfg, func() (config.Config, error) {
+		next, err := config.Load(loadOpts)
+		if err == nil && *addr != "" {
+			next.ListenAddr = *addr
+		}
+		return next, err
+	})
+	reloader.OnReload(func(old, next config.Config) {
+		if err := authenticator.SetConfig(next.Auth); err != nil {
+			fmt.Printf("Error applying authentication settings: %v\n", err)
+		}
+		if sessions != nil {
+			sessions.SetConfig(next)
+		}
+		if next.RateLimits.Upstream != old.RateLimits.Upstream {
+			ratelimit.SetRate(upstream, next.RateLimits.Upstream, time.Now())
+		}
+		applyConfig(registry, jobs, old, next, restricted)
+	})
+	go reloader.Run(context.Background(), 2*time.Second, loadOpts.ConfigPath(), cfg.CredentialsPath)
+
+	// Readiness tells "sws down" from "Sowesign unreachable": the background
+	// refreshes obtain a token at every interval, pages need cached courses
+	// or Sowesign to fetch them
+	readiness := health.NewChecker(5*time.Second, 10*time.Second)
+	readiness.Add("config", health.Config(reloader.Current, reloader.Err))
+	for _, acc := range registry.All() {
+		maxAge := func() time.Duration { return 2 * acc.Config().RefreshInterval }
+		readiness.Add("token:"+acc.Name, health.Token(acc.Client, maxAge))
+		readiness.Add("courses:"+acc.Name, health.Courses(acc.Client))
+	}
+	if len(registry.All()) == 0 {
+		// Visitors sign in with their own credentials, only Sowesign is checked
+		sowesign := client.NewClient(cfg, upstream)
+		sowesign.SetMetrics(m)
+		readiness.Add("sowesign", health.Upstream(sowesign))
+	}
+
+	// Trace every request and record the latency of every route, spans
+	// carry the request ID of the logs
+	handle := func(route string, h http.Handler) {
+		traced := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("http.request_id", middleware.RequestIDFromContext(r.Context())))
+			h.ServeHTTP(w, r)
+		})
+		http.Handle(route, otelhttp.NewHandler(m.InstrumentHandler(route, traced), route))
+	}
+
+	// Serve the embedded static files under content-hashed names, or the
+	// ones on disk in dev mode
+	static := assets.Dev(web.StaticDir, "/static/")
+	if !*dev {
+		if static, err = assets.Load(web.Static(), "/static/"); err != nil {
+			return err
+		}
+	}
+	templates.SetAssetURL(static.URL)
+	handle("/static/", static)
+	// The service worker controls the pages under its own path
+	handle("/sw.js", static.ServiceWorker("js/sw.js"))
+
+	// Pages require a session and the JSON API an API token or a session,
+	// feeds and exports are protected by their feed token
+	page := func(h http.HandlerFunc) http.Handler {
+		return authenticator.RequireUser(webHandler.RequireAccount(authenticator.CSRF(h)))
+	}
+	api := func(h http.HandlerFunc) http.Handler {
+		return authenticator.RequireAPI(webHandler.RequireAccount(authenticator.CSRF(h)))
+	}
+
+	// Register routes
+	handle("/", page(webHandler.HandleIndex))
+	handle("/login", webHandler.LoginRateLimit(webHandler.HandleLogin))
+	handle("/logout", page(webHandler.HandleLogout))
+	handle("/refresh", page(webHandler.RateLimit(webHandler.HandleRefresh)))
+	handle("/table", page(webHandler.HandleTable))
+	handle("/events", page(webHandler.HandleEvents))
+	handle("/profile", page(webHandler.HandleProfile))
+	handle("/history", page(webHandler.HandleHistory))
+	handle("/changes", page(webHandler.HandleChanges))
+	handle("/calendar", page(webHandler.HandleCalendarView))
+	handle("/courses/{id}", page(webHandler.HandleCourse))
+	handle("/api/courses", api(webHandler.RateLimit(webHandler.HandleAPICourses)))
+	handle("/api/changes", api(webHandler.RateLimit(webHandler.HandleAPIChanges)))
+	handle("/api/courses/{id}", api(webHandler.RateLimit(webHandler.HandleAPICourse)))
+	handle("/calendar.ics", http.HandlerFunc(webHandler.HandleCalendar))
+	handle("/export.csv", http.HandlerFunc(webHandler.HandleExportCSV))
+	handle("/export.jsonl", http.HandlerFunc(webHandler.HandleExportJSONLines))
+	// Probes are open to the orchestrator and left out of traces and metrics
+	http.Handle("/healthz", health.Live())
+	http.Handle("/readyz", readiness.Handler())
+	// Metrics are protected like the JSON API, scrapers use an API token
+	http.Handle("/metrics", authenticator.RequireAPI(m.Handler()))
+
+	server := &http.Server{
+		Addr: cfg.ListenAddr,
+		Handler: middleware.Chain(http.DefaultServeMux,
+			middleware.RequestID,
+			middleware.Logger,
+			middleware.Recover(webHandler.HandleServerError),
+			middleware.SecurityHeaders,
+			middleware.MaxBodySize(cfg.MaxBodySize),
+		),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		// No write timeout, server-sent events stay open
+		IdleTimeout:    2 * time.Minute,
+		MaxHeaderBytes: 64 << 10,
+	}
+
+	scheme := "http"
+	if cfg.TLS.Enabled() {
+		scheme = "https"
+	}
+	fmt.Printf("Server starting on %s://localhost%s\n", scheme, cfg.ListenAddr)
+	if len(registry.All()) > 0 {
+		// The feed token is a secret, it is not written to the logs
+		fmt.Println("Calendar feeds are served at /calendar.ics, run sws feed-token to get their URL")
+	}
+	if cfg.TLS.Enabled() {
+		return server.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+	}
+	return server.ListenAndServe()
+}
+
+// backgroundJobs runs the background jobs of every account, restarting them
+// when its configuration changes
+type backgroundJobs struct {
+	handler *handler.WebHandler
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+func newBackgroundJobs(webHandler *handler.WebHandler) *backgroundJobs {
+	return &backgroundJobs{
+		handler: webHandler,
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+// start (re)starts the background jobs of an account with its current configuration
+func (j *backgroundJobs) start(acc *account.Account) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if cancel, ok := j.cancels[acc.Name]; ok {
+		cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancels[acc.Name] = cancel
+
+	if err := startBackgroundJobs(ctx, acc, j.handler); err != nil {
+		cancel()
+		return err
+	}
+	return nil
+}
+
+// applyConfig swaps a reloaded configuration into the running accounts whose
+// profile changed and restarts their background jobs, which fetch the courses
+// again. Settings that cannot change at runtime are reported
+func applyConfig(registry *account.Registry, jobs *backgroundJobs, old, next config.Config, restricted bool) {
+	for _, acc := range registry.All() {
+		cfg, err := next.WithProfile(acc.Name)
+		if err != nil {
+			fmt.Printf("Profile %s was removed from the configuration, restart to stop serving it\n", acc.Name)
+			continue
+		}
+		if !config.ProfileChanged(old, next, acc.Name) {
+			continue
+		}
+		acc.SetConfig(cfg)
+		if err := jobs.start(acc); err != nil {
+			fmt.Printf("Error restarting background jobs of profile %s: %v\n", acc.Name, err)
+		}
+	}
+
+	if !restricted {
+		for _, name := range next.ProfileNames() {
+			if _, ok := registry.Get(name); !ok {
+				fmt.Printf("Profile %s was added to the configuration, restart to serve it\n", name)
+			}
+		}
+	}
+	if old.ListenAddr != next.ListenAddr {
+		fmt.Println("The listen address changed, restart to apply it")
+	}
+	if old.Auth.Mode != next.Auth.Mode {
+		fmt.Println("The authentication mode changed, restart to apply it")
+	}
+	if old.Tracing != next.Tracing {
+		fmt.Println("The tracing settings changed, restart to apply them")
+	}
+	if old.TLS != next.TLS || old.MaxBodySize != next.MaxBodySize {
+		fmt.Println("The TLS or request size settings changed, restart to apply them")
+	}
+	if old.DatabasePath != next.DatabasePath {
+		fmt.Println("The database path changed, restart to apply it")
+	}
+}
+
+// startBackgroundJobs refreshes the courses of an account in the background,
+// records them and their changes in its history, pushes changes to connected
+// pages and sends its reminders and daily agenda
+func startBackgroundJobs(ctx context.Context, acc *account.Account, webHandler *handler.WebHandler) error {
+	cfg, repo := acc.Config(), acc.Store
+
+	p := poller.NewPoller(acc.Client, cfg.RefreshInterval)
+	p.OnRefresh(func(courses []models.Course) {
+		if err := repo.RecordCourses(context.Background(), courses, time.Now()); err != nil {
+			fmt.Printf("Error recording course history of profile %s: %v\n", acc.Name, err)
+		}
+	})
+	if snapshot, ok, err := repo.LatestSnapshot(context.Background()); err != nil {
+		fmt.Printf("Error loading last course snapshot of profile %s: %v\n", acc.Name, err)
+	} else if ok {
+		p.Seed(snapshot)
+	}
+	p.OnChange(func(prev, next []models.Course) {
+		if changes := diff.Compare(prev, next, time.Now()); len(changes) > 0 {
+			if err := repo.RecordChanges(context.Background(), changes); err != nil {
+				fmt.Printf("Error recording schedule changes of profile %s: %v\n", acc.Name, err)
+			}
+		}
+		webHandler.PublishCourses(acc)
+	})
+	go p.Run(ctx)
+
+	notifier := notify.FromConfig(cfg.Notifiers)
+	if notifier == nil {
+		return nil
+	}
+
+	if len(cfg.ReminderOffsets) > 0 {
+		scheduler := reminder.NewScheduler(acc.Client, notifier, repo, cfg.ReminderOffsets)
+		go scheduler.Run(ctx, 30*time.Second)
+	}
+
+	if cfg.DigestTime != "" {
+		location, err := time.LoadLocation(cfg.DigestTimezone)
+		if err != nil {
+			return fmt.Errorf("invalid digest timezone: %v", err)
+		}
+		job, err := digest.NewJob(acc.Client.LoadNextCourses, notifier, cfg.DigestTime, location)
+		if err != nil {
+			return err
+		}
+		go job.Run(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package account
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"github.com/LaulauChau/sws/internal/client"
+	"github.com/LaulauChau/sws/internal/config"
+	"github.com/LaulauChau/sws/internal/events"
+	"github.com/LaulauChau/sws/internal/store"
+	"golang.org/x/time/rate"
+)
+
+// Account bundles everything that belongs to one Sowesign profile, so that
+// tokens, caches and history never leak from one profile to another
+type Account struct {
+	Name   string
+	Client *client.Client
+	Broker *events.Broker
+	Store  store.Repository
+
+	// storedFeedToken is the random feed token kept in the history database,
+	// used unless the configuration sets one
+	storedFeedToken string
+}
+
+// New creates an account with its own client and event broker for a
+// configuration returned by config.Config.WithProfile. Its calls to Sowesign
+// take from upstream, the bucket shared by every account, nil giving it one
+// of its own
+func New(cfg config.Config, repo store.Repository, upstream *rate.Limiter) *Account {
+	return &Account{
+		Name:   cfg.Profile,
+		Client: client.NewClient(cfg, upstream),
+		Broker: events.NewBroker(),
+		Store:  repo,
+	}
+}
+
+// Config returns the current configuration of the account
+func (a *Account) Config() config.Config {
+	return a.Client.Config()
+}
+
+// LoadFeedToken reads the feed token of the account from its history
+// database, generating it on first use
+func (a *Account) LoadFeedToken(ctx context.Context) error {
+	token, err := a.Store.FeedToken(ctx)
+	if err != nil {
+		return err
+	}
+	a.storedFeedToken = token
+	return nil
+}
+
+// FeedToken returns the secret token of the calendar feed of the account,
+// empty when it has none
+func (a *Account) FeedToken() string {
+	if token := a.Config().FeedToken; token != "" {
+		return token
+	}
+	return a.storedFeedToken
+}
+
+// SetConfig swaps the configuration of the account, e.g. after a reload
+func (a *Account) SetConfig(cfg config.Config) {
+	a.Client.SetConfig(cfg)
+}
+
+// Registry looks up accounts by profile name or feed token
+type Registry struct {
+	accounts []*Account
+	byName   map[string]*Account
+}
+
+// NewRegistry creates a registry, the first account being the default one
+func NewRegistry(accounts ...*Account) *Registry {
+	r := &Registry{byName: make(map[string]*Account, len(accounts))}
+	for _, a := range accounts {
+		if _, ok := r.byName[a.Name]; ok {
+			continue
+		}
+		r.accounts = append(r.accounts, a)
+		r.byName[a.Name] = a
+	}
+	return r
+}
+
+// Get returns the account of the named profile
+func (r *Registry) Get(name string) (*Account, bool) {
+	a, ok := r.byName[name]
+	return a, ok
+}
+
+// Default returns the account shown when no profile is selected
+func (r *Registry) Default() *Account {
+	if len(r.accounts) == 0 {
+		return nil
+	}
+	return r.accounts[0]
+}
+
+// All returns every account, in configuration order
+func (r *Registry) All() []*Account {
+	return r.accounts
+}
+
+// Names returns the profile names of every account, in configuration order
+func (r *Registry) Names() []string {
+	names := make([]string, len(r.accounts))
+	for i, a := range r.accounts {
+		names[i] = a.Name
+	}
+	return names
+}
+
+// ByFeedToken returns the account whose feed token matches
+func (r *Registry) ByFeedToken(token string) (*Account, bool) {
+	if token == "" {
+		return nil, false
+	}
+	for _, a := range r.accounts {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(a.FeedToken())) == 1 {
+			return a, true
+		}
+	}
+	return nil, false
+}
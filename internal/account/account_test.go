@@ -0,0 +1,102 @@
+package account
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/LaulauChau/sws/internal/config"
+	"github.com/LaulauChau/sws/internal/store"
+)
+
+func newTestAccount(t *testing.T, name, pin string) *Account {
+	t.Helper()
+
+	cfg := config.NewTestConfig()
+	cfg.Profiles = []config.Profile{{
+		Name:              name,
+		CodeEtablissement: "test-code",
+		Identifiant:       "test-id",
+		PIN:               pin,
+		FeedToken:         "token-" + name,
+	}}
+	cfg, err := cfg.WithProfile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(cfg, nil, nil)
+}
+
+func TestRegistry(t *testing.T) {
+	work := newTestAccount(t, "work", "1111")
+	school := newTestAccount(t, "school", "2222")
+	r := NewRegistry(work, school, newTestAccount(t, "work", "3333"))
+
+	if got := r.Names(); !slices.Equal(got, []string{"work", "school"}) {
+		t.Errorf("Names() = %v, want [work school]", got)
+	}
+	if got := r.Default(); got != work {
+		t.Errorf("Default() = %v, want work", got.Name)
+	}
+	if got, ok := r.Get("school"); !ok || got != school {
+		t.Errorf("Get(school) = %v, %v, want school account", got, ok)
+	}
+	if _, ok := r.Get("unknown"); ok {
+		t.Error("Get(unknown) should not find an account")
+	}
+
+	if got, ok := r.ByFeedToken("token-school"); !ok || got != school {
+		t.Errorf("ByFeedToken() = %v, %v, want school account", got, ok)
+	}
+	if _, ok := r.ByFeedToken(""); ok {
+		t.Error("ByFeedToken() should reject an empty token")
+	}
+	if _, ok := r.ByFeedToken("token-other"); ok {
+		t.Error("ByFeedToken() should reject an unknown token")
+	}
+}
+
+func TestNew_IsolatesClients(t *testing.T) {
+	work := newTestAccount(t, "work", "1111")
+	school := newTestAccount(t, "school", "2222")
+
+	if work.Client == school.Client || work.Broker == school.Broker {
+		t.Error("accounts should not share a client or a broker")
+	}
+	if work.Config().PIN != "1111" || school.Config().PIN != "2222" {
+		t.Errorf("accounts use PINs %q and %q, want 1111 and 2222", work.Config().PIN, school.Config().PIN)
+	}
+}
+
+func TestAccount_FeedToken(t *testing.T) {
+	repo, err := store.OpenSQLite(filepath.Join(t.TempDir(), "sws.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	cfg := config.NewTestConfig()
+	cfg.FeedToken = ""
+	acc := New(cfg, repo, nil)
+	if got := acc.FeedToken(); got != "" {
+		t.Errorf("FeedToken() before LoadFeedToken() = %q, want empty", got)
+	}
+	if err := acc.LoadFeedToken(context.Background()); err != nil {
+		t.Fatalf("LoadFeedToken() error = %v", err)
+	}
+	stored := acc.FeedToken()
+	if len(stored) != 32 {
+		t.Errorf("FeedToken() = %q, want a random 32 character token", stored)
+	}
+	if got, ok := NewRegistry(acc).ByFeedToken(stored); !ok || got != acc {
+		t.Errorf("ByFeedToken() = %v, %v, want the account", got, ok)
+	}
+
+	// A configured token takes precedence
+	cfg.FeedToken = "configured-token"
+	acc.SetConfig(cfg)
+	if got := acc.FeedToken(); got != "configured-token" {
+		t.Errorf("FeedToken() = %q, want configured-token", got)
+	}
+}
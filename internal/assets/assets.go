@@ -0,0 +1,256 @@
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// hashLength is the number of hex digits of the content hash in file names
+const hashLength = 10
+
+// compressible lists the extensions of the files worth compressing
+var compressible = map[string]bool{
+	".css": true, ".js": true, ".json": true, ".svg": true,
+	".html": true, ".txt": true, ".webmanifest": true,
+}
+
+// contentTypes lists the types of the extensions unknown to the mime package
+var contentTypes = map[string]string{
+	".webmanifest": "application/manifest+json",
+}
+
+// asset is a static file with its precompressed variants
+type asset struct {
+	hashedName  string
+	hash        string
+	contentType string
+	data        []byte
+	gzip        []byte
+	brotli      []byte
+}
+
+// Assets serves static files under a URL prefix. Embedded files get
+// content-hashed names cached for a year, and are precompressed with gzip
+// and brotli. In dev mode files are read from disk at every request
+type Assets struct {
+	prefix string
+	// dir is the directory served in dev mode
+	dir    string
+	byName map[string]*asset
+	// byPath finds assets by their plain or hashed name
+	byPath map[string]*asset
+}
+
+// Load reads, hashes and compresses every file of fsys, served under prefix
+func Load(fsys fs.FS, prefix string) (*Assets, error) {
+	a := &Assets{
+		prefix: prefix,
+		byName: make(map[string]*asset),
+		byPath: make(map[string]*asset),
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		as, err := newAsset(name, data)
+		if err != nil {
+			return fmt.Errorf("failed to compress %s: %v", name, err)
+		}
+		a.byName[name] = as
+		a.byPath[name] = as
+		a.byPath[as.hashedName] = as
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load static files: %v", err)
+	}
+	return a, nil
+}
+
+// Dev serves the files of dir from disk under prefix, without caching, so
+// that changes show up on reload
+func Dev(dir, prefix string) *Assets {
+	return &Assets{prefix: prefix, dir: dir}
+}
+
+// newAsset hashes a file and compresses it when that makes it smaller
+func newAsset(name string, data []byte) (*asset, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])[:hashLength]
+	ext := path.Ext(name)
+
+	contentType := contentTypes[ext]
+	if contentType == "" {
+		contentType = mime.TypeByExtension(ext)
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	as := &asset{
+		hashedName:  strings.TrimSuffix(name, ext) + "." + hash + ext,
+		hash:        hash,
+		contentType: contentType,
+		data:        data,
+	}
+	if !compressible[ext] {
+		return as, nil
+	}
+
+	var buf bytes.Buffer
+	gz, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := gz.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	if buf.Len() < len(data) {
+		as.gzip = bytes.Clone(buf.Bytes())
+	}
+
+	buf.Reset()
+	br := brotli.NewWriterLevel(&buf, brotli.BestCompression)
+	if _, err := br.Write(data); err != nil {
+		return nil, err
+	}
+	if err := br.Close(); err != nil {
+		return nil, err
+	}
+	if buf.Len() < len(data) {
+		as.brotli = bytes.Clone(buf.Bytes())
+	}
+	return as, nil
+}
+
+// URL returns the URL of a static file, with its content hash unless in dev
+// mode or unknown
+func (a *Assets) URL(name string) string {
+	if as, ok := a.byName[name]; ok {
+		return a.prefix + as.hashedName
+	}
+	return a.prefix + name
+}
+
+// ServeHTTP serves a static file by its plain or hashed name
+func (a *Assets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, a.prefix)
+	if a.dir != "" {
+		w.Header().Set("Cache-Control", "no-cache")
+		http.ServeFileFS(w, r, os.DirFS(a.dir), name)
+		return
+	}
+
+	as, ok := a.byPath[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Hashed names never change content, plain ones are revalidated
+	if name == as.hashedName {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+
+	data, etag := as.data, as.hash
+	if as.gzip != nil || as.brotli != nil {
+		w.Header().Add("Vary", "Accept-Encoding")
+		accept := r.Header.Get("Accept-Encoding")
+		switch {
+		case as.brotli != nil && acceptsEncoding(accept, "br"):
+			data, etag = as.brotli, as.hash+"-br"
+			w.Header().Set("Content-Encoding", "br")
+		case as.gzip != nil && acceptsEncoding(accept, "gzip"):
+			data, etag = as.gzip, as.hash+"-gz"
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+	}
+	w.Header().Set("Content-Type", as.contentType)
+	w.Header().Set("ETag", strconv.Quote(etag))
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+}
+
+// URLs returns the content-hashed URLs of every file but the excluded ones,
+// sorted. It is empty in dev mode
+func (a *Assets) URLs(exclude ...string) []string {
+	urls := make([]string, 0, len(a.byName))
+	for name, as := range a.byName {
+		if !slices.Contains(exclude, name) {
+			urls = append(urls, a.prefix+as.hashedName)
+		}
+	}
+	slices.Sort(urls)
+	return urls
+}
+
+// ServiceWorker serves the named script at the root of the site, so that it
+// controls every page, declaring the ASSETS variable with the URLs of the
+// files to precache. The script changes with every file, which makes
+// browsers install the new version
+func (a *Assets) ServiceWorker(name string) http.Handler {
+	assets, _ := json.Marshal(a.URLs(name))
+	header := []byte("var ASSETS = " + string(assets) + ";\n")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var script []byte
+		if a.dir != "" {
+			data, err := os.ReadFile(filepath.Join(a.dir, filepath.FromSlash(name)))
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			script = data
+		} else if as, ok := a.byName[name]; ok {
+			script = as.data
+		} else {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(append(slices.Clip(header), script...)))
+	})
+}
+
+// acceptsEncoding reports whether an Accept-Encoding header allows a coding
+func acceptsEncoding(header, coding string) bool {
+	for _, item := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), coding) {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimSpace(params), "=")
+		if !ok || strings.TrimSpace(key) != "q" {
+			return true
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return err == nil && q > 0
+	}
+	return false
+}
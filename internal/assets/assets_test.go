@@ -0,0 +1,198 @@
+package assets
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andybalholm/brotli"
+)
+
+var script = strings.Repeat("console.log('sws');\n", 100)
+
+func newTestAssets(t *testing.T) *Assets {
+	t.Helper()
+
+	a, err := Load(fstest.MapFS{
+		"js/app.js":    {Data: []byte(script)},
+		"img/logo.png": {Data: []byte("\x89PNG\r\n\x1a\n")},
+	}, "/static/")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return a
+}
+
+func serve(a *Assets, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAssets_URL(t *testing.T) {
+	a := newTestAssets(t)
+
+	url := a.URL("js/app.js")
+	if !strings.HasPrefix(url, "/static/js/app.") || !strings.HasSuffix(url, ".js") || len(url) != len("/static/js/app.js")+hashLength+1 {
+		t.Errorf("URL() = %q, want a content-hashed name", url)
+	}
+	if got := a.URL("js/missing.js"); got != "/static/js/missing.js" {
+		t.Errorf("URL() of an unknown file = %q, want the plain name", got)
+	}
+}
+
+func TestAssets_ServeHTTP(t *testing.T) {
+	a := newTestAssets(t)
+	hashed := a.URL("js/app.js")
+
+	tests := []struct {
+		name         string
+		path         string
+		encoding     string
+		wantStatus   int
+		wantCache    string
+		wantEncoding string
+	}{
+		{"hashed name", hashed, "", http.StatusOK, "public, max-age=31536000, immutable", ""},
+		{"plain name", "/static/js/app.js", "", http.StatusOK, "no-cache", ""},
+		{"gzip", hashed, "gzip, deflate", http.StatusOK, "public, max-age=31536000, immutable", "gzip"},
+		{"brotli preferred", hashed, "gzip, br", http.StatusOK, "public, max-age=31536000, immutable", "br"},
+		{"brotli refused", hashed, "br;q=0, gzip", http.StatusOK, "public, max-age=31536000, immutable", "gzip"},
+		{"not compressible", a.URL("img/logo.png"), "br", http.StatusOK, "public, max-age=31536000, immutable", ""},
+		{"unknown file", "/static/js/missing.js", "", http.StatusNotFound, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(a, tt.path, http.Header{"Accept-Encoding": {tt.encoding}})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("ServeHTTP() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			if got := rec.Header().Get("Cache-Control"); got != tt.wantCache {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.wantCache)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+
+			var body io.Reader = rec.Body
+			switch tt.wantEncoding {
+			case "gzip":
+				gz, err := gzip.NewReader(body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = gz
+			case "br":
+				body = brotli.NewReader(body)
+			}
+			data, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.HasSuffix(tt.path, ".js") && string(data) != script {
+				t.Error("ServeHTTP() body does not match the file")
+			}
+		})
+	}
+}
+
+func TestAssets_ETag(t *testing.T) {
+	a := newTestAssets(t)
+	hashed := a.URL("js/app.js")
+
+	plain := serve(a, hashed, nil).Header().Get("ETag")
+	gz := serve(a, hashed, http.Header{"Accept-Encoding": {"gzip"}}).Header().Get("ETag")
+	if plain == "" || plain == gz {
+		t.Errorf("ETag = %q and %q for gzip, want distinct tags", plain, gz)
+	}
+
+	rec := serve(a, hashed, http.Header{"If-None-Match": {plain}})
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("ServeHTTP() with a matching If-None-Match = %d, want 304", rec.Code)
+	}
+}
+
+func TestAssets_ServiceWorker(t *testing.T) {
+	a, err := Load(fstest.MapFS{
+		"js/app.js":            {Data: []byte(script)},
+		"js/sw.js":             {Data: []byte("self.skipWaiting();")},
+		"manifest.webmanifest": {Data: []byte(`{"name":"sws"}`)},
+	}, "/static/")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	a.ServiceWorker("js/sw.js").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sw.js", nil))
+	want := `var ASSETS = ["` + a.URL("js/app.js") + `","` + a.URL("manifest.webmanifest") + `"];` + "\nself.skipWaiting();"
+	if rec.Body.String() != want {
+		t.Errorf("ServiceWorker() = %q, want %q", rec.Body.String(), want)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache", got)
+	}
+
+	rec = serve(a, a.URL("manifest.webmanifest"), nil)
+	if got := rec.Header().Get("Content-Type"); got != "application/manifest+json" {
+		t.Errorf("Content-Type of the manifest = %q, want application/manifest+json", got)
+	}
+}
+
+func TestDev(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "js"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "js", "app.js"), []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := Dev(dir, "/static/")
+	if got := a.URL("js/app.js"); got != "/static/js/app.js" {
+		t.Errorf("URL() = %q, want the plain name", got)
+	}
+
+	// Changes on disk are served right away
+	if err := os.WriteFile(filepath.Join(dir, "js", "app.js"), []byte("v2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rec := serve(a, "/static/js/app.js", nil)
+	if rec.Body.String() != "v2" || rec.Header().Get("Cache-Control") != "no-cache" {
+		t.Errorf("ServeHTTP() = %q with Cache-Control %q, want v2 with no-cache", rec.Body.String(), rec.Header().Get("Cache-Control"))
+	}
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		coding string
+		want   bool
+	}{
+		{"gzip, deflate, br", "br", true},
+		{"gzip", "br", false},
+		{"GZIP", "gzip", true},
+		{"br;q=0.5", "br", true},
+		{"br; q=0", "br", false},
+		{"", "gzip", false},
+	}
+
+	for _, tt := range tests {
+		if got := acceptsEncoding(tt.header, tt.coding); got != tt.want {
+			t.Errorf("acceptsEncoding(%q, %q) = %v, want %v", tt.header, tt.coding, got, tt.want)
+		}
+	}
+}
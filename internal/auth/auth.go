@@ -0,0 +1,295 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// SessionCookie holds the signed session of a logged in user
+	SessionCookie = "sws_session"
+	// LoginCSRFCookie holds the random value the CSRF token of the login
+	// form is signed from, before there is a session
+	LoginCSRFCookie = "sws_login_csrf"
+	// CSRFHeader and CSRFField carry the CSRF token of state-changing requests
+	CSRFHeader = "X-CSRF-Token"
+	CSRFField  = "csrf_token"
+)
+
+// dummyHash is compared against when the username is unknown, so that
+// logins take the same time whether the user exists or not
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("sws-dummy-password"), bcrypt.DefaultCost)
+
+// Session identifies a logged in user
+type Session struct {
+	ID       string    `json:"id"`
+	Username string    `json:"u"`
+	Expires  time.Time `json:"exp"`
+}
+
+// Authenticator checks passwords, API tokens and session cookies
+type Authenticator struct {
+	mu        sync.RWMutex
+	cfg       config.Auth
+	secret    []byte
+	generated bool
+	revoked   map[string]time.Time
+	validate  func(Session) bool
+}
+
+// New creates an authenticator, generating a session secret when none is
+// configured
+func New(cfg config.Auth) (*Authenticator, error) {
+	a := &Authenticator{revoked: make(map[string]time.Time)}
+	if err := a.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// SetConfig swaps the authentication settings, e.g. after a reload. A
+// generated session secret is kept so that sessions survive reloads
+func (a *Authenticator) SetConfig(cfg config.Auth) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch {
+	case cfg.SessionSecret != "":
+		a.secret, a.generated = []byte(cfg.SessionSecret), false
+	case a.secret == nil || !a.generated:
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return fmt.Errorf("failed to generate session secret: %w", err)
+		}
+		a.secret, a.generated = secret, true
+	}
+	a.cfg = cfg
+	return nil
+}
+
+// Enabled reports whether requests must be authenticated
+func (a *Authenticator) Enabled() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.cfg.Enabled()
+}
+
+// CheckPassword reports whether the password matches the user's hash
+func (a *Authenticator) CheckPassword(username, password string) bool {
+	a.mu.RLock()
+	hash := dummyHash
+	found := false
+	for _, u := range a.cfg.Users {
+		if u.Username == username {
+			hash, found = []byte(u.PasswordHash), true
+			break
+		}
+	}
+	a.mu.RUnlock()
+
+	err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+	return found && err == nil
+}
+
+// CheckAPIToken reports whether the token is one of the configured API tokens
+func (a *Authenticator) CheckAPIToken(token string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	valid := false
+	for _, t := range a.cfg.APITokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			valid = true
+		}
+	}
+	return token != "" && valid
+}
+
+// NewSession starts a session for the user
+func (a *Authenticator) NewSession(username string) (Session, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return Session{}, fmt.Errorf("failed to generate session id: %w", err)
+	}
+
+	a.mu.RLock()
+	ttl := a.cfg.SessionTTL
+	a.mu.RUnlock()
+
+	return Session{
+		ID:       hex.EncodeToString(id),
+		Username: username,
+		Expires:  time.Now().Add(ttl).Truncate(time.Second),
+	}, nil
+}
+
+// SetSessionCookie stores the signed session in an HttpOnly cookie
+func (a *Authenticator) SetSessionCookie(w http.ResponseWriter, r *http.Request, s Session) error {
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	value := base64.RawURLEncoding.EncodeToString(payload)
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    value + "." + a.sign("session:"+value),
+		Path:     "/",
+		Expires:  s.Expires,
+		HttpOnly: true,
+		Secure:   a.secureCookies(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// ClearSessionCookie removes the session cookie
+func (a *Authenticator) ClearSessionCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   a.secureCookies(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// secureCookies reports whether cookies must only be sent over HTTPS, on
+// TLS connections and behind a TLS-terminating proxy when configured
+func (a *Authenticator) secureCookies(r *http.Request) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return r.TLS != nil || a.cfg.SecureCookies
+}
+
+// LoginCSRFToken returns the CSRF token of the login form, setting the
+// cookie it is bound to when the request has none
+func (a *Authenticator) LoginCSRFToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if c, err := r.Cookie(LoginCSRFCookie); err == nil && c.Value != "" {
+		return a.sign("login:" + c.Value), nil
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate login CSRF token: %w", err)
+	}
+	value := hex.EncodeToString(nonce)
+	http.SetCookie(w, &http.Cookie{
+		Name:     LoginCSRFCookie,
+		Value:    value,
+		Path:     LoginPath,
+		HttpOnly: true,
+		Secure:   a.secureCookies(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	return a.sign("login:" + value), nil
+}
+
+// CheckLoginCSRF reports whether a login form carries the CSRF token of its
+// cookie
+func (a *Authenticator) CheckLoginCSRF(r *http.Request) bool {
+	c, err := r.Cookie(LoginCSRFCookie)
+	if err != nil || c.Value == "" {
+		return false
+	}
+	return hmac.Equal([]byte(r.PostFormValue(CSRFField)), []byte(a.sign("login:"+c.Value)))
+}
+
+// SessionFromRequest returns the valid session of the request cookie
+func (a *Authenticator) SessionFromRequest(r *http.Request) (Session, bool) {
+	c, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return Session{}, false
+	}
+
+	value, signature, ok := strings.Cut(c.Value, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(a.sign("session:"+value))) {
+		return Session{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return Session{}, false
+	}
+
+	var s Session
+	if err := json.Unmarshal(payload, &s); err != nil || !time.Now().Before(s.Expires) {
+		return Session{}, false
+	}
+
+	a.mu.RLock()
+	_, revoked := a.revoked[s.ID]
+	validate := a.validate
+	a.mu.RUnlock()
+	if revoked || (validate != nil && !validate(s)) {
+		return Session{}, false
+	}
+	return s, true
+}
+
+// SetSessionValidator adds a check of otherwise valid sessions, e.g. to end
+// sessions whose server-side state was evicted
+func (a *Authenticator) SetSessionValidator(validate func(Session) bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.validate = validate
+}
+
+// Revoke invalidates a session before it expires, e.g. on logout
+func (a *Authenticator) Revoke(s Session) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	for id, expires := range a.revoked {
+		if now.After(expires) {
+			delete(a.revoked, id)
+		}
+	}
+	a.revoked[s.ID] = s.Expires
+}
+
+// CSRFToken returns the CSRF token bound to a session
+func (a *Authenticator) CSRFToken(s Session) string {
+	return a.sign("csrf:" + s.ID)
+}
+
+// sign returns the HMAC of a message with the session secret
+func (a *Authenticator) sign(message string) string {
+	a.mu.RLock()
+	mac := hmac.New(sha256.New, a.secret)
+	a.mu.RUnlock()
+
+	mac.Write([]byte(message))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+type sessionKey struct{}
+
+// WithSession stores the session of an authenticated request in the context
+func WithSession(ctx context.Context, s Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// FromContext returns the session stored by the middlewares, if the request
+// was authenticated with a session cookie
+func FromContext(ctx context.Context) (Session, bool) {
+	s, ok := ctx.Value(sessionKey{}).(Session)
+	return s, ok
+}
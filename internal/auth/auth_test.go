@@ -0,0 +1,327 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestAuthenticator(t *testing.T) *Authenticator {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := New(config.Auth{
+		Users:      []config.User{{Username: "alice", PasswordHash: string(hash)}},
+		SessionTTL: time.Hour,
+		BasicAuth:  true,
+		APITokens:  []string{"0123456789abcdef"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// sessionRequest returns a request carrying a session cookie for alice
+func sessionRequest(t *testing.T, a *Authenticator, method, target string) (*http.Request, Session) {
+	t.Helper()
+
+	s, err := a.NewSession("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	if err := a.SetSessionCookie(rec, httptest.NewRequest(http.MethodGet, "/", nil), s); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(method, target, nil)
+	for _, c := range rec.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	return r, s
+}
+
+func TestAuthenticator_CheckPassword(t *testing.T) {
+	a := newTestAuthenticator(t)
+
+	if !a.CheckPassword("alice", "secret") {
+		t.Error("CheckPassword() should accept the right password")
+	}
+	if a.CheckPassword("alice", "wrong") || a.CheckPassword("bob", "secret") {
+		t.Error("CheckPassword() should reject a wrong password or user")
+	}
+	if !a.CheckAPIToken("0123456789abcdef") || a.CheckAPIToken("") || a.CheckAPIToken("other") {
+		t.Error("CheckAPIToken() should only accept configured tokens")
+	}
+}
+
+func TestAuthenticator_Session(t *testing.T) {
+	a := newTestAuthenticator(t)
+	r, s := sessionRequest(t, a, http.MethodGet, "/")
+
+	got, ok := a.SessionFromRequest(r)
+	if !ok || got.ID != s.ID || got.Username != "alice" {
+		t.Fatalf("SessionFromRequest() = %+v, %v, want the session of alice", got, ok)
+	}
+
+	// Tampered cookies are rejected
+	c, _ := r.Cookie(SessionCookie)
+	tampered := httptest.NewRequest(http.MethodGet, "/", nil)
+	tampered.AddCookie(&http.Cookie{Name: SessionCookie, Value: "x" + c.Value})
+	if _, ok := a.SessionFromRequest(tampered); ok {
+		t.Error("SessionFromRequest() should reject a tampered cookie")
+	}
+
+	// Sessions signed with another secret are rejected
+	other := newTestAuthenticator(t)
+	if _, ok := other.SessionFromRequest(r); ok {
+		t.Error("SessionFromRequest() should reject a session signed with another secret")
+	}
+
+	// Sessions rejected by the validator are rejected
+	a.SetSessionValidator(func(Session) bool { return false })
+	if _, ok := a.SessionFromRequest(r); ok {
+		t.Error("SessionFromRequest() should reject a session refused by the validator")
+	}
+	a.SetSessionValidator(nil)
+
+	a.Revoke(s)
+	if _, ok := a.SessionFromRequest(r); ok {
+		t.Error("SessionFromRequest() should reject a revoked session")
+	}
+}
+
+func TestAuthenticator_SecureCookies(t *testing.T) {
+	a := newTestAuthenticator(t)
+	s, err := a.NewSession("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secure := func() bool {
+		rec := httptest.NewRecorder()
+		if err := a.SetSessionCookie(rec, httptest.NewRequest(http.MethodGet, "/", nil), s); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Result().Cookies()[0].Secure
+	}
+
+	if secure() {
+		t.Error("SetSessionCookie() over plain HTTP should not be Secure by default")
+	}
+
+	// Behind a TLS-terminating proxy
+	cfg := a.cfg
+	cfg.SecureCookies = true
+	if err := a.SetConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !secure() {
+		t.Error("SetSessionCookie() should be Secure with secure cookies enabled")
+	}
+}
+
+func TestAuthenticator_LoginCSRF(t *testing.T) {
+	a := newTestAuthenticator(t)
+
+	rec := httptest.NewRecorder()
+	token, err := a.LoginCSRFToken(rec, httptest.NewRequest(http.MethodGet, LoginPath, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != LoginCSRFCookie || !cookies[0].HttpOnly {
+		t.Fatalf("LoginCSRFToken() cookies = %+v, want an HttpOnly %s cookie", cookies, LoginCSRFCookie)
+	}
+
+	post := func(token string, cookie *http.Cookie) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, LoginPath, strings.NewReader(url.Values{CSRFField: {token}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		return r
+	}
+	if !a.CheckLoginCSRF(post(token, cookies[0])) {
+		t.Error("CheckLoginCSRF() should accept the token of the cookie")
+	}
+	forged := &http.Cookie{Name: LoginCSRFCookie, Value: "forged"}
+	if a.CheckLoginCSRF(post(token, nil)) || a.CheckLoginCSRF(post("", cookies[0])) || a.CheckLoginCSRF(post(token, forged)) {
+		t.Error("CheckLoginCSRF() should reject a missing token or cookie, or another cookie")
+	}
+
+	// The form keeps the token of an existing cookie
+	r := httptest.NewRequest(http.MethodGet, LoginPath, nil)
+	r.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	if again, err := a.LoginCSRFToken(rec, r); err != nil || again != token || len(rec.Result().Cookies()) != 0 {
+		t.Errorf("LoginCSRFToken() = %q, %v, want the same token without a new cookie", again, err)
+	}
+}
+
+func TestRequireUser(t *testing.T) {
+	a := newTestAuthenticator(t)
+	handler := a.RequireUser(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s, ok := FromContext(r.Context()); ok {
+			w.Write([]byte(s.Username))
+		}
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/history?name=x", nil))
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/login?next="+url.QueryEscape("/history?name=x") {
+		t.Errorf("anonymous request got %d to %q, want a redirect to the login page", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/table", nil)
+	r.Header.Set("HX-Request", "true")
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("HX-Redirect") == "" {
+		t.Errorf("anonymous htmx request got %d, want 401 with HX-Redirect", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	r, _ = sessionRequest(t, a, http.MethodGet, "/")
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK || rec.Body.String() != "alice" {
+		t.Errorf("session request got %d %q, want 200 alice", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth("alice", "secret")
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Errorf("basic auth request got %d, want 200", rec.Code)
+	}
+}
+
+func TestRequireAPI(t *testing.T) {
+	a := newTestAuthenticator(t)
+	handler := a.RequireAPI(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{name: "no credentials", want: http.StatusUnauthorized},
+		{name: "valid token", authorization: "Bearer 0123456789abcdef", want: http.StatusOK},
+		{name: "invalid token", authorization: "Bearer nope", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/courses", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	a := newTestAuthenticator(t)
+	handler := a.RequireUser(a.CSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	r, s := sessionRequest(t, a, http.MethodPost, "/refresh")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("POST without CSRF token got %d, want 403", rec.Code)
+	}
+
+	r, s = sessionRequest(t, a, http.MethodPost, "/refresh")
+	r.Header.Set(CSRFHeader, a.CSRFToken(s))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Errorf("POST with CSRF header got %d, want 200", rec.Code)
+	}
+
+	r, s = sessionRequest(t, a, http.MethodPost, "/logout")
+	form := url.Values{CSRFField: {a.CSRFToken(s)}}
+	r.Body = io.NopCloser(strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Errorf("POST with CSRF form field got %d, want 200", rec.Code)
+	}
+
+	r, _ = sessionRequest(t, a, http.MethodGet, "/")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET got %d, want 200", rec.Code)
+	}
+}
+
+func TestCSRF_WithoutSession(t *testing.T) {
+	a := newTestAuthenticator(t)
+	handler := a.RequireAPI(a.CSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		bearer  bool
+		want    int
+	}{
+		{name: "script", want: http.StatusOK},
+		{name: "same origin", headers: map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "http://example.com"}, want: http.StatusOK},
+		{name: "same origin without fetch metadata", headers: map[string]string{"Origin": "http://example.com"}, want: http.StatusOK},
+		{name: "cross site", headers: map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example"}, want: http.StatusForbidden},
+		{name: "same site", headers: map[string]string{"Sec-Fetch-Site": "same-site"}, want: http.StatusForbidden},
+		{name: "cross origin without fetch metadata", headers: map[string]string{"Origin": "https://evil.example"}, want: http.StatusForbidden},
+		{name: "API token", headers: map[string]string{"Origin": "https://evil.example"}, bearer: true, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "http://example.com/refresh", nil)
+			if tt.bearer {
+				r.Header.Set("Authorization", "Bearer 0123456789abcdef")
+			} else {
+				r.SetBasicAuth("alice", "secret")
+			}
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			if rec.Code != tt.want {
+				t.Errorf("POST got %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	a, err := New(config.Auth{SessionTTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := a.RequireUser(a.CSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/refresh", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("request with authentication disabled got %d, want 200", rec.Code)
+	}
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// LoginPath is where unauthenticated page requests are redirected to
+const LoginPath = "/login"
+
+// authenticate checks the session cookie, then HTTP basic auth when enabled,
+// returning the request with the session in its context
+func (a *Authenticator) authenticate(r *http.Request) (*http.Request, bool) {
+	if s, ok := a.SessionFromRequest(r); ok {
+		return r.WithContext(WithSession(r.Context(), s)), true
+	}
+
+	a.mu.RLock()
+	basicAuth := a.cfg.BasicAuth
+	a.mu.RUnlock()
+	if username, password, ok := r.BasicAuth(); ok && basicAuth {
+		return r, a.CheckPassword(username, password)
+	}
+	return r, false
+}
+
+// RequireUser lets logged in users through and sends everyone else to the
+// login page
+func (a *Authenticator) RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r, ok := a.authenticate(r)
+		if ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		target := LoginPath + "?next=" + url.QueryEscape(r.URL.RequestURI())
+		if r.Header.Get("HX-Request") == "true" {
+			// Let htmx reload the whole page rather than swapping the login form
+			w.Header().Set("HX-Redirect", target)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, target, http.StatusSeeOther)
+	})
+}
+
+// RequireAPI lets requests with an API token, a session or valid basic auth
+// credentials through, answering a JSON error otherwise
+func (a *Authenticator) RequireAPI(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			if a.CheckAPIToken(token) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		} else if r, ok := a.authenticate(r); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("WWW-Authenticate", `Bearer realm="sws"`)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "Authentication required"})
+	})
+}
+
+// CSRF rejects state-changing requests authenticated by a session cookie
+// that do not carry the CSRF token of the session, and cross-origin ones
+// without a session, e.g. authenticated by basic auth credentials that
+// browsers attach to forged requests too. Only requests with an API token,
+// which browsers never send on their own, are exempt. It must be wrapped by
+// RequireUser or RequireAPI
+func (a *Authenticator) CSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		s, ok := FromContext(r.Context())
+		if !ok {
+			if !sameOrigin(r) {
+				http.Error(w, "Cross-origin request rejected", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := r.Header.Get(CSRFHeader)
+		if token == "" {
+			token = r.PostFormValue(CSRFField)
+		}
+		if !hmac.Equal([]byte(token), []byte(a.CSRFToken(s))) {
+			http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sameOrigin reports whether a request comes from a page of this server or
+// from a client other than a browser, browsers sending Sec-Fetch-Site or
+// Origin with every cross-origin request
+func sameOrigin(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return true
+	case "":
+	default:
+		return false
+	}
+
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
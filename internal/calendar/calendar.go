@@ -0,0 +1,242 @@
+package calendar
+
+import (
+	"slices"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/service"
+)
+
+const (
+	// defaultFirstHour and defaultLastHour bound the hours shown by a week,
+	// widened to fit earlier or later courses
+	defaultFirstHour = 8
+	defaultLastHour  = 19
+	// defaultLength is the length of courses without an end time
+	defaultLength = time.Hour
+)
+
+// Block is a course placed in the day column of a week
+type Block struct {
+	Course models.Course
+	// Start and End are in Paris
+	Start time.Time
+	End   time.Time
+	// Top and Height are percentages of the height of the hours shown
+	Top    float64
+	Height float64
+	// Lane and Lanes split the width of the column between overlapping courses
+	Lane  int
+	Lanes int
+}
+
+// WeekDay is a day column of a week
+type WeekDay struct {
+	// Date is midnight in Paris
+	Date   time.Time
+	Today  bool
+	Blocks []Block
+}
+
+// Week is the courses of a week from Monday to Sunday
+type Week struct {
+	// Start is Monday at midnight in Paris
+	Start time.Time
+	Days  []WeekDay
+	// FirstHour and LastHour are the hours shown, LastHour included
+	FirstHour int
+	LastHour  int
+	// Now is the position of the current time in percent of the height of the
+	// hours shown, negative when it is not in the week nor the hours shown
+	Now float64
+}
+
+// Hours returns the hours shown by the week
+func (w Week) Hours() []int {
+	hours := make([]int, 0, w.LastHour-w.FirstHour+1)
+	for h := w.FirstHour; h <= w.LastHour; h++ {
+		hours = append(hours, h)
+	}
+	return hours
+}
+
+// Prev and Next return a day of the previous and next weeks
+func (w Week) Prev() time.Time { return w.Start.AddDate(0, 0, -7) }
+func (w Week) Next() time.Time { return w.Start.AddDate(0, 0, 7) }
+
+// Day returns midnight in Paris of the day of t
+func Day(t time.Time) time.Time {
+	t = t.In(service.ParisLocation())
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// Monday returns midnight in Paris of the Monday of the week of t
+func Monday(t time.Time) time.Time {
+	day := Day(t)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+// span returns the start and end of a course in Paris, ok being false when
+// its start cannot be parsed
+func span(course models.Course) (start, end time.Time, ok bool) {
+	start, end = service.CourseStart(course), service.CourseEnd(course)
+	if start.IsZero() {
+		return time.Time{}, time.Time{}, false
+	}
+	if !end.After(start) {
+		end = start.Add(defaultLength)
+	}
+	return start.In(service.ParisLocation()), end.In(service.ParisLocation()), true
+}
+
+// NewWeek places the courses of the week of day, marking now
+func NewWeek(courses []models.Course, day, now time.Time) Week {
+	w := Week{Start: Monday(day), FirstHour: defaultFirstHour, LastHour: defaultLastHour, Now: -1}
+	end := w.Start.AddDate(0, 0, 7)
+	today := Day(now)
+
+	var blocks [7][]Block
+	for _, course := range courses {
+		start, stop, ok := span(course)
+		if !ok || start.Before(w.Start) || !start.Before(end) {
+			continue
+		}
+		i := int(Day(start).Sub(w.Start).Hours()+12) / 24
+		blocks[i] = append(blocks[i], Block{Course: course, Start: start, End: stop})
+
+		w.FirstHour = min(w.FirstHour, start.Hour())
+		lastHour := stop.Hour()
+		if stop.Minute() == 0 && stop.Hour() > 0 && Day(stop).Equal(Day(start)) {
+			lastHour--
+		}
+		if !Day(stop).Equal(Day(start)) {
+			lastHour = 23
+		}
+		w.LastHour = max(w.LastHour, lastHour)
+	}
+
+	for i := range blocks {
+		date := w.Start.AddDate(0, 0, i)
+		day := WeekDay{Date: date, Today: date.Equal(today), Blocks: blocks[i]}
+		w.place(day.Blocks, date)
+		w.Days = append(w.Days, day)
+
+		if day.Today {
+			if top := w.position(now.In(service.ParisLocation()), date); top >= 0 && top <= 100 {
+				w.Now = top
+			}
+		}
+	}
+	return w
+}
+
+// position returns the position of t in percent of the hours shown on date
+func (w Week) position(t, date time.Time) float64 {
+	first := time.Date(date.Year(), date.Month(), date.Day(), w.FirstHour, 0, 0, 0, date.Location())
+	length := time.Duration(w.LastHour-w.FirstHour+1) * time.Hour
+	return float64(t.Sub(first)) / float64(length) * 100
+}
+
+// place positions the blocks of a day, giving overlapping courses lanes side
+// by side
+func (w Week) place(blocks []Block, date time.Time) {
+	slices.SortStableFunc(blocks, func(a, b Block) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	// Blocks overlapping each other, directly or not, form a cluster whose
+	// blocks all get the width of its number of lanes
+	var cluster []int
+	var laneEnds []time.Time
+	var clusterEnd time.Time
+	flush := func() {
+		for _, i := range cluster {
+			blocks[i].Lanes = len(laneEnds)
+		}
+		cluster, laneEnds = cluster[:0], laneEnds[:0]
+	}
+
+	for i := range blocks {
+		b := &blocks[i]
+		top, bottom := w.position(b.Start, date), w.position(b.End, date)
+		b.Top, b.Height = max(top, 0), min(bottom, 100)-max(top, 0)
+
+		if len(cluster) > 0 && !b.Start.Before(clusterEnd) {
+			flush()
+		}
+		lane := slices.IndexFunc(laneEnds, func(end time.Time) bool { return !b.Start.Before(end) })
+		if lane < 0 {
+			lane = len(laneEnds)
+			laneEnds = append(laneEnds, b.End)
+		} else {
+			laneEnds[lane] = b.End
+		}
+		b.Lane = lane
+		if len(cluster) == 0 || b.End.After(clusterEnd) {
+			clusterEnd = b.End
+		}
+		cluster = append(cluster, i)
+	}
+	flush()
+}
+
+// MonthDay is a cell of a month
+type MonthDay struct {
+	// Date is midnight in Paris
+	Date time.Time
+	// Outside reports whether the day belongs to the previous or next month
+	Outside bool
+	Today   bool
+	Courses []models.Course
+}
+
+// Month is the courses of a month, in weeks from Monday to Sunday
+type Month struct {
+	// Start is the first day of the month at midnight in Paris
+	Start time.Time
+	Weeks [][]MonthDay
+}
+
+// Prev and Next return the first day of the previous and next months
+func (m Month) Prev() time.Time { return m.Start.AddDate(0, -1, 0) }
+func (m Month) Next() time.Time { return m.Start.AddDate(0, 1, 0) }
+
+// NewMonth lists the courses of each day of the month of day, marking now
+func NewMonth(courses []models.Course, day, now time.Time) Month {
+	day = Day(day)
+	m := Month{Start: time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())}
+	first := Monday(m.Start)
+	end := m.Start.AddDate(0, 1, 0)
+	today := Day(now)
+
+	byDay := make(map[string][]models.Course)
+	for _, course := range courses {
+		start, _, ok := span(course)
+		if !ok {
+			continue
+		}
+		key := start.Format(time.DateOnly)
+		byDay[key] = append(byDay[key], course)
+	}
+	for _, list := range byDay {
+		slices.SortStableFunc(list, func(a, b models.Course) int {
+			return service.CourseStart(a).Compare(service.CourseStart(b))
+		})
+	}
+
+	for date := first; date.Before(end); date = date.AddDate(0, 0, 7) {
+		week := make([]MonthDay, 0, 7)
+		for i := range 7 {
+			d := date.AddDate(0, 0, i)
+			week = append(week, MonthDay{
+				Date:    d,
+				Outside: d.Month() != m.Start.Month(),
+				Today:   d.Equal(today),
+				Courses: byDay[d.Format(time.DateOnly)],
+			})
+		}
+		m.Weeks = append(m.Weeks, week)
+	}
+	return m
+}
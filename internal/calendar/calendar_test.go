@@ -0,0 +1,118 @@
+package calendar
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/service"
+)
+
+func paris(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, service.ParisLocation())
+}
+
+func TestMonday(t *testing.T) {
+	tests := []struct {
+		day  time.Time
+		want time.Time
+	}{
+		{day: paris(2025, 2, 10, 9, 0), want: paris(2025, 2, 10, 0, 0)},
+		{day: paris(2025, 2, 16, 23, 0), want: paris(2025, 2, 10, 0, 0)},
+		{day: paris(2025, 3, 1, 0, 0), want: paris(2025, 2, 24, 0, 0)},
+		// Late on Sunday in UTC is already Monday in Paris
+		{day: time.Date(2025, 2, 16, 23, 30, 0, 0, time.UTC), want: paris(2025, 2, 17, 0, 0)},
+	}
+
+	for _, tt := range tests {
+		if got := Monday(tt.day); !got.Equal(tt.want) {
+			t.Errorf("Monday(%v) = %v, want %v", tt.day, got, tt.want)
+		}
+	}
+}
+
+func TestNewWeek(t *testing.T) {
+	courses := []models.Course{
+		// 9:00 to 12:30 in Paris
+		{ID: 1, Date: "2025-02-10", Start: "08:00:00+00:00", End: "11:30:00+00:00"},
+		// Overlaps the first course
+		{ID: 2, Date: "2025-02-10", Start: "10:00:00+00:00", End: "12:00:00+00:00"},
+		// Starts when the first one ends, in the same cluster as the second
+		{ID: 3, Date: "2025-02-10", Start: "11:30:00+00:00", End: "12:30:00+00:00"},
+		// Alone in the afternoon
+		{ID: 4, Date: "2025-02-10", Start: "13:00:00+00:00", End: "14:00:00+00:00"},
+		// 7:00 to 8:00 in Paris on Friday, widening the hours shown
+		{ID: 5, Date: "2025-02-14", Start: "06:00:00+00:00", End: "07:00:00+00:00"},
+		// Next week
+		{ID: 6, Date: "2025-02-17", Start: "08:00:00+00:00", End: "09:00:00+00:00"},
+		{ID: 7, Name: "Sans horaire"},
+	}
+
+	w := NewWeek(courses, paris(2025, 2, 12, 0, 0), paris(2025, 2, 10, 10, 0))
+	if !w.Start.Equal(paris(2025, 2, 10, 0, 0)) || len(w.Days) != 7 {
+		t.Fatalf("NewWeek() start = %v with %d days, want Monday 10 with 7 days", w.Start, len(w.Days))
+	}
+	if w.FirstHour != 7 || w.LastHour != 19 {
+		t.Errorf("NewWeek() hours = %d-%d, want 7-19", w.FirstHour, w.LastHour)
+	}
+	if !w.Days[0].Today || w.Days[1].Today {
+		t.Errorf("NewWeek() today = %v, %v, want Monday only", w.Days[0].Today, w.Days[1].Today)
+	}
+	// 10:00 is 3 hours after 7:00 out of 13 hours shown
+	if want := 3.0 / 13 * 100; math.Abs(w.Now-want) > 0.01 {
+		t.Errorf("NewWeek() now = %v, want %v", w.Now, want)
+	}
+
+	monday := w.Days[0].Blocks
+	if len(monday) != 4 || len(w.Days[4].Blocks) != 1 {
+		t.Fatalf("NewWeek() blocks = %d on Monday and %d on Friday, want 4 and 1", len(monday), len(w.Days[4].Blocks))
+	}
+	wantLanes := []struct{ id, lane, lanes int }{{1, 0, 2}, {2, 1, 2}, {3, 0, 2}, {4, 0, 1}}
+	for i, want := range wantLanes {
+		b := monday[i]
+		if b.Course.ID != want.id || b.Lane != want.lane || b.Lanes != want.lanes {
+			t.Errorf("block %d = course %d in lane %d of %d, want course %d in lane %d of %d",
+				i, b.Course.ID, b.Lane, b.Lanes, want.id, want.lane, want.lanes)
+		}
+	}
+	if b := monday[0]; math.Abs(b.Top-2.0/13*100) > 0.01 || math.Abs(b.Height-3.5/13*100) > 0.01 {
+		t.Errorf("block 0 = top %v height %v, want 2h and 3h30 out of 13h", b.Top, b.Height)
+	}
+
+	// Outside of the week, there is no current time marker
+	if w := NewWeek(courses, paris(2025, 2, 17, 0, 0), paris(2025, 2, 10, 10, 0)); w.Now >= 0 || len(w.Days[0].Blocks) != 1 {
+		t.Errorf("NewWeek() next week now = %v with %d blocks, want no marker and 1 block", w.Now, len(w.Days[0].Blocks))
+	}
+}
+
+func TestNewMonth(t *testing.T) {
+	courses := []models.Course{
+		{ID: 2, Date: "2025-02-10", Start: "13:00:00+00:00", End: "14:00:00+00:00"},
+		{ID: 1, Date: "2025-02-10", Start: "08:00:00+00:00", End: "11:30:00+00:00"},
+		{ID: 3, Date: "2025-03-03", Start: "08:00:00+00:00", End: "11:30:00+00:00"},
+	}
+
+	m := NewMonth(courses, paris(2025, 2, 12, 0, 0), paris(2025, 2, 10, 10, 0))
+	if !m.Start.Equal(paris(2025, 2, 1, 0, 0)) {
+		t.Errorf("NewMonth() start = %v, want February 1", m.Start)
+	}
+	// February 2025 starts on a Saturday and ends on a Friday
+	if len(m.Weeks) != 5 {
+		t.Fatalf("NewMonth() = %d weeks, want 5", len(m.Weeks))
+	}
+	if first := m.Weeks[0][0]; !first.Date.Equal(paris(2025, 1, 27, 0, 0)) || !first.Outside {
+		t.Errorf("NewMonth() first day = %v outside %v, want January 27 outside", first.Date, first.Outside)
+	}
+
+	day := m.Weeks[2][0]
+	if !day.Date.Equal(paris(2025, 2, 10, 0, 0)) || !day.Today || day.Outside {
+		t.Errorf("NewMonth() day = %+v, want today February 10", day)
+	}
+	if len(day.Courses) != 2 || day.Courses[0].ID != 1 || day.Courses[1].ID != 2 {
+		t.Errorf("NewMonth() courses = %+v, want courses 1 and 2 in order", day.Courses)
+	}
+	if last := m.Weeks[4][6]; !last.Date.Equal(paris(2025, 3, 2, 0, 0)) || last.Courses != nil {
+		t.Errorf("NewMonth() last day = %+v, want March 2 without courses", last)
+	}
+}
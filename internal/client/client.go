@@ -1,22 +1,39 @@
 package client
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/LaulauChau/sws/internal/config"
+	"github.com/LaulauChau/sws/internal/metrics"
 	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/ratelimit"
+	"github.com/LaulauChau/sws/internal/service"
+	"github.com/LaulauChau/sws/internal/tracing"
 	"github.com/LaulauChau/sws/pkg/cache"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"golang.org/x/time/rate"
 )
 
+var tracer = tracing.Tracer("github.com/LaulauChau/sws/internal/client")
+
 var (
 	postTokenURL     = "https://app.sowesign.com/api/portal/authentication/token"
-	nextCoursesURL   = "https://app.sowesign.com/api/student-app/future-courses?limit=8"
+	nextCoursesURL   = "https://app.sowesign.com/api/student-app/future-courses"
 	currentCourseURL = "https://app.sowesign.com/api/trainer-app/current-courses?limit=1"
 )
 
@@ -29,44 +46,270 @@ func SetBaseURLs(tokenURL, coursesURL, currentURL string) {
 
 type Client struct {
 	httpClient *http.Client
+	mu         sync.RWMutex
 	token      string
 	config     config.Config
 	cache      *cache.Cache[[]models.Course]
+	// upstream is the token bucket of the calls to Sowesign, shared by every
+	// client of the server, and profileLimiter the one of the profile when it
+	// overrides the upstream rate limit, both of which each call takes from
+	upstream       *rate.Limiter
+	profileLimiter *rate.Limiter
+	// sleep waits for the rate limit between the pages of a fetch
+	sleep   func(ctx context.Context, d time.Duration) error
+	metrics *metrics.Metrics
+	// tokenObtained is when a token was last obtained, tokenErr the error of
+	// the last attempt
+	tokenObtained time.Time
+	tokenErr      error
+	// extras holds the fields of the last fetched courses that Course does
+	// not map, by course ID
+	extras map[int]map[string]json.RawMessage
+	// codeErrors holds the last fetched courses whose code could not be
+	// generated, so that each one is only counted once
+	codeErrors map[string]bool
 }
 
 type AuthResponse struct {
 	Token string `json:"token"`
 }
 
-func NewClient(config config.Config) *Client {
+// NewClient creates a client whose calls to Sowesign are limited by
+// upstream, the bucket shared by every client of the server. A nil upstream
+// gives the client a bucket of its own, e.g. for a command
+func NewClient(config config.Config, upstream *rate.Limiter) *Client {
+	ttl := config.CacheTTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	if upstream == nil {
+		upstream = ratelimit.NewLimiter(config.RateLimits.Upstream)
+	}
+
 	return &Client{
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
-			Transport: &http.Transport{
+			Transport: otelhttp.NewTransport(&http.Transport{
 				MaxIdleConns:        100,
 				MaxIdleConnsPerHost: 100,
 				IdleConnTimeout:     90 * time.Second,
 				DisableCompression:  true, // Often faster for small responses
 				ForceAttemptHTTP2:   true,
-			},
+			}),
 		},
-		config: config,
-		cache:  cache.NewCache[[]models.Course](24 * time.Hour),
+		config:         config,
+		cache:          cache.NewCache[[]models.Course](ttl),
+		upstream:       upstream,
+		profileLimiter: newProfileLimiter(config),
+		sleep:          sleep,
+	}
+}
+
+// newProfileLimiter returns the bucket of the profile of the configuration,
+// nil unless it overrides the upstream rate limit
+func newProfileLimiter(cfg config.Config) *rate.Limiter {
+	if r := cfg.ProfileRateLimits().Upstream; r != cfg.RateLimits.Upstream {
+		return ratelimit.NewLimiter(r)
 	}
+	return nil
 }
 
 func isTesting() bool {
 	return flag.Lookup("test.v") != nil
 }
 
+// Config returns the configuration the client currently uses
+func (c *Client) Config() config.Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config
+}
+
+// SetConfig swaps the configuration of a running client. Changing the
+// credentials discards the token and the cached courses of the old account
+func (c *Client) SetConfig(cfg config.Config) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cfg.CodeEtablissement != c.config.CodeEtablissement || cfg.Identifiant != c.config.Identifiant || cfg.PIN != c.config.PIN {
+		c.token = ""
+		c.cache.Invalidate()
+	}
+	if cfg.CacheTTL > 0 {
+		c.cache.SetTimeout(cfg.CacheTTL)
+	}
+	if cfg.RateLimits.Upstream != c.config.RateLimits.Upstream {
+		ratelimit.SetRate(c.upstream, cfg.RateLimits.Upstream, time.Now())
+	}
+	if cfg.ProfileRateLimits().Upstream != c.config.ProfileRateLimits().Upstream {
+		c.profileLimiter = newProfileLimiter(cfg)
+	}
+	c.config = cfg
+}
+
+// SetMetrics records the calls to Sowesign of the client
+func (c *Client) SetMetrics(m *metrics.Metrics) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.metrics = m
+}
+
+// CoursesFetchedAt returns when the cached courses were fetched, zero when
+// none were
+func (c *Client) CoursesFetchedAt() time.Time {
+	stats := c.cache.Stats()
+	if !stats.Initialized {
+		return time.Time{}
+	}
+	return time.Now().Add(-stats.Age)
+}
+
+// CacheStats returns the statistics of the course cache
+func (c *Client) CacheStats() cache.Stats {
+	return c.cache.Stats()
+}
+
+// do sends a request to a Sowesign endpoint in a span, recording its status
+// and latency
+func (c *Client) do(endpoint string, req *http.Request) (*http.Response, error) {
+	c.mu.RLock()
+	m := c.metrics
+	c.mu.RUnlock()
+
+	ctx, span := tracer.Start(req.Context(), "sowesign "+endpoint)
+	defer span.End()
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	status := 0
+	if err == nil {
+		status = resp.StatusCode
+	} else {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	m.ObserveUpstream(endpoint, status, time.Since(start))
+	return resp, err
+}
+
+// cachedCourses looks the courses up in the cache in a span
+func (c *Client) cachedCourses(ctx context.Context) ([]models.Course, bool) {
+	_, span := tracer.Start(ctx, "cache lookup")
+	defer span.End()
+
+	courses, ok := c.cache.Get()
+	span.SetAttributes(attribute.Bool("cache.hit", ok))
+	return courses, ok
+}
+
+// reserve takes a token for a call to Sowesign, failing with a
+// ratelimit.Error when calls are too frequent
+func (c *Client) reserve() error {
+	c.mu.RLock()
+	upstream, profile := c.upstream, c.profileLimiter
+	c.mu.RUnlock()
+	return ratelimit.ReserveAll(time.Now(), profile, upstream)
+}
+
+// waitReserve takes a token for a call to Sowesign, waiting until the rate
+// limit allows the call. It fails with a ratelimit.Error when ctx would end
+// before
+func (c *Client) waitReserve(ctx context.Context) error {
+	c.mu.RLock()
+	upstream, profile := c.upstream, c.profileLimiter
+	c.mu.RUnlock()
+
+	now := time.Now()
+	delay, cancel, err := ratelimit.Delay(now, profile, upstream)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(now.Add(delay)) {
+		cancel()
+		return &ratelimit.Error{RetryAfter: delay}
+	}
+	if err := c.sleep(ctx, delay); err != nil {
+		cancel()
+		return err
+	}
+	return nil
+}
+
+// sleep waits for d unless ctx ends first
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (c *Client) GetToken() error {
-	if c.config.CodeEtablissement == "" || c.config.Identifiant == "" || c.config.PIN == "" {
+	return c.GetTokenContext(context.Background())
+}
+
+// GetTokenContext is GetToken with the context of the request it is made for
+func (c *Client) GetTokenContext(ctx context.Context) error {
+	err := c.getToken(ctx)
+	c.mu.Lock()
+	m := c.metrics
+	c.tokenErr = err
+	if err == nil {
+		c.tokenObtained = time.Now()
+	}
+	c.mu.Unlock()
+	m.ObserveTokenRefresh(err)
+	return err
+}
+
+// TokenStatus returns when a token was last obtained, zero when none was,
+// and the error of the last attempt
+func (c *Client) TokenStatus() (time.Time, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tokenObtained, c.tokenErr
+}
+
+// Ping checks that Sowesign answers, without authenticating nor counting
+// against the rate limit
+func (c *Client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", postTokenURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	resp, err := c.do("ping", req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("server returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (c *Client) getToken(ctx context.Context) error {
+	cfg := c.Config()
+	if cfg.CodeEtablissement == "" || cfg.Identifiant == "" || cfg.PIN == "" {
 		return fmt.Errorf("empty credentials provided")
 	}
 
-	auth := base64.StdEncoding.EncodeToString([]byte(c.config.CodeEtablissement + c.config.Identifiant + c.config.PIN))
+	if err := c.reserve(); err != nil {
+		return err
+	}
 
-	req, err := http.NewRequest("POST", postTokenURL, nil)
+	auth := base64.StdEncoding.EncodeToString([]byte(cfg.CodeEtablissement + cfg.Identifiant + cfg.PIN))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", postTokenURL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %v", err)
 	}
@@ -74,7 +317,7 @@ func (c *Client) GetToken() error {
 	req.Header.Set("Authorization", "JBAuth "+auth)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do("token", req)
 	if err != nil {
 		return fmt.Errorf("failed to send request: %v", err)
 	}
@@ -98,65 +341,258 @@ func (c *Client) GetToken() error {
 		return fmt.Errorf("received empty token from server")
 	}
 
+	c.mu.Lock()
 	c.token = "Bearer " + authResp.Token
+	c.mu.Unlock()
 	if !isTesting() {
-		fmt.Println("Successfully obtained token")
+		fmt.Fprintln(os.Stderr, "Successfully obtained token")
 	}
 	return nil
 }
 
 func (c *Client) GetNextCourses() ([]models.Course, error) {
-	if courses, ok := c.cache.Get(); ok {
+	return c.GetNextCoursesContext(context.Background())
+}
+
+// GetNextCoursesContext is GetNextCourses with the context of the request it
+// is made for
+func (c *Client) GetNextCoursesContext(ctx context.Context) ([]models.Course, error) {
+	if courses, ok := c.cachedCourses(ctx); ok {
 		if !isTesting() {
-			fmt.Println("Retrieved courses from cache")
+			fmt.Fprintln(os.Stderr, "Retrieved courses from cache")
 		}
 		return courses, nil
 	}
 
-	if c.token == "" {
+	return c.FetchNextCoursesContext(ctx)
+}
+
+// LoadNextCourses returns the cached courses, only authenticating and
+// contacting the API when nothing has been cached yet
+func (c *Client) LoadNextCourses() ([]models.Course, error) {
+	return c.LoadNextCoursesContext(context.Background())
+}
+
+// LoadNextCoursesContext is LoadNextCourses with the context of the request
+// it is made for
+func (c *Client) LoadNextCoursesContext(ctx context.Context) ([]models.Course, error) {
+	if courses, ok := c.cachedCourses(ctx); ok {
+		return courses, nil
+	}
+
+	if err := c.GetTokenContext(ctx); err != nil {
+		return nil, err
+	}
+	return c.FetchNextCoursesContext(ctx)
+}
+
+// CachedNextCourses returns the cached courses without contacting the API
+func (c *Client) CachedNextCourses() ([]models.Course, bool) {
+	return c.cache.Get()
+}
+
+// FetchNextCourses retrieves the next courses from the API, bypassing and refreshing the cache
+func (c *Client) FetchNextCourses() ([]models.Course, error) {
+	return c.FetchNextCoursesContext(context.Background())
+}
+
+const (
+	// defaultCourseLimit is the page size of configurations without one
+	defaultCourseLimit = 8
+	// maxCoursePages caps the requests of a fetch, in case Sowesign keeps
+	// returning new courses
+	maxCoursePages = 50
+)
+
+// FetchNextCoursesContext is FetchNextCourses with the context of the
+// request it is made for. Pages of courses.limit courses are requested until
+// courses.horizon is covered, merged by course ID. Every page counts against
+// the upstream rate limit: the first page fails with the rate limit error
+// when it is exceeded, the following ones wait for it unless ctx ends first
+func (c *Client) FetchNextCoursesContext(ctx context.Context) ([]models.Course, error) {
+	c.mu.RLock()
+	token := c.token
+	settings := c.config.Courses
+	c.mu.RUnlock()
+
+	if token == "" {
 		return nil, fmt.Errorf("no authentication token available")
 	}
 
-	req, err := http.NewRequest("GET", nextCoursesURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+	limit := settings.Limit
+	if limit <= 0 {
+		limit = defaultCourseLimit
+	}
+	var deadline time.Time
+	if settings.Horizon > 0 {
+		deadline = time.Now().Add(settings.Horizon)
 	}
 
-	req.Header.Set("Authorization", c.token)
-	req.Header.Set("Content-Type", "application/json")
+	if !isTesting() {
+		fmt.Fprintln(os.Stderr, "Sending request to get next courses...")
+	}
+	var courses []models.Course
+	extras := make(map[int]map[string]json.RawMessage)
+	seen := make(map[int]bool)
+	for page := 0; page < maxCoursePages; page++ {
+		// The first page is refused right away, like any call to Sowesign
+		var err error
+		if page == 0 {
+			err = c.reserve()
+		} else {
+			err = c.waitReserve(ctx)
+		}
+		if err != nil {
+			return nil, err
+		}
+		batch, batchExtras, err := c.fetchCoursesPage(ctx, token, page*limit, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		added := 0
+		for _, course := range batch {
+			if !seen[course.ID] {
+				seen[course.ID] = true
+				courses = append(courses, course)
+				added++
+			}
+		}
+		maps.Copy(extras, batchExtras)
+
+		// Stop on the last page, and when Sowesign ignores the offset
+		if deadline.IsZero() || len(batch) < limit || added == 0 {
+			break
+		}
+		if last := service.CourseStart(batch[len(batch)-1]); !last.IsZero() && last.After(deadline) {
+			break
+		}
+	}
+	if !deadline.IsZero() {
+		courses = slices.DeleteFunc(courses, func(course models.Course) bool {
+			return service.CourseStart(course).After(deadline)
+		})
+	}
+
+	c.mu.Lock()
+	c.extras = extras
+	c.observeCodeErrors(courses)
+	c.mu.Unlock()
+
+	c.cache.Set(courses)
 
 	if !isTesting() {
-		fmt.Println("Sending request to get next courses...")
+		fmt.Fprintf(os.Stderr, "Retrieved %d courses from API and cached\n", len(courses))
 	}
-	resp, err := c.httpClient.Do(req)
+	return courses, nil
+}
+
+// observeCodeErrors counts the fetched courses whose code cannot be
+// generated, except those already counted by the previous fetch. It must be
+// called with c.mu held
+func (c *Client) observeCodeErrors(courses []models.Course) {
+	current := make(map[string]bool)
+	for _, course := range courses {
+		reason := service.CodeError(course)
+		if reason == "" {
+			continue
+		}
+		key := codeErrorKey(course)
+		if !c.codeErrors[key] {
+			c.metrics.ObserveCodeError(reason)
+		}
+		current[key] = true
+	}
+	c.codeErrors = current
+}
+
+// codeErrorKey identifies a course by its ID, or by its name and time when it
+// has none
+func codeErrorKey(course models.Course) string {
+	if course.ID != 0 {
+		return strconv.Itoa(course.ID)
+	}
+	return course.Name + "|" + course.Date + "|" + course.Start
+}
+
+// fetchCoursesPage requests limit courses from offset
+func (c *Client) fetchCoursesPage(ctx context.Context, token string, offset, limit int) ([]models.Course, map[int]map[string]json.RawMessage, error) {
+	u, err := url.Parse(nextCoursesURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
+		return nil, nil, fmt.Errorf("failed to create request: %v", err)
 	}
-	defer func() {
-		if cerr := resp.Body.Close(); cerr != nil && err == nil {
-			err = cerr
-		}
-	}()
+	q := u.Query()
+	q.Set("limit", strconv.Itoa(limit))
+	if offset > 0 {
+		q.Set("offset", strconv.Itoa(offset))
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do("future_courses", req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("server returned status code %d: %s", resp.StatusCode, string(body))
+		return nil, nil, fmt.Errorf("server returned status code %d: %s", resp.StatusCode, string(body))
 	}
 
-	var courses []models.Course
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+		return nil, nil, fmt.Errorf("failed to read response body: %v", err)
 	}
 
+	var courses []models.Course
 	if err := json.Unmarshal(body, &courses); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+		return nil, nil, fmt.Errorf("failed to decode response: %v", err)
 	}
+	extras, err := decodeExtras(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return courses, extras, nil
+}
 
-	c.cache.Set(courses)
+// CourseExtras returns the fields Sowesign sent for a course that Course
+// does not map, nil when there are none or the course was not fetched
+func (c *Client) CourseExtras(id int) map[string]json.RawMessage {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.extras[id]
+}
 
-	if !isTesting() {
-		fmt.Printf("Retrieved %d courses from API and cached\n", len(courses))
+// courseFields lists the JSON fields mapped by Course
+var courseFields = []string{"id", "name", "date", "start", "end"}
+
+// decodeExtras collects the fields of every course that Course does not map
+func decodeExtras(body []byte) (map[int]map[string]json.RawMessage, error) {
+	var raw []map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, err
 	}
-	return courses, nil
+
+	extras := make(map[int]map[string]json.RawMessage)
+	for _, fields := range raw {
+		var id int
+		if err := json.Unmarshal(fields["id"], &id); err != nil {
+			continue
+		}
+		for _, name := range courseFields {
+			delete(fields, name)
+		}
+		if len(fields) > 0 {
+			extras[id] = fields
+		}
+	}
+	return extras, nil
 }
@@ -1,13 +1,25 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/LaulauChau/sws/internal/config"
+	"github.com/LaulauChau/sws/internal/metrics"
+	"github.com/LaulauChau/sws/internal/mock"
 	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/ratelimit"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 func TestClient_GetToken(t *testing.T) {
@@ -60,7 +72,7 @@ func TestClient_GetToken(t *testing.T) {
 
 			// Use test config
 			cfg := config.NewTestConfig()
-			c := NewClient(cfg)
+			c := NewClient(cfg, nil)
 			c.httpClient = server.Client()
 
 			// Override token URL for testing
@@ -120,7 +132,7 @@ func TestClient_GetNextCourses(t *testing.T) {
 
 			// Use test config and initialize client
 			cfg := config.NewTestConfig()
-			c := NewClient(cfg)
+			c := NewClient(cfg, nil)
 			c.token = "test-token"
 			c.httpClient = server.Client()
 
@@ -141,10 +153,522 @@ func TestClient_GetNextCourses(t *testing.T) {
 
 func TestClient_GetNextCourses_NoToken(t *testing.T) {
 	cfg := config.NewTestConfig()
-	c := NewClient(cfg)
+	c := NewClient(cfg, nil)
 
 	_, err := c.GetNextCourses()
 	if err == nil {
 		t.Error("Expected error when calling GetNextCourses without token")
 	}
 }
+
+func TestClient_LoadNextCourses(t *testing.T) {
+	var tokenRequests, courseRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			tokenRequests++
+			json.NewEncoder(w).Encode(AuthResponse{Token: "test-token"})
+			return
+		}
+		courseRequests++
+		json.NewEncoder(w).Encode([]models.Course{{ID: 1, Name: "Test Course"}})
+	}))
+	defer server.Close()
+
+	c := NewClient(config.NewTestConfig(), nil)
+	c.httpClient = server.Client()
+	postTokenURL = server.URL
+	nextCoursesURL = server.URL
+
+	for i := 0; i < 2; i++ {
+		courses, err := c.LoadNextCourses()
+		if err != nil {
+			t.Fatalf("LoadNextCourses() error = %v", err)
+		}
+		if len(courses) != 1 {
+			t.Errorf("LoadNextCourses() got = %v courses, want 1", len(courses))
+		}
+	}
+
+	if tokenRequests != 1 || courseRequests != 1 {
+		t.Errorf("expected the second call to be served from cache, got %d token and %d course requests", tokenRequests, courseRequests)
+	}
+}
+
+func TestClient_TokenStatusAndPing(t *testing.T) {
+	status := http.StatusUnauthorized
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		if r.Method == http.MethodPost && status == http.StatusOK {
+			json.NewEncoder(w).Encode(AuthResponse{Token: "test-token"})
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(config.NewTestConfig(), nil)
+	c.httpClient = server.Client()
+	postTokenURL = server.URL
+
+	// Sowesign answering, even with an error, is reachable
+	if err := c.Ping(context.Background()); err != nil {
+		t.Errorf("Ping() error = %v, want nil", err)
+	}
+	if err := c.GetToken(); err == nil {
+		t.Fatal("GetToken() should fail when Sowesign rejects the credentials")
+	}
+	if obtained, err := c.TokenStatus(); !obtained.IsZero() || err == nil {
+		t.Errorf("TokenStatus() = %v, %v, want no token and the error", obtained, err)
+	}
+
+	status = http.StatusOK
+	if err := c.GetToken(); err != nil {
+		t.Fatalf("GetToken() error = %v", err)
+	}
+	if obtained, err := c.TokenStatus(); obtained.IsZero() || err != nil {
+		t.Errorf("TokenStatus() = %v, %v, want a token and no error", obtained, err)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := c.Ping(context.Background()); err == nil {
+		t.Error("Ping() should fail when Sowesign is unavailable")
+	}
+}
+
+func TestClient_CourseExtras(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"id": 1, "name": "Innover", "date": "2025-02-10", "start": "08:00:00+00:00", "end": "12:00:00+00:00", "room": "B204", "teacher": {"name": "Dupont"}},
+			{"id": 2, "name": "Gestion de projet", "date": "2025-02-11", "start": "08:00:00+00:00", "end": "12:00:00+00:00"}
+		]`))
+	}))
+	defer server.Close()
+
+	c := NewClient(config.NewTestConfig(), nil)
+	c.httpClient = server.Client()
+	c.token = "Bearer test-token"
+	nextCoursesURL = server.URL
+
+	courses, err := c.FetchNextCourses()
+	if err != nil || len(courses) != 2 || courses[0].Name != "Innover" {
+		t.Fatalf("FetchNextCourses() = %v, %v, want the two courses", courses, err)
+	}
+
+	extras := c.CourseExtras(1)
+	if len(extras) != 2 || string(extras["room"]) != `"B204"` || string(extras["teacher"]) != `{"name": "Dupont"}` {
+		t.Errorf("CourseExtras(1) = %s, want room and teacher", extras)
+	}
+	if extras := c.CourseExtras(2); extras != nil {
+		t.Errorf("CourseExtras(2) = %s, want nil", extras)
+	}
+}
+
+func TestClient_SetConfig(t *testing.T) {
+	c := NewClient(config.NewTestConfig(), nil)
+	c.token = "Bearer test-token"
+	c.cache.Set([]models.Course{{ID: 1}})
+
+	cfg := config.NewTestConfig()
+	cfg.RefreshInterval *= 2
+	c.SetConfig(cfg)
+	if _, ok := c.CachedNextCourses(); !ok || c.token == "" {
+		t.Error("SetConfig() without credential changes should keep the token and cache")
+	}
+
+	cfg.PIN = "other-pin"
+	c.SetConfig(cfg)
+	if _, ok := c.CachedNextCourses(); ok {
+		t.Error("SetConfig() with new credentials should invalidate the cache")
+	}
+	if c.token != "" {
+		t.Error("SetConfig() with new credentials should discard the token")
+	}
+	if got := c.Config().PIN; got != "other-pin" {
+		t.Errorf("Config().PIN = %q, want %q", got, "other-pin")
+	}
+}
+
+func TestClient_RateLimit(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		json.NewEncoder(w).Encode(AuthResponse{Token: "test-token"})
+	}))
+	defer server.Close()
+
+	cfg := config.NewTestConfig()
+	cfg.RateLimits.Upstream = config.Rate{Requests: 1, Per: time.Hour, Burst: 2}
+	c := NewClient(cfg, nil)
+	c.httpClient = server.Client()
+	postTokenURL = server.URL
+
+	for i := 0; i < 2; i++ {
+		if err := c.GetToken(); err != nil {
+			t.Fatalf("GetToken() error = %v", err)
+		}
+	}
+	err := c.GetToken()
+	if _, ok := ratelimit.RetryAfter(err); !ok {
+		t.Errorf("GetToken() error = %v, want a rate limit error", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected rate limited calls not to reach the server, got %d requests", requests)
+	}
+
+	// Disabling the limit applies immediately
+	cfg.RateLimits.Upstream = config.Rate{}
+	c.SetConfig(cfg)
+	if err := c.GetToken(); err != nil {
+		t.Errorf("GetToken() error = %v, want no limit", err)
+	}
+}
+
+func TestClient_SharedRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(AuthResponse{Token: "test-token"})
+	}))
+	defer server.Close()
+	postTokenURL = server.URL
+
+	// Every client takes from the shared bucket
+	shared := ratelimit.NewLimiter(config.Rate{Requests: 1, Per: time.Hour, Burst: 3})
+	first := NewClient(config.NewTestConfig(), shared)
+	second := NewClient(config.NewTestConfig(), shared)
+	for _, c := range []*Client{first, second, first} {
+		if err := c.GetToken(); err != nil {
+			t.Fatalf("GetToken() error = %v", err)
+		}
+	}
+	if _, ok := ratelimit.RetryAfter(second.GetToken()); !ok {
+		t.Error("GetToken() should be limited once the shared bucket is empty")
+	}
+
+	// A profile overriding the upstream rate limit is limited further
+	cfg := config.NewTestConfig()
+	cfg.Profiles[0].RateLimits = &config.RateLimits{Upstream: config.Rate{Requests: 1, Per: time.Hour, Burst: 1}}
+	shared = ratelimit.NewLimiter(config.Rate{Requests: 1, Per: time.Hour, Burst: 3})
+	limited := NewClient(cfg, shared)
+	if err := limited.GetToken(); err != nil {
+		t.Fatalf("GetToken() error = %v", err)
+	}
+	if _, ok := ratelimit.RetryAfter(limited.GetToken()); !ok {
+		t.Error("GetToken() should be limited by the profile bucket")
+	}
+	if got := shared.Tokens(); got < 1.9 || got > 2.1 {
+		t.Errorf("shared bucket has %v tokens, want 2 left after the limited call", got)
+	}
+}
+
+func TestClient_Metrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			json.NewEncoder(w).Encode(AuthResponse{Token: "test-token"})
+			return
+		}
+		json.NewEncoder(w).Encode([]models.Course{{ID: 1}})
+	}))
+	defer server.Close()
+
+	reg := prometheus.NewRegistry()
+	m := metrics.New(reg)
+	c := NewClient(config.NewTestConfig(), nil)
+	c.httpClient = server.Client()
+	c.SetMetrics(m)
+	postTokenURL = server.URL
+	nextCoursesURL = server.URL
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.LoadNextCourses(); err != nil {
+			t.Fatalf("LoadNextCourses() error = %v", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`sws_upstream_requests_total{endpoint="token",status="200"} 1`,
+		`sws_upstream_requests_total{endpoint="future_courses",status="200"} 1`,
+		`sws_token_refreshes_total{result="success"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("metrics do not contain %q", want)
+		}
+	}
+	if s := c.CacheStats(); s.Hits != 1 || s.Misses != 1 {
+		t.Errorf("CacheStats() = %+v, want 1 hit and 1 miss", s)
+	}
+}
+
+func TestClient_CodeErrorMetrics(t *testing.T) {
+	courses := []models.Course{
+		{ID: 1, Date: "2025-02-10"},
+		{Name: "Sans ID", Date: "2025-02-10", Start: "08:00:00+00:00"},
+		{ID: 2, Date: "2025-02-10", Start: "08:00:00+00:00"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			json.NewEncoder(w).Encode(AuthResponse{Token: "test-token"})
+			return
+		}
+		json.NewEncoder(w).Encode(courses)
+	}))
+	defer server.Close()
+
+	m := metrics.New(prometheus.NewRegistry())
+	c := NewClient(config.NewTestConfig(), nil)
+	c.SetMetrics(m)
+	postTokenURL = server.URL
+	nextCoursesURL = server.URL
+	if err := c.GetToken(); err != nil {
+		t.Fatalf("GetToken() error = %v", err)
+	}
+
+	// Courses are counted once however often they are fetched or shown
+	for i := 0; i < 2; i++ {
+		if _, err := c.FetchNextCourses(); err != nil {
+			t.Fatalf("FetchNextCourses() error = %v", err)
+		}
+	}
+	courses = append(courses, models.Course{ID: 3, Date: "demain", Start: "08:00:00+00:00"})
+	if _, err := c.FetchNextCourses(); err != nil {
+		t.Fatalf("FetchNextCourses() error = %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`sws_code_generation_errors_total{reason="invalid_start"} 2`,
+		`sws_code_generation_errors_total{reason="missing_id"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("metrics do not contain %q", want)
+		}
+	}
+}
+
+func TestClient_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			json.NewEncoder(w).Encode(AuthResponse{Token: "test-token"})
+			return
+		}
+		json.NewEncoder(w).Encode([]models.Course{{ID: 1}})
+	}))
+	defer server.Close()
+
+	c := NewClient(config.NewTestConfig(), nil)
+	postTokenURL = server.URL
+	nextCoursesURL = server.URL
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	if _, err := c.LoadNextCoursesContext(ctx); err != nil {
+		t.Fatalf("LoadNextCoursesContext() error = %v", err)
+	}
+	parent.End()
+
+	var names []string
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() == parent.SpanContext().SpanID() {
+			names = append(names, span.Name())
+		}
+	}
+	if got, want := strings.Join(names, ","), "cache lookup,sowesign token,sowesign future_courses"; got != want {
+		t.Errorf("child spans = %s, want %s", got, want)
+	}
+
+	// The transport traces the HTTP requests themselves
+	var httpSpans int
+	for _, span := range recorder.Ended() {
+		if strings.HasPrefix(span.Name(), "HTTP ") {
+			httpSpans++
+		}
+	}
+	if httpSpans != 2 {
+		t.Errorf("got %d HTTP client spans, want 2", httpSpans)
+	}
+}
+
+func TestClient_Pagination(t *testing.T) {
+	server := mock.NewServer()
+	defer server.Close()
+
+	// One course a day at 8:00 UTC for 20 days from tomorrow
+	today := time.Now().UTC()
+	var courses []models.Course
+	for i := 1; i <= 20; i++ {
+		courses = append(courses, models.Course{
+			ID:    i,
+			Name:  "Cours",
+			Date:  today.AddDate(0, 0, i).Format("2006-01-02"),
+			Start: "08:00:00+00:00",
+			End:   "10:00:00+00:00",
+		})
+	}
+	server.SetCourses(courses)
+	postTokenURL = server.URL + "/api/portal/authentication/token"
+	nextCoursesURL = server.URL + "/api/student-app/future-courses"
+
+	tests := []struct {
+		name         string
+		courses      config.Courses
+		wantCourses  int
+		wantRequests int
+	}{
+		{name: "single page", courses: config.Courses{Limit: 5}, wantCourses: 5, wantRequests: 1},
+		// The 7th course starts at most 7 days and 8 hours from now
+		{name: "week", courses: config.Courses{Limit: 5, Horizon: 7*24*time.Hour + 8*time.Hour}, wantCourses: 7, wantRequests: 2},
+		{name: "every page", courses: config.Courses{Limit: 5, Horizon: 30 * 24 * time.Hour}, wantCourses: 20, wantRequests: 5},
+		{name: "larger pages", courses: config.Courses{Limit: 50, Horizon: 30 * 24 * time.Hour}, wantCourses: 20, wantRequests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.NewTestConfig()
+			cfg.Courses = tt.courses
+			c := NewClient(cfg, nil)
+			if err := c.GetToken(); err != nil {
+				t.Fatalf("GetToken() error = %v", err)
+			}
+
+			server.CourseRequests = 0
+			got, err := c.FetchNextCourses()
+			if err != nil {
+				t.Fatalf("FetchNextCourses() error = %v", err)
+			}
+			if len(got) != tt.wantCourses || server.CourseRequests != tt.wantRequests {
+				t.Errorf("FetchNextCourses() = %d courses in %d requests, want %d in %d",
+					len(got), server.CourseRequests, tt.wantCourses, tt.wantRequests)
+			}
+			for i, course := range got {
+				if course.ID != i+1 {
+					t.Fatalf("FetchNextCourses()[%d].ID = %d, want %d", i, course.ID, i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestClient_PaginationIgnoredOffset(t *testing.T) {
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format("2006-01-02")
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			json.NewEncoder(w).Encode(AuthResponse{Token: "test-token"})
+			return
+		}
+		requests++
+		json.NewEncoder(w).Encode([]models.Course{
+			{ID: 1, Date: tomorrow, Start: "08:00:00+00:00"},
+			{ID: 2, Date: tomorrow, Start: "10:00:00+00:00", Name: fmt.Sprintf("page %d", requests)},
+		})
+	}))
+	defer server.Close()
+	postTokenURL = server.URL
+	nextCoursesURL = server.URL
+
+	cfg := config.NewTestConfig()
+	cfg.Courses = config.Courses{Limit: 2, Horizon: 30 * 24 * time.Hour}
+	c := NewClient(cfg, nil)
+	if err := c.GetToken(); err != nil {
+		t.Fatalf("GetToken() error = %v", err)
+	}
+
+	// The second page only repeats the first, which ends the pagination
+	got, err := c.FetchNextCourses()
+	if err != nil {
+		t.Fatalf("FetchNextCourses() error = %v", err)
+	}
+	if len(got) != 2 || got[1].Name != "page 1" || requests != 2 {
+		t.Errorf("FetchNextCourses() = %+v in %d requests, want the 2 courses of the first page in 2 requests", got, requests)
+	}
+}
+
+func TestClient_PaginationRateLimit(t *testing.T) {
+	server := mock.NewServer()
+	defer server.Close()
+
+	today := time.Now().UTC()
+	var courses []models.Course
+	for i := 1; i <= 20; i++ {
+		courses = append(courses, models.Course{ID: i, Date: today.AddDate(0, 0, i).Format("2006-01-02"), Start: "08:00:00+00:00"})
+	}
+	server.SetCourses(courses)
+	postTokenURL = server.URL + "/api/portal/authentication/token"
+	nextCoursesURL = server.URL + "/api/student-app/future-courses"
+
+	// The token and two pages empty the bucket, and the third page would wait
+	// past the end of the request
+	cfg := config.NewTestConfig()
+	cfg.Courses = config.Courses{Limit: 5, Horizon: 30 * 24 * time.Hour}
+	c := NewClient(cfg, ratelimit.NewLimiter(config.Rate{Requests: 1, Per: time.Hour, Burst: 3}))
+	if err := c.GetToken(); err != nil {
+		t.Fatalf("GetToken() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	got, err := c.FetchNextCoursesContext(ctx)
+	if _, ok := ratelimit.RetryAfter(err); !ok || got != nil {
+		t.Errorf("FetchNextCoursesContext() = %d courses, %v, want a rate limit error", len(got), err)
+	}
+	if server.CourseRequests != 2 {
+		t.Errorf("Sowesign got %d course requests, want 2", server.CourseRequests)
+	}
+	if _, ok := c.CachedNextCourses(); ok {
+		t.Error("CachedNextCourses() should not hold part of the courses")
+	}
+}
+
+func TestClient_PaginationWaitsForRateLimit(t *testing.T) {
+	server := mock.NewServer()
+	defer server.Close()
+
+	// Three courses a day need 12 pages of 8 to cover 30 days
+	today := time.Now().UTC()
+	var courses []models.Course
+	for i := 1; i <= 40*3; i++ {
+		date := today.AddDate(0, 0, (i+2)/3).Format("2006-01-02")
+		courses = append(courses, models.Course{ID: i, Date: date, Start: fmt.Sprintf("%02d:00:00+00:00", 8+i%3*2)})
+	}
+	server.SetCourses(courses)
+	postTokenURL = server.URL + "/api/portal/authentication/token"
+	nextCoursesURL = server.URL + "/api/student-app/future-courses"
+
+	cfg := config.NewTestConfig()
+	cfg.Courses.Horizon = 30 * 24 * time.Hour
+	c := NewClient(cfg, nil)
+	var waits []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		if d > 0 {
+			waits = append(waits, d)
+		}
+		return nil
+	}
+	if err := c.GetToken(); err != nil {
+		t.Fatalf("GetToken() error = %v", err)
+	}
+
+	got, err := c.FetchNextCourses()
+	if err != nil {
+		t.Fatalf("FetchNextCourses() error = %v", err)
+	}
+	if len(got) < 29*3 || len(got) > 30*3 {
+		t.Errorf("FetchNextCourses() = %d courses, want the %d to %d of the next 30 days", len(got), 29*3, 30*3)
+	}
+	if server.CourseRequests <= 10 {
+		t.Errorf("Sowesign got %d course requests, want more than the burst of 10", server.CourseRequests)
+	}
+	// The token and 9 pages empty the bucket, the following pages wait for
+	// it to refill a token every 3s
+	if want := server.CourseRequests - 9; len(waits) != want {
+		t.Errorf("fetch waited %d times, want %d", len(waits), want)
+	}
+	for i, d := range waits {
+		if limit := time.Duration(i+1) * 3 * time.Second; d <= 0 || d > limit {
+			t.Errorf("wait %d = %v, want at most %v", i, d, limit)
+		}
+	}
+}
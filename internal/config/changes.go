@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Changes describes the settings that differ between two configurations, by
+// their path in the config file. Secrets are reported as changed without
+// revealing their values
+func Changes(old, new Config) ([]string, error) {
+	oldValues, err := flatten(old)
+	if err != nil {
+		return nil, err
+	}
+	newValues, err := flatten(new)
+	if err != nil {
+		return nil, err
+	}
+	oldShown, err := flatten(old.Redacted())
+	if err != nil {
+		return nil, err
+	}
+	newShown, err := flatten(new.Redacted())
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []string
+	for _, key := range unionKeys(oldValues, newValues) {
+		if oldValues[key] == newValues[key] {
+			continue
+		}
+		switch before, after := oldShown[key], newShown[key]; {
+		case before == after:
+			changes = append(changes, key+": changed")
+		case before == "":
+			changes = append(changes, fmt.Sprintf("%s: set to %s", key, after))
+		case after == "":
+			changes = append(changes, fmt.Sprintf("%s: removed (was %s)", key, before))
+		default:
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", key, before, after))
+		}
+	}
+	return changes, nil
+}
+
+// flatten maps every setting of the config file schema to its value
+func flatten(c Config) (map[string]string, error) {
+	data, err := c.YAML()
+	if err != nil {
+		return nil, err
+	}
+	var tree any
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	values := make(map[string]string)
+	var walk func(path string, v any)
+	walk = func(path string, v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for key, child := range v {
+				if path != "" {
+					key = path + "." + key
+				}
+				walk(key, child)
+			}
+		case []any:
+			// Profiles are identified by name, so that reordering them is not a
+			// change, other lists are compared as a whole
+			if path == "profiles" {
+				for i, child := range v {
+					profile, _ := child.(map[string]any)
+					key := fmt.Sprintf("profiles[%d]", i)
+					if name, ok := profile["name"].(string); ok && name != "" {
+						key = "profiles[" + name + "]"
+						delete(profile, "name")
+					}
+					walk(key, child)
+				}
+				return
+			}
+			items := make([]string, len(v))
+			for i, child := range v {
+				items[i] = fmt.Sprint(child)
+			}
+			values[path] = "[" + strings.Join(items, ", ") + "]"
+		default:
+			values[path] = fmt.Sprint(v)
+		}
+	}
+	walk("", tree)
+	return values, nil
+}
+
+// ProfileChanged reports whether the named profile runs differently under
+// next than under old, changes to the other profiles being ignored
+func ProfileChanged(old, next Config, name string) bool {
+	before, err := old.WithProfile(name)
+	if err != nil {
+		return true
+	}
+	after, err := next.WithProfile(name)
+	if err != nil {
+		return true
+	}
+	before.Profiles = []Profile{old.Profiles[old.profileIndex(name)]}
+	after.Profiles = []Profile{next.Profiles[next.profileIndex(name)]}
+	return !reflect.DeepEqual(before, after)
+}
+
+// unionKeys returns the sorted keys of both maps
+func unionKeys(a, b map[string]string) []string {
+	var keys []string
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
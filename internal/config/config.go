@@ -3,51 +3,399 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+const (
+	defaultListenAddr      = ":8080"
+	defaultRefreshInterval = 5 * time.Minute
+	defaultCacheTTL        = 24 * time.Hour
+	defaultCourseLimit     = 8
+	defaultSessionTTL      = 7 * 24 * time.Hour
+	defaultIdleTimeout     = 30 * time.Minute
+	defaultDatabasePath    = "sws.db"
+	defaultDigestTimezone  = "Europe/Paris"
+	defaultConfigPath      = "sws.yaml"
+	defaultMaxBodySize     = 1 << 20
+
+	// TracingOTLP exports traces to an OpenTelemetry collector over HTTP
+	TracingOTLP = "otlp"
+	// TracingStdout prints traces, for local debugging
+	TracingStdout = "stdout"
+
+	// AuthModeLocal authenticates the users listed in the configuration
+	AuthModeLocal = "local"
+	// AuthModeSowesign lets every visitor sign in with their own Sowesign
+	// credentials, which are only kept in memory for the session
+	AuthModeSowesign = "sowesign"
+
+	// DefaultProfile is the name of the profile configured by the unprefixed
+	// SOWESIGN_* variables
+	DefaultProfile = "default"
+)
+
+// profileNamePattern restricts profile names to what fits in a variable name
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
 type Config struct {
+	// Profile is the name of the profile the credentials below belong to
+	Profile           string `json:"profile"`
 	CodeEtablissement string `json:"codeEtablissement"`
 	Identifiant       string `json:"identifiant"`
 	PIN               string `json:"PIN"`
+	ListenAddr        string `json:"listenAddr"`
+	// TLS serves HTTPS when both files are set
+	TLS TLS `json:"tls"`
+	// MaxBodySize is the largest request body accepted, in bytes
+	MaxBodySize     int64         `json:"maxBodySize"`
+	RefreshInterval time.Duration `json:"refreshInterval"`
+	// CacheTTL is how long fetched courses are served from the cache
+	CacheTTL time.Duration `json:"cacheTTL"`
+	// Courses configures how many upcoming courses are fetched
+	Courses Courses `json:"courses"`
+	// FeedToken is the secret token of the calendar feed, a random one being
+	// kept in the history database when empty
+	FeedToken    string `json:"feedToken"`
+	DatabasePath string `json:"databasePath"`
+	// CredentialsPath is the encrypted credentials file written by sws login
+	CredentialsPath string `json:"credentialsPath"`
+	// ReminderOffsets lists how long before each course a reminder is sent
+	ReminderOffsets []time.Duration `json:"reminderOffsets"`
+	Notifiers       Notifiers       `json:"notifiers"`
+	// DigestTime is the HH:MM time at which the daily agenda is sent, empty to disable
+	DigestTime     string `json:"digestTime"`
+	DigestTimezone string `json:"digestTimezone"`
+	// Profiles lists every configured Sowesign account, the first one is active
+	Profiles []Profile `json:"profiles"`
+	Auth     Auth      `json:"auth"`
+	// RateLimits applies to every profile without its own limits
+	RateLimits RateLimits `json:"rateLimits"`
+	Tracing    Tracing    `json:"tracing"`
+}
+
+// Courses configures how upcoming courses are fetched from Sowesign
+type Courses struct {
+	// Limit is the number of courses asked for in each request
+	Limit int `json:"limit"`
+	// Horizon is how far ahead courses are fetched, following pages until it
+	// is covered. Zero fetches a single page
+	Horizon time.Duration `json:"horizon"`
+}
+
+// Tracing configures the export of OpenTelemetry traces
+type Tracing struct {
+	// Exporter is TracingOTLP, TracingStdout or empty to disable tracing
+	Exporter string `json:"exporter"`
+	// Endpoint is the OTLP/HTTP URL of the collector, the OTEL_EXPORTER_OTLP_*
+	// variables are used when empty
+	Endpoint string `json:"endpoint"`
+	// SampleRatio is the fraction of requests traced
+	SampleRatio float64 `json:"sampleRatio"`
+}
+
+// Enabled reports whether traces are exported
+func (t Tracing) Enabled() bool {
+	return t.Exporter != ""
+}
+
+// RateLimits configures how often clients may refresh and how often Sowesign
+// is called
+type RateLimits struct {
+	// Client limits the refreshes and API requests of each client IP
+	Client Rate `json:"client"`
+	// Upstream limits the calls to Sowesign of every profile and visitor
+	// together, or of the profile when set in its rate limits
+	Upstream Rate `json:"upstream"`
+}
+
+// Rate allows Requests requests every Per with bursts of up to Burst
+// requests, zero Requests disables the limit
+type Rate struct {
+	Requests int           `json:"requests"`
+	Per      time.Duration `json:"per"`
+	Burst    int           `json:"burst"`
+}
+
+// Enabled reports whether the rate limits requests
+func (r Rate) Enabled() bool {
+	return r.Requests > 0
+}
+
+// TLS locates the certificate and private key the server listens with
+type TLS struct {
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+}
+
+// Enabled reports whether the server listens with TLS
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// Auth configures who may use the web UI and the JSON API, which are open to
+// anyone when no user and no API token is configured
+type Auth struct {
+	// Mode is AuthModeLocal or AuthModeSowesign
+	Mode  string `json:"mode"`
+	Users []User `json:"users"`
+	// SessionSecret signs session cookies, a random one is used when empty
+	SessionSecret string        `json:"sessionSecret"`
+	SessionTTL    time.Duration `json:"sessionTTL"`
+	// SecureCookies marks cookies Secure on plain HTTP requests too, for a
+	// TLS-terminating proxy in front of sws
+	SecureCookies bool `json:"secureCookies"`
+	// BasicAuth lets scripts authenticate with HTTP basic auth
+	BasicAuth bool     `json:"basicAuth"`
+	APITokens []string `json:"apiTokens"`
+	// IdleTimeout ends Sowesign sessions left unused, forgetting their credentials
+	IdleTimeout time.Duration `json:"idleTimeout"`
+}
+
+// User is an account of the web UI, with a bcrypt password hash
+type User struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"passwordHash"`
+}
+
+// Enabled reports whether authentication is required
+func (a Auth) Enabled() bool {
+	return a.SowesignSessions() || len(a.Users) > 0 || len(a.APITokens) > 0
+}
+
+// SowesignSessions reports whether visitors sign in with their own Sowesign
+// credentials
+func (a Auth) SowesignSessions() bool {
+	return a.Mode == AuthModeSowesign
+}
+
+// Profile holds the credentials of one Sowesign account
+type Profile struct {
+	Name              string `json:"name"`
+	CodeEtablissement string `json:"codeEtablissement"`
+	Identifiant       string `json:"identifiant"`
+	PIN               string `json:"PIN"`
+	FeedToken         string `json:"feedToken"`
+	// RateLimits overrides the global rate limits for this profile
+	RateLimits *RateLimits `json:"rateLimits,omitempty"`
+}
+
+// Notifiers configures the targets reminders are delivered to, empty
+// targets are disabled
+type Notifiers struct {
+	WebhookURL   string   `json:"webhookURL"`
+	SMTPAddr     string   `json:"smtpAddr"`
+	SMTPUsername string   `json:"smtpUsername"`
+	SMTPPassword string   `json:"smtpPassword"`
+	SMTPFrom     string   `json:"smtpFrom"`
+	SMTPTo       []string `json:"smtpTo"`
+	NtfyURL      string   `json:"ntfyURL"`
+	NtfyToken    string   `json:"ntfyToken"`
+}
+
+// defaults returns the configuration used for every setting left unset
+func defaults() Config {
+	return Config{
+		ListenAddr:      defaultListenAddr,
+		MaxBodySize:     defaultMaxBodySize,
+		RefreshInterval: defaultRefreshInterval,
+		CacheTTL:        defaultCacheTTL,
+		Courses:         Courses{Limit: defaultCourseLimit},
+		DatabasePath:    defaultDatabasePath,
+		CredentialsPath: defaultCredentialsPath,
+		DigestTimezone:  defaultDigestTimezone,
+		Auth: Auth{
+			Mode:        AuthModeLocal,
+			SessionTTL:  defaultSessionTTL,
+			IdleTimeout: defaultIdleTimeout,
+		},
+		RateLimits: RateLimits{
+			Client:   Rate{Requests: 30, Per: time.Minute, Burst: 10},
+			Upstream: Rate{Requests: 20, Per: time.Minute, Burst: 10},
+		},
+		Tracing: Tracing{SampleRatio: 1},
+	}
+}
+
+// Options selects the sources Load reads besides environment variables
+type Options struct {
+	// Path is the config file, SWS_CONFIG or sws.yaml when empty
+	Path string
+	// Passphrase unlocks the credentials file, SWS_PASSPHRASE when empty
+	Passphrase string
 }
 
-// NewConfig creates a new Config instance from environment variables
+// ConfigPath returns the config file Load reads
+func (o Options) ConfigPath() string {
+	path, _ := o.configPath()
+	return path
+}
+
+// configPath returns the config file and whether it was explicitly chosen,
+// in which case it must exist
+func (o Options) configPath() (string, bool) {
+	if o.Path != "" {
+		return o.Path, true
+	}
+	if path := os.Getenv("SWS_CONFIG"); path != "" {
+		return path, true
+	}
+	return defaultConfigPath, false
+}
+
+// NewConfig creates a new Config instance from the config file, the
+// credentials file and environment variables
 func NewConfig() (Config, error) {
+	return Load(Options{})
+}
+
+// Load builds the configuration from, by increasing precedence, the
+// defaults, the config file, the encrypted credentials file and environment
+// variables
+func Load(opts Options) (Config, error) {
+	cfg, env, err := loadBase(opts)
+	if err != nil {
+		return Config{}, err
+	}
+
+	passphrase := opts.Passphrase
+	if passphrase == "" {
+		passphrase = env.get("SWS_PASSPHRASE")
+	}
+	if env.err != nil {
+		return Config{}, env.err
+	}
+	if err := cfg.loadCredentials(passphrase); err != nil {
+		return Config{}, err
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return Config{}, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	if len(cfg.Profiles) == 0 {
+		// Visitors bring their own credentials
+		return cfg, nil
+	}
+	return cfg.WithProfile(cfg.Profiles[0].Name)
+}
+
+// loadBase reads the defaults, the config file and the location of the
+// credentials file
+func loadBase(opts Options) (Config, *envLoader, error) {
 	// Load .env file if it exists
 	if err := godotenv.Load(); err != nil {
 		// Only return error if file exists but couldn't be loaded
 		if !os.IsNotExist(err) {
-			return Config{}, fmt.Errorf("error loading .env file: %w", err)
+			return Config{}, nil, fmt.Errorf("error loading .env file: %w", err)
 		}
 	}
 
-	cfg := Config{
-		CodeEtablissement: os.Getenv("SOWESIGN_CODE_ETABLISSEMENT"),
-		Identifiant:       os.Getenv("SOWESIGN_IDENTIFIANT"),
-		PIN:               os.Getenv("SOWESIGN_PIN"),
+	cfg := defaults()
+	env := &envLoader{}
+
+	if path, explicit := opts.configPath(); explicit {
+		if err := cfg.loadFile(path); err != nil {
+			return Config{}, nil, err
+		}
+	} else if err := cfg.loadFile(path); err != nil && !os.IsNotExist(err) {
+		return Config{}, nil, err
 	}
 
-	// Validate required fields
-	if cfg.CodeEtablissement == "" {
-		return Config{}, fmt.Errorf("SOWESIGN_CODE_ETABLISSEMENT is required")
+	env.setString(&cfg.CredentialsPath, "SWS_CREDENTIALS_PATH")
+	if env.err != nil {
+		return Config{}, nil, env.err
 	}
-	if cfg.Identifiant == "" {
-		return Config{}, fmt.Errorf("SOWESIGN_IDENTIFIANT is required")
+	return cfg, env, nil
+}
+
+// CredentialsPath returns the location of the encrypted credentials file,
+// without requiring the rest of the configuration to be complete
+func CredentialsPath(opts Options) (string, error) {
+	cfg, _, err := loadBase(opts)
+	if err != nil {
+		return "", err
 	}
-	if cfg.PIN == "" {
-		return Config{}, fmt.Errorf("SOWESIGN_PIN is required")
+	return cfg.CredentialsPath, nil
+}
+
+// ProfileNames returns the names of the configured profiles, in order
+func (c Config) ProfileNames() []string {
+	names := make([]string, len(c.Profiles))
+	for i, p := range c.Profiles {
+		names[i] = p.Name
 	}
+	return names
+}
 
-	return cfg, nil
+// WithProfile returns a copy of the configuration using the credentials of
+// the named profile
+func (c Config) WithProfile(name string) (Config, error) {
+	for _, p := range c.Profiles {
+		if p.Name == name {
+			c.Profile = p.Name
+			c.CodeEtablissement = p.CodeEtablissement
+			c.Identifiant = p.Identifiant
+			c.PIN = p.PIN
+			c.FeedToken = p.FeedToken
+			return c, nil
+		}
+	}
+	return Config{}, fmt.Errorf("unknown profile %q, expected one of: %s", name, strings.Join(c.ProfileNames(), ", "))
+}
+
+// ProfileRateLimits returns the rate limits of the active profile, the
+// global ones unless the profile has its own
+func (c Config) ProfileRateLimits() RateLimits {
+	if i := c.profileIndex(c.Profile); i >= 0 && c.Profiles[i].RateLimits != nil {
+		return *c.Profiles[i].RateLimits
+	}
+	return c.RateLimits
+}
+
+// ProfileDatabasePath returns the history database of the active profile,
+// profiles other than the default one get their own file next to it
+func (c Config) ProfileDatabasePath() string {
+	if c.Profile == "" || c.Profile == DefaultProfile {
+		return c.DatabasePath
+	}
+	ext := filepath.Ext(c.DatabasePath)
+	return strings.TrimSuffix(c.DatabasePath, ext) + "-" + c.Profile + ext
+}
+
+// splitList splits a comma-separated list, ignoring empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 // NewTestConfig creates a Config instance for testing
 func NewTestConfig() Config {
-	return Config{
-		CodeEtablissement: "test-code",
-		Identifiant:       "test-id",
-		PIN:               "test-pin",
-	}
+	cfg := defaults()
+	cfg.Profile = DefaultProfile
+	cfg.CodeEtablissement = "test-code"
+	cfg.Identifiant = "test-id"
+	cfg.PIN = "test-pin"
+	cfg.FeedToken = "test-feed-token"
+	cfg.Profiles = []Profile{{
+		Name:              DefaultProfile,
+		CodeEtablissement: cfg.CodeEtablissement,
+		Identifiant:       cfg.Identifiant,
+		PIN:               cfg.PIN,
+		FeedToken:         cfg.FeedToken,
+	}}
+	return cfg
 }
@@ -2,7 +2,11 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewConfig(t *testing.T) {
@@ -64,3 +68,548 @@ func TestNewTestConfig(t *testing.T) {
 		t.Errorf("Expected test-pin, got %s", config.PIN)
 	}
 }
+
+func TestNewConfig_RefreshInterval(t *testing.T) {
+	t.Setenv("SOWESIGN_CODE_ETABLISSEMENT", "test-code")
+	t.Setenv("SOWESIGN_IDENTIFIANT", "test-id")
+	t.Setenv("SOWESIGN_PIN", "test-pin")
+
+	t.Setenv("SWS_REFRESH_INTERVAL", "30s")
+	config, err := NewConfig()
+	if err != nil {
+		t.Fatalf("NewConfig() failed: %v", err)
+	}
+	if config.RefreshInterval != 30*time.Second {
+		t.Errorf("Expected 30s, got %s", config.RefreshInterval)
+	}
+
+	t.Setenv("SWS_REFRESH_INTERVAL", "soon")
+	if _, err := NewConfig(); err == nil {
+		t.Error("Expected error with invalid refresh interval")
+	}
+}
+
+func TestNewConfig_Courses(t *testing.T) {
+	t.Setenv("SOWESIGN_CODE_ETABLISSEMENT", "test-code")
+	t.Setenv("SOWESIGN_IDENTIFIANT", "test-id")
+	t.Setenv("SOWESIGN_PIN", "test-pin")
+
+	config, err := NewConfig()
+	if err != nil {
+		t.Fatalf("NewConfig() failed: %v", err)
+	}
+	if config.Courses != (Courses{Limit: 8}) {
+		t.Errorf("Expected a single page of 8 courses by default, got %+v", config.Courses)
+	}
+
+	t.Setenv("SWS_COURSE_LIMIT", "20")
+	t.Setenv("SWS_COURSE_HORIZON", "168h")
+	if config, err = NewConfig(); err != nil {
+		t.Fatalf("NewConfig() failed: %v", err)
+	}
+	if config.Courses != (Courses{Limit: 20, Horizon: 7 * 24 * time.Hour}) {
+		t.Errorf("Expected 20 courses per page over a week, got %+v", config.Courses)
+	}
+
+	t.Setenv("SWS_COURSE_LIMIT", "0")
+	if _, err := NewConfig(); err == nil {
+		t.Error("Expected error with a zero course limit")
+	}
+}
+
+func TestNewConfig_FeedToken(t *testing.T) {
+	t.Setenv("SOWESIGN_CODE_ETABLISSEMENT", "test-code")
+	t.Setenv("SOWESIGN_IDENTIFIANT", "test-id")
+	t.Setenv("SOWESIGN_PIN", "test-pin")
+	t.Setenv("SWS_FEED_TOKEN", "")
+
+	// Without a configured token, a random one is kept in the history database
+	config, err := NewConfig()
+	if err != nil {
+		t.Fatalf("NewConfig() failed: %v", err)
+	}
+	if config.FeedToken != "" {
+		t.Errorf("Expected no feed token, got %q", config.FeedToken)
+	}
+
+	t.Setenv("SWS_FEED_TOKEN", "custom-token")
+	custom, err := NewConfig()
+	if err != nil {
+		t.Fatalf("NewConfig() failed: %v", err)
+	}
+	if custom.FeedToken != "custom-token" {
+		t.Errorf("Expected custom-token, got %q", custom.FeedToken)
+	}
+}
+
+func TestNewConfig_Reminders(t *testing.T) {
+	t.Setenv("SOWESIGN_CODE_ETABLISSEMENT", "test-code")
+	t.Setenv("SOWESIGN_IDENTIFIANT", "test-id")
+	t.Setenv("SOWESIGN_PIN", "test-pin")
+	t.Setenv("SWS_REMINDER_OFFSETS", "15m, 1h,")
+	t.Setenv("SWS_SMTP_ADDR", "localhost:25")
+	t.Setenv("SWS_SMTP_FROM", "sws@example.com")
+	t.Setenv("SWS_SMTP_TO", "a@example.com,b@example.com")
+
+	config, err := NewConfig()
+	if err != nil {
+		t.Fatalf("NewConfig() failed: %v", err)
+	}
+	if len(config.ReminderOffsets) != 2 || config.ReminderOffsets[0] != 15*time.Minute || config.ReminderOffsets[1] != time.Hour {
+		t.Errorf("Expected [15m 1h], got %v", config.ReminderOffsets)
+	}
+	if len(config.Notifiers.SMTPTo) != 2 {
+		t.Errorf("Expected 2 recipients, got %v", config.Notifiers.SMTPTo)
+	}
+
+	t.Setenv("SWS_SMTP_TO", "")
+	if _, err := NewConfig(); err == nil {
+		t.Error("Expected error with SMTP server but no recipient")
+	}
+
+	t.Setenv("SWS_SMTP_ADDR", "")
+	t.Setenv("SWS_REMINDER_OFFSETS", "-5m")
+	if _, err := NewConfig(); err == nil {
+		t.Error("Expected error with negative reminder offset")
+	}
+}
+
+func TestNewConfig_Digest(t *testing.T) {
+	t.Setenv("SOWESIGN_CODE_ETABLISSEMENT", "test-code")
+	t.Setenv("SOWESIGN_IDENTIFIANT", "test-id")
+	t.Setenv("SOWESIGN_PIN", "test-pin")
+
+	t.Setenv("SWS_DIGEST_TIME", "07:30")
+	t.Setenv("SWS_DIGEST_TIMEZONE", "")
+	config, err := NewConfig()
+	if err != nil {
+		t.Fatalf("NewConfig() failed: %v", err)
+	}
+	if config.DigestTime != "07:30" || config.DigestTimezone != "Europe/Paris" {
+		t.Errorf("Expected 07:30 Europe/Paris, got %s %s", config.DigestTime, config.DigestTimezone)
+	}
+
+	t.Setenv("SWS_DIGEST_TIME", "7h30")
+	if _, err := NewConfig(); err == nil {
+		t.Error("Expected error with invalid digest time")
+	}
+
+	t.Setenv("SWS_DIGEST_TIME", "07:30")
+	t.Setenv("SWS_DIGEST_TIMEZONE", "Mars/Olympus_Mons")
+	if _, err := NewConfig(); err == nil {
+		t.Error("Expected error with invalid digest timezone")
+	}
+}
+
+func TestNewConfig_Profiles(t *testing.T) {
+	t.Setenv("SOWESIGN_CODE_ETABLISSEMENT", "test-code")
+	t.Setenv("SOWESIGN_IDENTIFIANT", "test-id")
+	t.Setenv("SOWESIGN_PIN", "test-pin")
+	t.Setenv("SWS_FEED_TOKEN", "")
+	t.Setenv("SWS_DATABASE_PATH", "data/sws.db")
+	t.Setenv("SOWESIGN_PROFILES", "work, night-school")
+	t.Setenv("SOWESIGN_WORK_CODE_ETABLISSEMENT", "work-code")
+	t.Setenv("SOWESIGN_WORK_IDENTIFIANT", "work-id")
+	t.Setenv("SOWESIGN_WORK_PIN", "work-pin")
+	t.Setenv("SWS_WORK_FEED_TOKEN", "work-token")
+	t.Setenv("SOWESIGN_NIGHT_SCHOOL_CODE_ETABLISSEMENT", "school-code")
+	t.Setenv("SOWESIGN_NIGHT_SCHOOL_IDENTIFIANT", "school-id")
+	t.Setenv("SOWESIGN_NIGHT_SCHOOL_PIN", "school-pin")
+
+	config, err := NewConfig()
+	if err != nil {
+		t.Fatalf("NewConfig() failed: %v", err)
+	}
+
+	names := config.ProfileNames()
+	if len(names) != 3 || names[0] != DefaultProfile || names[1] != "work" || names[2] != "night-school" {
+		t.Fatalf("Expected [default work night-school], got %v", names)
+	}
+	if config.Profile != DefaultProfile || config.CodeEtablissement != "test-code" {
+		t.Errorf("Expected the default profile to be active, got %q", config.Profile)
+	}
+
+	work, err := config.WithProfile("work")
+	if err != nil {
+		t.Fatalf("WithProfile() failed: %v", err)
+	}
+	if work.CodeEtablissement != "work-code" || work.PIN != "work-pin" || work.FeedToken != "work-token" {
+		t.Errorf("Expected work credentials, got %+v", work)
+	}
+	if got := work.ProfileDatabasePath(); got != "data/sws-work.db" {
+		t.Errorf("ProfileDatabasePath() = %q, want %q", got, "data/sws-work.db")
+	}
+	if got := config.ProfileDatabasePath(); got != "data/sws.db" {
+		t.Errorf("ProfileDatabasePath() = %q, want %q", got, "data/sws.db")
+	}
+
+	school, err := config.WithProfile("night-school")
+	if err != nil {
+		t.Fatalf("WithProfile() failed: %v", err)
+	}
+	if school.FeedToken != "" {
+		t.Errorf("Expected no feed token, got %q", school.FeedToken)
+	}
+
+	if _, err := config.WithProfile("unknown"); err == nil {
+		t.Error("Expected error with an unknown profile")
+	}
+}
+
+func TestNewConfig_InvalidProfiles(t *testing.T) {
+	t.Setenv("SOWESIGN_CODE_ETABLISSEMENT", "")
+	t.Setenv("SOWESIGN_IDENTIFIANT", "")
+	t.Setenv("SOWESIGN_PIN", "")
+	t.Setenv("SOWESIGN_WORK_CODE_ETABLISSEMENT", "work-code")
+	t.Setenv("SOWESIGN_WORK_IDENTIFIANT", "work-id")
+	t.Setenv("SOWESIGN_WORK_PIN", "work-pin")
+
+	t.Setenv("SOWESIGN_PROFILES", "work")
+	config, err := NewConfig()
+	if err != nil {
+		t.Fatalf("NewConfig() failed: %v", err)
+	}
+	if config.Profile != "work" {
+		t.Errorf("Expected the work profile to be active, got %q", config.Profile)
+	}
+
+	for _, profiles := range []string{"Work", "work,work", "work,school"} {
+		t.Setenv("SOWESIGN_PROFILES", profiles)
+		if _, err := NewConfig(); err == nil {
+			t.Errorf("Expected error with SOWESIGN_PROFILES=%q", profiles)
+		}
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sws.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_File(t *testing.T) {
+	t.Setenv("SOWESIGN_CODE_ETABLISSEMENT", "")
+	t.Setenv("SOWESIGN_IDENTIFIANT", "")
+	t.Setenv("SOWESIGN_PIN", "env-pin")
+	t.Setenv("SOWESIGN_PROFILES", "")
+	t.Setenv("SWS_REFRESH_INTERVAL", "")
+	t.Setenv("SWS_CACHE_TTL", "2h")
+
+	path := writeConfigFile(t, `
+listen: ":9090"
+refresh_interval: 1m
+cache_ttl: 30m
+courses:
+  limit: 50
+  horizon: 720h
+reminders:
+  offsets: [15m, 1h]
+digest:
+  time: "07:30"
+auth:
+  secure_cookies: true
+profiles:
+  - name: default
+    code_etablissement: file-code
+    identifiant: file-id
+    pin: file-pin
+  - name: work
+    code_etablissement: work-code
+    identifiant: work-id
+    pin: work-pin
+`)
+
+	config, err := Load(Options{Path: path})
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if config.ListenAddr != ":9090" || config.RefreshInterval != time.Minute || config.DigestTime != "07:30" {
+		t.Errorf("Expected file settings, got %+v", config)
+	}
+	if config.CacheTTL != 2*time.Hour {
+		t.Errorf("Expected the environment to override the file, got cache TTL %s", config.CacheTTL)
+	}
+	if !config.Auth.SecureCookies {
+		t.Error("Expected secure cookies from the file")
+	}
+	if config.Courses.Limit != 50 || config.Courses.Horizon != 30*24*time.Hour {
+		t.Errorf("Expected 50 courses per page over 30 days, got %+v", config.Courses)
+	}
+	if config.DatabasePath != defaultDatabasePath {
+		t.Errorf("Expected default database path, got %q", config.DatabasePath)
+	}
+	if len(config.ReminderOffsets) != 2 || config.ReminderOffsets[1] != time.Hour {
+		t.Errorf("Expected [15m 1h], got %v", config.ReminderOffsets)
+	}
+	if config.CodeEtablissement != "file-code" || config.PIN != "env-pin" {
+		t.Errorf("Expected file credentials with the PIN from the environment, got %q %q", config.CodeEtablissement, config.PIN)
+	}
+	if names := config.ProfileNames(); len(names) != 2 || names[1] != "work" {
+		t.Errorf("Expected [default work], got %v", names)
+	}
+}
+
+func TestLoad_RateLimits(t *testing.T) {
+	t.Setenv("SOWESIGN_CODE_ETABLISSEMENT", "")
+	t.Setenv("SOWESIGN_IDENTIFIANT", "")
+	t.Setenv("SOWESIGN_PIN", "")
+	t.Setenv("SOWESIGN_PROFILES", "")
+	t.Setenv("SWS_RATE_LIMIT_CLIENT", "5/1s")
+	t.Setenv("SWS_NIGHT_RATE_LIMIT_UPSTREAM", "0")
+
+	path := writeConfigFile(t, `
+rate_limits:
+  upstream: {requests: 10, per: 1m, burst: 3}
+profiles:
+  - name: work
+    code_etablissement: work-code
+    identifiant: work-id
+    pin: work-pin
+    rate_limits:
+      upstream: {requests: 2, per: 1m}
+  - name: night
+    code_etablissement: night-code
+    identifiant: night-id
+    pin: night-pin
+  - name: day
+    code_etablissement: day-code
+    identifiant: day-id
+    pin: day-pin
+`)
+
+	cfg, err := Load(Options{Path: path})
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	tests := []struct {
+		profile      string
+		wantClient   Rate
+		wantUpstream Rate
+	}{
+		{"work", Rate{Requests: 30, Per: time.Minute, Burst: 10}, Rate{Requests: 2, Per: time.Minute}},
+		{"night", Rate{Requests: 5, Per: time.Second, Burst: 5}, Rate{}},
+		{"day", Rate{Requests: 5, Per: time.Second, Burst: 5}, Rate{Requests: 10, Per: time.Minute, Burst: 3}},
+	}
+	for _, tt := range tests {
+		got, err := cfg.WithProfile(tt.profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if limits := got.ProfileRateLimits(); limits.Client != tt.wantClient || limits.Upstream != tt.wantUpstream {
+			t.Errorf("ProfileRateLimits() of %s = %+v, want client %+v and upstream %+v", tt.profile, limits, tt.wantClient, tt.wantUpstream)
+		}
+	}
+}
+
+func TestLoad_InvalidFile(t *testing.T) {
+	t.Setenv("SOWESIGN_CODE_ETABLISSEMENT", "test-code")
+	t.Setenv("SOWESIGN_IDENTIFIANT", "test-id")
+	t.Setenv("SOWESIGN_PIN", "test-pin")
+	t.Setenv("SOWESIGN_PROFILES", "")
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "unknown field",
+			content: "refresh: 1m\n",
+			want:    "field refresh not found",
+		},
+		{
+			name:    "invalid duration",
+			content: "cache_ttl: soon\n",
+			want:    "time.Duration",
+		},
+		{
+			name:    "negative duration",
+			content: "refresh_interval: -1m\n",
+			want:    "refresh_interval: must be a positive duration",
+		},
+		{
+			name:    "negative course horizon",
+			content: "courses:\n  horizon: -24h\n",
+			want:    "courses.horizon: must be a positive duration or 0",
+		},
+		{
+			name:    "invalid digest time",
+			content: "digest:\n  time: 7h30\n",
+			want:    "digest.time: must be formatted as HH:MM",
+		},
+		{
+			name:    "incomplete smtp",
+			content: "notifiers:\n  smtp:\n    addr: localhost:25\n",
+			want:    "notifiers.smtp.from: is required",
+		},
+		{
+			name:    "incomplete profile",
+			content: "profiles:\n  - name: work\n    identifiant: work-id\n",
+			want:    "profiles[1].code_etablissement: is required (or set SOWESIGN_WORK_CODE_ETABLISSEMENT)",
+		},
+		{
+			name:    "rate limit without period",
+			content: "rate_limits:\n  client: {requests: 10}\n",
+			want:    "rate_limits.client.per: must be a positive duration",
+		},
+		{
+			name:    "unknown auth mode",
+			content: "auth:\n  mode: ldap\n",
+			want:    "auth.mode: must be local or sowesign",
+		},
+		{
+			name:    "api tokens with sowesign sessions",
+			content: "auth:\n  mode: sowesign\n  api_tokens: [0123456789abcdef]\n",
+			want:    "auth.api_tokens: cannot be used when auth.mode is sowesign",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(Options{Path: writeConfigFile(t, tt.content)})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := Load(Options{Path: filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Error("Expected error with a missing explicit config file")
+	}
+}
+
+func TestLoad_SowesignSessions(t *testing.T) {
+	for _, key := range []string{"SOWESIGN_CODE_ETABLISSEMENT", "SOWESIGN_IDENTIFIANT", "SOWESIGN_PIN", "SOWESIGN_PROFILES"} {
+		t.Setenv(key, "")
+	}
+
+	cfg, err := Load(Options{Path: writeConfigFile(t, "auth:\n  mode: sowesign\n  idle_timeout: 10m\n")})
+	if err != nil {
+		t.Fatalf("Load() error = %v, want no profile to be required", err)
+	}
+	if !cfg.Auth.SowesignSessions() || !cfg.Auth.Enabled() {
+		t.Error("Load() should enable Sowesign sessions")
+	}
+	if cfg.Auth.IdleTimeout != 10*time.Minute {
+		t.Errorf("Auth.IdleTimeout = %v, want 10m", cfg.Auth.IdleTimeout)
+	}
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	config := NewTestConfig()
+	config.Notifiers.SMTPPassword = "smtp-secret"
+
+	out, err := config.Redacted().YAML()
+	if err != nil {
+		t.Fatalf("YAML() failed: %v", err)
+	}
+
+	for _, secret := range []string{"test-pin", config.FeedToken, "smtp-secret"} {
+		if strings.Contains(string(out), secret) {
+			t.Errorf("YAML() leaks %q:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(string(out), "identifiant: test-id") {
+		t.Errorf("YAML() should keep non secret settings:\n%s", out)
+	}
+	if config.PIN != "test-pin" || config.Profiles[0].PIN != "test-pin" {
+		t.Error("Redacted() should not modify the original configuration")
+	}
+}
+
+func TestChanges(t *testing.T) {
+	old := NewTestConfig()
+	new := NewTestConfig()
+	new.RefreshInterval = time.Minute
+	new.DigestTime = "07:30"
+	new.Profiles[0].PIN = "new-pin"
+
+	changes, err := Changes(old, new)
+	if err != nil {
+		t.Fatalf("Changes() failed: %v", err)
+	}
+
+	want := []string{
+		"digest.time: set to 07:30",
+		"profiles[default].pin: changed",
+		"refresh_interval: 5m0s -> 1m0s",
+	}
+	if strings.Join(changes, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Changes() = %q, want %q", changes, want)
+	}
+
+	if changes, _ := Changes(old, old); len(changes) != 0 {
+		t.Errorf("Changes() = %q, want none", changes)
+	}
+}
+
+func TestChanges_Profiles(t *testing.T) {
+	old := NewTestConfig()
+	old.Profiles = append(old.Profiles,
+		Profile{Name: "work", CodeEtablissement: "work-code", Identifiant: "work-id", PIN: "work-pin"},
+		Profile{Name: "school", CodeEtablissement: "school-code", Identifiant: "school-id", PIN: "school-pin"})
+
+	// Reordering profiles changes nothing
+	reordered := old
+	reordered.Profiles = []Profile{old.Profiles[2], old.Profiles[0], old.Profiles[1]}
+	if changes, err := Changes(old, reordered); err != nil || len(changes) != 0 {
+		t.Errorf("Changes() = %q, %v, want none", changes, err)
+	}
+
+	// Removing a profile only reports that profile
+	removed := old
+	removed.Profiles = []Profile{old.Profiles[0], old.Profiles[2]}
+	changes, err := Changes(old, removed)
+	if err != nil {
+		t.Fatalf("Changes() failed: %v", err)
+	}
+	want := []string{
+		"profiles[work].code_etablissement: removed (was work-code)",
+		"profiles[work].identifiant: removed (was work-id)",
+		"profiles[work].pin: removed (was [redacted])",
+	}
+	if strings.Join(changes, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Changes() = %q, want %q", changes, want)
+	}
+}
+
+func TestProfileChanged(t *testing.T) {
+	old := NewTestConfig()
+	old.Profiles = append(old.Profiles, Profile{Name: "work", CodeEtablissement: "work-code", Identifiant: "work-id", PIN: "work-pin"})
+
+	otherProfile := old
+	otherProfile.Profiles = slices.Clone(old.Profiles)
+	otherProfile.Profiles[1].PIN = "new-pin"
+	ownRateLimits := old
+	ownRateLimits.Profiles = slices.Clone(old.Profiles)
+	ownRateLimits.Profiles[0].RateLimits = &RateLimits{Upstream: Rate{Requests: 1, Per: time.Minute, Burst: 1}}
+	global := old
+	global.RefreshInterval = 2 * old.RefreshInterval
+	removed := old
+	removed.Profiles = old.Profiles[1:]
+
+	tests := []struct {
+		name string
+		next Config
+		want bool
+	}{
+		{name: "unchanged", next: old, want: false},
+		{name: "other profile", next: otherProfile, want: false},
+		{name: "own rate limits", next: ownRateLimits, want: true},
+		{name: "global setting", next: global, want: true},
+		{name: "removed", next: removed, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ProfileChanged(old, tt.next, DefaultProfile); got != tt.want {
+				t.Errorf("ProfileChanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
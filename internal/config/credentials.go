@@ -0,0 +1,188 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	defaultCredentialsPath = "sws.credentials"
+	credentialsVersion     = 1
+
+	// scrypt parameters recommended for interactive logins
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+var (
+	// ErrCredentialsLocked is returned when a credentials file exists but no
+	// passphrase was given to unlock it
+	ErrCredentialsLocked = errors.New("credentials file is locked, set SWS_PASSPHRASE")
+	// ErrWrongPassphrase is returned when the credentials file cannot be
+	// decrypted with the given passphrase
+	ErrWrongPassphrase = errors.New("wrong passphrase or corrupted credentials file")
+)
+
+// credentialsFile is the on-disk format of the encrypted credentials
+type credentialsFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// ReadCredentials decrypts the profiles stored in a credentials file
+func ReadCredentials(path, passphrase string) ([]Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f credentialsFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s: %w", path, err)
+	}
+	if f.Version != credentialsVersion {
+		return nil, fmt.Errorf("unsupported credentials file version %d", f.Version)
+	}
+
+	gcm, err := credentialsCipher(passphrase, f.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, f.Nonce, f.Ciphertext, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	var profiles []Profile
+	if err := json.Unmarshal(plaintext, &profiles); err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s: %w", path, err)
+	}
+	return profiles, nil
+}
+
+// WriteCredentials encrypts the profiles with a key derived from the
+// passphrase and atomically replaces the credentials file
+func WriteCredentials(path, passphrase string, profiles []Profile) error {
+	if passphrase == "" {
+		return fmt.Errorf("passphrase is required")
+	}
+
+	plaintext, err := json.Marshal(profiles)
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+
+	f := credentialsFile{Version: credentialsVersion, Salt: make([]byte, 16)}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	gcm, err := credentialsCipher(passphrase, f.Salt)
+	if err != nil {
+		return err
+	}
+	f.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	f.Ciphertext = gcm.Seal(nil, f.Nonce, plaintext, nil)
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".sws-credentials-*")
+	if err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write credentials: %w", err)
+	}
+	return nil
+}
+
+// credentialsCipher derives the AES-256-GCM cipher of a passphrase and salt
+func credentialsCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// loadCredentials merges the profiles of the credentials file, when it
+// exists, into the configuration
+func (c *Config) loadCredentials(passphrase string) error {
+	if _, err := os.Stat(c.CredentialsPath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if passphrase == "" {
+		return ErrCredentialsLocked
+	}
+
+	profiles, err := ReadCredentials(c.CredentialsPath, passphrase)
+	if err != nil {
+		return err
+	}
+	for _, p := range profiles {
+		c.MergeProfile(p)
+	}
+	return nil
+}
+
+// MergeProfile adds a profile, or overrides the non-empty fields of the
+// profile with the same name. The default profile is kept first
+func (c *Config) MergeProfile(p Profile) {
+	i := c.profileIndex(p.Name)
+	if i < 0 {
+		if p.Name == DefaultProfile {
+			c.Profiles = append([]Profile{p}, c.Profiles...)
+		} else {
+			c.Profiles = append(c.Profiles, p)
+		}
+		return
+	}
+
+	dst := &c.Profiles[i]
+	setIf(&dst.CodeEtablissement, p.CodeEtablissement)
+	setIf(&dst.Identifiant, p.Identifiant)
+	setIf(&dst.PIN, p.PIN)
+	setIf(&dst.FeedToken, p.FeedToken)
+	if p.RateLimits != nil {
+		dst.RateLimits = p.RateLimits
+	}
+}
+
+// profileIndex returns the index of the named profile, or -1
+func (c Config) profileIndex(name string) int {
+	for i, p := range c.Profiles {
+		if p.Name == name {
+			return i
+		}
+	}
+	return -1
+}
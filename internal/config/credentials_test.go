@@ -0,0 +1,92 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCredentials_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sws.credentials")
+	profiles := []Profile{{Name: DefaultProfile, CodeEtablissement: "code", Identifiant: "id", PIN: "1234"}}
+
+	if err := WriteCredentials(path, "correct horse", profiles); err != nil {
+		t.Fatalf("WriteCredentials() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "1234") {
+		t.Error("credentials file should not contain the PIN in plaintext")
+	}
+
+	got, err := ReadCredentials(path, "correct horse")
+	if err != nil {
+		t.Fatalf("ReadCredentials() failed: %v", err)
+	}
+	if len(got) != 1 || got[0] != profiles[0] {
+		t.Errorf("ReadCredentials() = %+v, want %+v", got, profiles)
+	}
+
+	if _, err := ReadCredentials(path, "wrong"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("ReadCredentials() error = %v, want %v", err, ErrWrongPassphrase)
+	}
+}
+
+func TestLoad_Credentials(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sws.credentials")
+	t.Setenv("SWS_CREDENTIALS_PATH", path)
+	t.Setenv("SWS_PASSPHRASE", "")
+	t.Setenv("SOWESIGN_CODE_ETABLISSEMENT", "")
+	t.Setenv("SOWESIGN_IDENTIFIANT", "")
+	t.Setenv("SOWESIGN_PIN", "")
+	t.Setenv("SOWESIGN_PROFILES", "")
+
+	stored := []Profile{{Name: DefaultProfile, CodeEtablissement: "code", Identifiant: "id", PIN: "stored-pin"}}
+	if err := WriteCredentials(path, "secret", stored); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(Options{}); !errors.Is(err, ErrCredentialsLocked) {
+		t.Errorf("Load() error = %v, want %v", err, ErrCredentialsLocked)
+	}
+
+	config, err := Load(Options{Passphrase: "secret"})
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if config.PIN != "stored-pin" {
+		t.Errorf("Expected the stored PIN, got %q", config.PIN)
+	}
+
+	// Environment variables take precedence over stored credentials, and
+	// secrets can be read from files
+	pinFile := filepath.Join(dir, "pin")
+	if err := os.WriteFile(pinFile, []byte("file-pin\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	passphraseFile := filepath.Join(dir, "passphrase")
+	if err := os.WriteFile(passphraseFile, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOWESIGN_PIN_FILE", pinFile)
+	t.Setenv("SWS_PASSPHRASE_FILE", passphraseFile)
+
+	config, err = Load(Options{})
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if config.PIN != "file-pin" || config.CodeEtablissement != "code" {
+		t.Errorf("Expected the PIN from SOWESIGN_PIN_FILE with stored credentials, got %q %q", config.PIN, config.CodeEtablissement)
+	}
+
+	t.Setenv("SOWESIGN_PIN_FILE", filepath.Join(dir, "missing"))
+	if _, err := Load(Options{}); err == nil || !strings.Contains(err.Error(), "SOWESIGN_PIN_FILE") {
+		t.Errorf("Load() error = %v, want an error about SOWESIGN_PIN_FILE", err)
+	}
+}
@@ -0,0 +1,256 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envLoader reads environment variables, falling back to the file named by
+// <KEY>_FILE as done for Docker and Kubernetes secrets. The first error is
+// kept so that variables can be read in sequence
+type envLoader struct {
+	err error
+}
+
+// get returns the value of a variable, or the trimmed content of its _FILE
+func (l *envLoader) get(key string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+
+	path := os.Getenv(key + "_FILE")
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if l.err == nil {
+			l.err = fmt.Errorf("failed to read %s_FILE: %w", key, err)
+		}
+		return ""
+	}
+	return strings.TrimRight(string(data), "\r\n")
+}
+
+// setString overrides dst with the variable when it is set
+func (l *envLoader) setString(dst *string, key string) {
+	if v := l.get(key); v != "" {
+		*dst = v
+	}
+}
+
+// setDuration overrides dst with the variable when it is set
+func (l *envLoader) setDuration(dst *time.Duration, key string) {
+	v := l.get(key)
+	if v == "" {
+		return
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		if l.err == nil {
+			l.err = fmt.Errorf("%s must be a positive duration, got %q", key, v)
+		}
+		return
+	}
+	*dst = d
+}
+
+// setInt64 overrides dst with the variable when it is set
+func (l *envLoader) setInt64(dst *int64, key string) {
+	v := l.get(key)
+	if v == "" {
+		return
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		if l.err == nil {
+			l.err = fmt.Errorf("%s must be a positive integer, got %q", key, v)
+		}
+		return
+	}
+	*dst = n
+}
+
+// setRate overrides dst with a requests/period variable such as 30/1m, 0
+// disabling the limit. Up to requests requests may be made at once
+func (l *envLoader) setRate(dst *Rate, key string) bool {
+	v := l.get(key)
+	if v == "" {
+		return false
+	}
+	if v == "0" {
+		*dst = Rate{}
+		return true
+	}
+
+	requests, per, ok := strings.Cut(v, "/")
+	n, err := strconv.Atoi(requests)
+	d, derr := time.ParseDuration(per)
+	if !ok || err != nil || derr != nil || n <= 0 || d <= 0 {
+		if l.err == nil {
+			l.err = fmt.Errorf("%s must be formatted as requests/period (e.g. 30/1m) or 0, got %q", key, v)
+		}
+		return false
+	}
+	*dst = Rate{Requests: n, Per: d, Burst: n}
+	return true
+}
+
+// loadEnv overrides the configuration with the environment variables that
+// are set
+func (c *Config) loadEnv() error {
+	var env envLoader
+
+	env.setString(&c.ListenAddr, "SWS_ADDR")
+	env.setString(&c.TLS.CertFile, "SWS_TLS_CERT_FILE")
+	env.setString(&c.TLS.KeyFile, "SWS_TLS_KEY_FILE")
+	env.setInt64(&c.MaxBodySize, "SWS_MAX_BODY_SIZE")
+	env.setString(&c.DatabasePath, "SWS_DATABASE_PATH")
+	env.setString(&c.DigestTime, "SWS_DIGEST_TIME")
+	env.setString(&c.DigestTimezone, "SWS_DIGEST_TIMEZONE")
+	env.setString(&c.Notifiers.WebhookURL, "SWS_WEBHOOK_URL")
+	env.setString(&c.Notifiers.SMTPAddr, "SWS_SMTP_ADDR")
+	env.setString(&c.Notifiers.SMTPUsername, "SWS_SMTP_USERNAME")
+	env.setString(&c.Notifiers.SMTPPassword, "SWS_SMTP_PASSWORD")
+	env.setString(&c.Notifiers.SMTPFrom, "SWS_SMTP_FROM")
+	env.setString(&c.Notifiers.NtfyURL, "SWS_NTFY_URL")
+	env.setString(&c.Notifiers.NtfyToken, "SWS_NTFY_TOKEN")
+	if to := splitList(env.get("SWS_SMTP_TO")); len(to) > 0 {
+		c.Notifiers.SMTPTo = to
+	}
+
+	env.setDuration(&c.RefreshInterval, "SWS_REFRESH_INTERVAL")
+	env.setDuration(&c.CacheTTL, "SWS_CACHE_TTL")
+	if v := env.get("SWS_COURSE_LIMIT"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return fmt.Errorf("SWS_COURSE_LIMIT must be a positive integer, got %q", v)
+		}
+		c.Courses.Limit = limit
+	}
+	env.setDuration(&c.Courses.Horizon, "SWS_COURSE_HORIZON")
+
+	if v := env.get("SWS_REMINDER_OFFSETS"); v != "" {
+		c.ReminderOffsets = nil
+		for _, item := range splitList(v) {
+			d, err := time.ParseDuration(item)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("SWS_REMINDER_OFFSETS must be a list of positive durations, got %q", item)
+			}
+			c.ReminderOffsets = append(c.ReminderOffsets, d)
+		}
+	}
+
+	env.setString(&c.Auth.Mode, "SWS_AUTH_MODE")
+	env.setDuration(&c.Auth.IdleTimeout, "SWS_SESSION_IDLE_TIMEOUT")
+	env.setString(&c.Auth.SessionSecret, "SWS_SESSION_SECRET")
+	env.setDuration(&c.Auth.SessionTTL, "SWS_SESSION_TTL")
+	if tokens := splitList(env.get("SWS_API_TOKENS")); len(tokens) > 0 {
+		c.Auth.APITokens = tokens
+	}
+	if v := env.get("SWS_SECURE_COOKIES"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("SWS_SECURE_COOKIES must be a boolean, got %q", v)
+		}
+		c.Auth.SecureCookies = enabled
+	}
+	if v := env.get("SWS_BASIC_AUTH"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("SWS_BASIC_AUTH must be a boolean, got %q", v)
+		}
+		c.Auth.BasicAuth = enabled
+	}
+	if v := env.get("SWS_AUTH_USERS"); v != "" {
+		c.Auth.Users = nil
+		for _, item := range splitList(v) {
+			username, hash, ok := strings.Cut(item, ":")
+			if !ok {
+				return fmt.Errorf("SWS_AUTH_USERS must be a list of username:bcrypt-hash, got %q", username)
+			}
+			c.Auth.Users = append(c.Auth.Users, User{Username: username, PasswordHash: hash})
+		}
+	}
+
+	env.setString(&c.Tracing.Exporter, "SWS_TRACING_EXPORTER")
+	env.setString(&c.Tracing.Endpoint, "SWS_TRACING_ENDPOINT")
+	if v := env.get("SWS_TRACING_SAMPLE_RATIO"); v != "" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("SWS_TRACING_SAMPLE_RATIO must be a number, got %q", v)
+		}
+		c.Tracing.SampleRatio = ratio
+	}
+
+	env.setRate(&c.RateLimits.Client, "SWS_RATE_LIMIT_CLIENT")
+	env.setRate(&c.RateLimits.Upstream, "SWS_RATE_LIMIT_UPSTREAM")
+
+	if err := c.loadEnvProfiles(&env); err != nil {
+		return err
+	}
+	return env.err
+}
+
+// loadEnvProfiles reads the default profile from the unprefixed SOWESIGN_*
+// variables and the profiles listed in SOWESIGN_PROFILES from
+// SOWESIGN_<NAME>_* variables, overriding the profiles with the same name
+func (c *Config) loadEnvProfiles(env *envLoader) error {
+	if p := envProfile(env, DefaultProfile); c.profileIndex(DefaultProfile) >= 0 ||
+		p.CodeEtablissement != "" || p.Identifiant != "" || p.PIN != "" {
+		c.MergeProfile(p)
+	}
+
+	var seen []string
+	for _, name := range splitList(env.get("SOWESIGN_PROFILES")) {
+		if slices.Contains(seen, name) {
+			return fmt.Errorf("SOWESIGN_PROFILES: duplicate profile %q", name)
+		}
+		seen = append(seen, name)
+		c.MergeProfile(envProfile(env, name))
+	}
+
+	// The unprefixed limits of the default profile are the global ones
+	for i, p := range c.Profiles {
+		if p.Name == DefaultProfile {
+			continue
+		}
+		_, settingsPrefix := profileEnvPrefixes(p.Name)
+		limits := c.RateLimits
+		if p.RateLimits != nil {
+			limits = *p.RateLimits
+		}
+		client := env.setRate(&limits.Client, settingsPrefix+"RATE_LIMIT_CLIENT")
+		upstream := env.setRate(&limits.Upstream, settingsPrefix+"RATE_LIMIT_UPSTREAM")
+		if client || upstream {
+			c.Profiles[i].RateLimits = &limits
+		}
+	}
+	return nil
+}
+
+// envProfile reads the variables of one profile
+func envProfile(env *envLoader, name string) Profile {
+	credentialsPrefix, settingsPrefix := profileEnvPrefixes(name)
+	return Profile{
+		Name:              name,
+		CodeEtablissement: env.get(credentialsPrefix + "CODE_ETABLISSEMENT"),
+		Identifiant:       env.get(credentialsPrefix + "IDENTIFIANT"),
+		PIN:               env.get(credentialsPrefix + "PIN"),
+		FeedToken:         env.get(settingsPrefix + "FEED_TOKEN"),
+	}
+}
+
+// profileEnvPrefixes returns the prefixes of the credentials and settings
+// variables of a profile
+func profileEnvPrefixes(name string) (credentials, settings string) {
+	if name == DefaultProfile {
+		return "SOWESIGN_", "SWS_"
+	}
+	key := strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+	return "SOWESIGN_" + key, "SWS_" + key
+}
@@ -0,0 +1,338 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secrets in the printed configuration
+const redacted = "[redacted]"
+
+// fileConfig is the schema of the YAML config file, documented in
+// sws.example.yaml
+type fileConfig struct {
+	Listen          string          `yaml:"listen,omitempty"`
+	TLS             fileTLS         `yaml:"tls,omitempty"`
+	MaxBodySize     int64           `yaml:"max_body_size,omitempty"`
+	RefreshInterval time.Duration   `yaml:"refresh_interval,omitempty"`
+	CacheTTL        time.Duration   `yaml:"cache_ttl,omitempty"`
+	Courses         fileCourses     `yaml:"courses,omitempty"`
+	DatabasePath    string          `yaml:"database_path,omitempty"`
+	CredentialsPath string          `yaml:"credentials_path,omitempty"`
+	Reminders       fileReminders   `yaml:"reminders,omitempty"`
+	Digest          fileDigest      `yaml:"digest,omitempty"`
+	Notifiers       fileNotifiers   `yaml:"notifiers,omitempty"`
+	Profiles        []fileProfile   `yaml:"profiles,omitempty"`
+	Auth            fileAuth        `yaml:"auth,omitempty"`
+	RateLimits      *fileRateLimits `yaml:"rate_limits,omitempty"`
+	Tracing         fileTracing     `yaml:"tracing,omitempty"`
+}
+
+type fileCourses struct {
+	Limit   int           `yaml:"limit,omitempty"`
+	Horizon time.Duration `yaml:"horizon,omitempty"`
+}
+
+type fileTracing struct {
+	Exporter    string   `yaml:"exporter,omitempty"`
+	Endpoint    string   `yaml:"endpoint,omitempty"`
+	SampleRatio *float64 `yaml:"sample_ratio,omitempty"`
+}
+
+type fileTLS struct {
+	CertFile string `yaml:"cert_file,omitempty"`
+	KeyFile  string `yaml:"key_file,omitempty"`
+}
+
+type fileReminders struct {
+	Offsets []time.Duration `yaml:"offsets,omitempty"`
+}
+
+type fileDigest struct {
+	Time     string `yaml:"time,omitempty"`
+	Timezone string `yaml:"timezone,omitempty"`
+}
+
+type fileNotifiers struct {
+	Webhook fileWebhook `yaml:"webhook,omitempty"`
+	SMTP    fileSMTP    `yaml:"smtp,omitempty"`
+	Ntfy    fileNtfy    `yaml:"ntfy,omitempty"`
+}
+
+type fileWebhook struct {
+	URL string `yaml:"url,omitempty"`
+}
+
+type fileSMTP struct {
+	Addr     string   `yaml:"addr,omitempty"`
+	Username string   `yaml:"username,omitempty"`
+	Password string   `yaml:"password,omitempty"`
+	From     string   `yaml:"from,omitempty"`
+	To       []string `yaml:"to,omitempty"`
+}
+
+type fileNtfy struct {
+	URL   string `yaml:"url,omitempty"`
+	Token string `yaml:"token,omitempty"`
+}
+
+type fileProfile struct {
+	Name              string          `yaml:"name"`
+	CodeEtablissement string          `yaml:"code_etablissement,omitempty"`
+	Identifiant       string          `yaml:"identifiant,omitempty"`
+	PIN               string          `yaml:"pin,omitempty"`
+	FeedToken         string          `yaml:"feed_token,omitempty"`
+	RateLimits        *fileRateLimits `yaml:"rate_limits,omitempty"`
+}
+
+type fileRateLimits struct {
+	Client   *fileRate `yaml:"client,omitempty"`
+	Upstream *fileRate `yaml:"upstream,omitempty"`
+}
+
+// fileRate is a rate limit, requests: 0 disabling it
+type fileRate struct {
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per,omitempty"`
+	Burst    int           `yaml:"burst,omitempty"`
+}
+
+// apply overrides the limits set in the file
+func (f *fileRateLimits) apply(l *RateLimits) {
+	if f == nil {
+		return
+	}
+	if f.Client != nil {
+		l.Client = Rate(*f.Client)
+	}
+	if f.Upstream != nil {
+		l.Upstream = Rate(*f.Upstream)
+	}
+}
+
+// newFileRateLimits converts rate limits to the config file schema
+func newFileRateLimits(l *RateLimits) *fileRateLimits {
+	if l == nil {
+		return nil
+	}
+	client, upstream := fileRate(l.Client), fileRate(l.Upstream)
+	return &fileRateLimits{Client: &client, Upstream: &upstream}
+}
+
+type fileAuth struct {
+	Mode          string        `yaml:"mode,omitempty"`
+	Users         []fileUser    `yaml:"users,omitempty"`
+	SessionSecret string        `yaml:"session_secret,omitempty"`
+	SessionTTL    time.Duration `yaml:"session_ttl,omitempty"`
+	SecureCookies bool          `yaml:"secure_cookies,omitempty"`
+	BasicAuth     bool          `yaml:"basic_auth,omitempty"`
+	APITokens     []string      `yaml:"api_tokens,omitempty"`
+	IdleTimeout   time.Duration `yaml:"idle_timeout,omitempty"`
+}
+
+type fileUser struct {
+	Username     string `yaml:"username"`
+	PasswordHash string `yaml:"password_hash"`
+}
+
+// loadFile overrides the configuration with the settings of a YAML file,
+// rejecting unknown fields
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return err
+		}
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var f fileConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	f.apply(c)
+	return nil
+}
+
+// apply copies the settings present in the file to the configuration
+func (f fileConfig) apply(c *Config) {
+	setIf(&c.ListenAddr, f.Listen)
+	setIf(&c.TLS.CertFile, f.TLS.CertFile)
+	setIf(&c.TLS.KeyFile, f.TLS.KeyFile)
+	setIf(&c.MaxBodySize, f.MaxBodySize)
+	setIf(&c.RefreshInterval, f.RefreshInterval)
+	setIf(&c.CacheTTL, f.CacheTTL)
+	setIf(&c.Courses.Limit, f.Courses.Limit)
+	setIf(&c.Courses.Horizon, f.Courses.Horizon)
+	setIf(&c.DatabasePath, f.DatabasePath)
+	setIf(&c.CredentialsPath, f.CredentialsPath)
+	setIf(&c.DigestTime, f.Digest.Time)
+	setIf(&c.DigestTimezone, f.Digest.Timezone)
+	setIf(&c.Notifiers.WebhookURL, f.Notifiers.Webhook.URL)
+	setIf(&c.Notifiers.SMTPAddr, f.Notifiers.SMTP.Addr)
+	setIf(&c.Notifiers.SMTPUsername, f.Notifiers.SMTP.Username)
+	setIf(&c.Notifiers.SMTPPassword, f.Notifiers.SMTP.Password)
+	setIf(&c.Notifiers.SMTPFrom, f.Notifiers.SMTP.From)
+	setIf(&c.Notifiers.NtfyURL, f.Notifiers.Ntfy.URL)
+	setIf(&c.Notifiers.NtfyToken, f.Notifiers.Ntfy.Token)
+	if len(f.Notifiers.SMTP.To) > 0 {
+		c.Notifiers.SMTPTo = f.Notifiers.SMTP.To
+	}
+	if len(f.Reminders.Offsets) > 0 {
+		c.ReminderOffsets = f.Reminders.Offsets
+	}
+
+	setIf(&c.Auth.Mode, f.Auth.Mode)
+	setIf(&c.Auth.IdleTimeout, f.Auth.IdleTimeout)
+	setIf(&c.Auth.SessionSecret, f.Auth.SessionSecret)
+	setIf(&c.Auth.SessionTTL, f.Auth.SessionTTL)
+	setIf(&c.Auth.SecureCookies, f.Auth.SecureCookies)
+	setIf(&c.Auth.BasicAuth, f.Auth.BasicAuth)
+	if len(f.Auth.APITokens) > 0 {
+		c.Auth.APITokens = f.Auth.APITokens
+	}
+	for _, u := range f.Auth.Users {
+		c.Auth.Users = append(c.Auth.Users, User(u))
+	}
+
+	f.RateLimits.apply(&c.RateLimits)
+
+	setIf(&c.Tracing.Exporter, f.Tracing.Exporter)
+	setIf(&c.Tracing.Endpoint, f.Tracing.Endpoint)
+	if f.Tracing.SampleRatio != nil {
+		c.Tracing.SampleRatio = *f.Tracing.SampleRatio
+	}
+
+	for _, p := range f.Profiles {
+		profile := Profile{
+			Name:              p.Name,
+			CodeEtablissement: p.CodeEtablissement,
+			Identifiant:       p.Identifiant,
+			PIN:               p.PIN,
+			FeedToken:         p.FeedToken,
+		}
+		if p.RateLimits != nil {
+			// Limits missing from the profile are the global ones
+			limits := c.RateLimits
+			p.RateLimits.apply(&limits)
+			profile.RateLimits = &limits
+		}
+		c.Profiles = append(c.Profiles, profile)
+	}
+}
+
+// setIf overrides dst with v unless v is the zero value
+func setIf[T comparable](dst *T, v T) {
+	var zero T
+	if v != zero {
+		*dst = v
+	}
+}
+
+// YAML encodes the configuration using the config file schema
+func (c Config) YAML() ([]byte, error) {
+	f := fileConfig{
+		Listen:          c.ListenAddr,
+		TLS:             fileTLS(c.TLS),
+		MaxBodySize:     c.MaxBodySize,
+		RefreshInterval: c.RefreshInterval,
+		CacheTTL:        c.CacheTTL,
+		Courses:         fileCourses(c.Courses),
+		DatabasePath:    c.DatabasePath,
+		CredentialsPath: c.CredentialsPath,
+		Reminders:       fileReminders{Offsets: c.ReminderOffsets},
+		Digest:          fileDigest{Time: c.DigestTime, Timezone: c.DigestTimezone},
+		Notifiers: fileNotifiers{
+			Webhook: fileWebhook{URL: c.Notifiers.WebhookURL},
+			SMTP: fileSMTP{
+				Addr:     c.Notifiers.SMTPAddr,
+				Username: c.Notifiers.SMTPUsername,
+				Password: c.Notifiers.SMTPPassword,
+				From:     c.Notifiers.SMTPFrom,
+				To:       c.Notifiers.SMTPTo,
+			},
+			Ntfy: fileNtfy{URL: c.Notifiers.NtfyURL, Token: c.Notifiers.NtfyToken},
+		},
+	}
+	for _, p := range c.Profiles {
+		f.Profiles = append(f.Profiles, fileProfile{
+			Name:              p.Name,
+			CodeEtablissement: p.CodeEtablissement,
+			Identifiant:       p.Identifiant,
+			PIN:               p.PIN,
+			FeedToken:         p.FeedToken,
+			RateLimits:        newFileRateLimits(p.RateLimits),
+		})
+	}
+	f.RateLimits = newFileRateLimits(&c.RateLimits)
+	f.Tracing = fileTracing{
+		Exporter:    c.Tracing.Exporter,
+		Endpoint:    c.Tracing.Endpoint,
+		SampleRatio: &c.Tracing.SampleRatio,
+	}
+	f.Auth = fileAuth{
+		Mode:          c.Auth.Mode,
+		IdleTimeout:   c.Auth.IdleTimeout,
+		SessionSecret: c.Auth.SessionSecret,
+		SessionTTL:    c.Auth.SessionTTL,
+		SecureCookies: c.Auth.SecureCookies,
+		BasicAuth:     c.Auth.BasicAuth,
+		APITokens:     c.Auth.APITokens,
+	}
+	for _, u := range c.Auth.Users {
+		f.Auth.Users = append(f.Auth.Users, fileUser(u))
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(f); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Redacted returns a copy of the configuration with its secrets masked,
+// suitable for printing
+func (c Config) Redacted() Config {
+	redact := func(s *string) {
+		if *s != "" {
+			*s = redacted
+		}
+	}
+
+	redact(&c.PIN)
+	redact(&c.FeedToken)
+	redact(&c.Notifiers.WebhookURL)
+	redact(&c.Notifiers.SMTPPassword)
+	redact(&c.Notifiers.NtfyToken)
+
+	c.Profiles = append([]Profile(nil), c.Profiles...)
+	for i := range c.Profiles {
+		redact(&c.Profiles[i].PIN)
+		redact(&c.Profiles[i].FeedToken)
+	}
+
+	redact(&c.Auth.SessionSecret)
+	c.Auth.Users = append([]User(nil), c.Auth.Users...)
+	for i := range c.Auth.Users {
+		redact(&c.Auth.Users[i].PasswordHash)
+	}
+	c.Auth.APITokens = append([]string(nil), c.Auth.APITokens...)
+	for i := range c.Auth.APITokens {
+		redact(&c.Auth.APITokens[i])
+	}
+	return c
+}
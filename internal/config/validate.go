@@ -0,0 +1,181 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Validate checks the configuration, reporting every invalid setting by its
+// path in the config file
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(path, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if c.ListenAddr == "" {
+		invalid("listen", "is required")
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		invalid("tls", "cert_file and key_file must be set together")
+	}
+	if c.MaxBodySize <= 0 {
+		invalid("max_body_size", "must be a positive number of bytes, got %d", c.MaxBodySize)
+	}
+	if c.RefreshInterval <= 0 {
+		invalid("refresh_interval", "must be a positive duration, got %s", c.RefreshInterval)
+	}
+	if c.CacheTTL <= 0 {
+		invalid("cache_ttl", "must be a positive duration, got %s", c.CacheTTL)
+	}
+	if c.Courses.Limit <= 0 {
+		invalid("courses.limit", "must be a positive number of courses, got %d", c.Courses.Limit)
+	}
+	if c.Courses.Horizon < 0 {
+		invalid("courses.horizon", "must be a positive duration or 0 for a single page, got %s", c.Courses.Horizon)
+	}
+	if c.DatabasePath == "" {
+		invalid("database_path", "is required")
+	}
+
+	for i, d := range c.ReminderOffsets {
+		if d <= 0 {
+			invalid(fmt.Sprintf("reminders.offsets[%d]", i), "must be a positive duration, got %s", d)
+		}
+	}
+
+	if c.DigestTime != "" {
+		if _, err := time.Parse("15:04", c.DigestTime); err != nil {
+			invalid("digest.time", "must be formatted as HH:MM, got %q", c.DigestTime)
+		}
+	}
+	if _, err := time.LoadLocation(c.DigestTimezone); err != nil {
+		invalid("digest.timezone", "is not a valid timezone: %v", err)
+	}
+
+	if c.Notifiers.WebhookURL != "" && !validHTTPURL(c.Notifiers.WebhookURL) {
+		invalid("notifiers.webhook.url", "must be an http or https URL")
+	}
+	if c.Notifiers.NtfyURL != "" && !validHTTPURL(c.Notifiers.NtfyURL) {
+		invalid("notifiers.ntfy.url", "must be an http or https URL")
+	}
+	if c.Notifiers.SMTPAddr != "" {
+		if c.Notifiers.SMTPFrom == "" {
+			invalid("notifiers.smtp.from", "is required when notifiers.smtp.addr is set")
+		}
+		if len(c.Notifiers.SMTPTo) == 0 {
+			invalid("notifiers.smtp.to", "is required when notifiers.smtp.addr is set")
+		}
+	}
+
+	if len(c.Profiles) == 0 && !c.Auth.SowesignSessions() {
+		invalid("profiles", "at least one profile is required, set SOWESIGN_CODE_ETABLISSEMENT, SOWESIGN_IDENTIFIANT and SOWESIGN_PIN")
+	}
+	seen := make(map[string]bool)
+	for i, p := range c.Profiles {
+		path := fmt.Sprintf("profiles[%d]", i)
+		if !profileNamePattern.MatchString(p.Name) {
+			invalid(path+".name", "%q is invalid, use lowercase letters, digits, - and _", p.Name)
+		} else if seen[p.Name] {
+			invalid(path+".name", "duplicate profile %q", p.Name)
+		}
+		seen[p.Name] = true
+
+		prefix, _ := profileEnvPrefixes(p.Name)
+		for _, field := range []struct{ name, value, env string }{
+			{"code_etablissement", p.CodeEtablissement, prefix + "CODE_ETABLISSEMENT"},
+			{"identifiant", p.Identifiant, prefix + "IDENTIFIANT"},
+			{"pin", p.PIN, prefix + "PIN"},
+		} {
+			if field.value == "" {
+				invalid(path+"."+field.name, "is required (or set %s)", field.env)
+			}
+		}
+	}
+
+	switch c.Tracing.Exporter {
+	case "", TracingOTLP, TracingStdout:
+	default:
+		invalid("tracing.exporter", "must be %s, %s or empty, got %q", TracingOTLP, TracingStdout, c.Tracing.Exporter)
+	}
+	if c.Tracing.Endpoint != "" && !validHTTPURL(c.Tracing.Endpoint) {
+		invalid("tracing.endpoint", "must be an http or https URL")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		invalid("tracing.sample_ratio", "must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+
+	validRate := func(path string, r Rate) {
+		switch {
+		case r.Requests < 0:
+			invalid(path+".requests", "must not be negative, got %d", r.Requests)
+		case r.Requests > 0 && r.Per <= 0:
+			invalid(path+".per", "must be a positive duration, got %s", r.Per)
+		case r.Burst < 0:
+			invalid(path+".burst", "must not be negative, got %d", r.Burst)
+		}
+	}
+	validRate("rate_limits.client", c.RateLimits.Client)
+	validRate("rate_limits.upstream", c.RateLimits.Upstream)
+	for i, p := range c.Profiles {
+		if p.RateLimits != nil {
+			validRate(fmt.Sprintf("profiles[%d].rate_limits.client", i), p.RateLimits.Client)
+			validRate(fmt.Sprintf("profiles[%d].rate_limits.upstream", i), p.RateLimits.Upstream)
+		}
+	}
+
+	switch c.Auth.Mode {
+	case AuthModeLocal:
+	case AuthModeSowesign:
+		if len(c.Auth.Users) > 0 {
+			invalid("auth.users", "cannot be used when auth.mode is %s", AuthModeSowesign)
+		}
+		if len(c.Auth.APITokens) > 0 {
+			invalid("auth.api_tokens", "cannot be used when auth.mode is %s, API requests use the session of the visitor", AuthModeSowesign)
+		}
+		if c.Auth.BasicAuth {
+			invalid("auth.basic_auth", "cannot be used when auth.mode is %s", AuthModeSowesign)
+		}
+	default:
+		invalid("auth.mode", "must be %s or %s, got %q", AuthModeLocal, AuthModeSowesign, c.Auth.Mode)
+	}
+	if c.Auth.IdleTimeout <= 0 {
+		invalid("auth.idle_timeout", "must be a positive duration, got %s", c.Auth.IdleTimeout)
+	}
+	if c.Auth.SessionTTL <= 0 {
+		invalid("auth.session_ttl", "must be a positive duration, got %s", c.Auth.SessionTTL)
+	}
+	if c.Auth.SessionSecret != "" && len(c.Auth.SessionSecret) < 32 {
+		invalid("auth.session_secret", "must be at least 32 characters long")
+	}
+	users := make(map[string]bool)
+	for i, u := range c.Auth.Users {
+		path := fmt.Sprintf("auth.users[%d]", i)
+		if u.Username == "" {
+			invalid(path+".username", "is required")
+		} else if users[u.Username] {
+			invalid(path+".username", "duplicate user %q", u.Username)
+		}
+		users[u.Username] = true
+		if _, err := bcrypt.Cost([]byte(u.PasswordHash)); err != nil {
+			invalid(path+".password_hash", "must be a bcrypt hash, see sws hash-password")
+		}
+	}
+	for i, token := range c.Auth.APITokens {
+		if len(token) < 16 {
+			invalid(fmt.Sprintf("auth.api_tokens[%d]", i), "must be at least 16 characters long")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validHTTPURL reports whether s is an absolute http or https URL
+func validHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
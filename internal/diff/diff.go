@@ -0,0 +1,106 @@
+package diff
+
+import (
+	"sort"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/service"
+)
+
+// Kind classifies a schedule change
+type Kind string
+
+const (
+	Added   Kind = "added"
+	Removed Kind = "removed"
+	Moved   Kind = "moved"
+	Renamed Kind = "renamed"
+)
+
+// Change describes how a course differs between two snapshots
+type Change struct {
+	Kind       Kind           `json:"kind"`
+	CourseID   int            `json:"courseId"`
+	Before     *models.Course `json:"before,omitempty"`
+	After      *models.Course `json:"after,omitempty"`
+	DetectedAt time.Time      `json:"detectedAt"`
+}
+
+// Course returns the most recent known state of the changed course
+func (c Change) Course() models.Course {
+	if c.After != nil {
+		return *c.After
+	}
+	if c.Before != nil {
+		return *c.Before
+	}
+	return models.Course{ID: c.CourseID}
+}
+
+// Compare classifies the differences between the previous and the next
+// snapshot of courses, detected at the given time.
+//
+// Sowesign only returns a rolling window of upcoming courses, so a course
+// missing from the next snapshot is only reported as removed if it had not
+// started yet and was scheduled within the horizon of the next snapshot.
+// Otherwise it simply ended or slid out of the window.
+func Compare(prev, next []models.Course, now time.Time) []Change {
+	before := make(map[int]models.Course, len(prev))
+	for _, course := range prev {
+		before[course.ID] = course
+	}
+
+	var horizon time.Time
+	after := make(map[int]models.Course, len(next))
+	for _, course := range next {
+		after[course.ID] = course
+		if start := service.CourseStart(course); start.After(horizon) {
+			horizon = start
+		}
+	}
+
+	var changes []Change
+	for _, course := range next {
+		old, ok := before[course.ID]
+		if !ok {
+			changes = append(changes, newChange(Added, nil, &course, now))
+			continue
+		}
+		if old.Date != course.Date || old.Start != course.Start || old.End != course.End {
+			changes = append(changes, newChange(Moved, &old, &course, now))
+		}
+		if old.Name != course.Name {
+			changes = append(changes, newChange(Renamed, &old, &course, now))
+		}
+	}
+
+	for _, course := range prev {
+		if _, ok := after[course.ID]; ok {
+			continue
+		}
+		start := service.CourseStart(course)
+		if start.IsZero() || !start.After(now) {
+			continue
+		}
+		if len(next) > 0 && start.After(horizon) {
+			continue
+		}
+		changes = append(changes, newChange(Removed, &course, nil, now))
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return service.CourseStart(changes[i].Course()).Before(service.CourseStart(changes[j].Course()))
+	})
+	return changes
+}
+
+func newChange(kind Kind, before, after *models.Course, now time.Time) Change {
+	c := Change{Kind: kind, Before: before, After: after, DetectedAt: now}
+	if after != nil {
+		c.CourseID = after.ID
+	} else if before != nil {
+		c.CourseID = before.ID
+	}
+	return c
+}
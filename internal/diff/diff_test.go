@@ -0,0 +1,113 @@
+package diff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/models"
+)
+
+func TestCompare(t *testing.T) {
+	now := time.Date(2025, 2, 10, 10, 0, 0, 0, time.UTC)
+
+	past := models.Course{ID: 1, Name: "Past", Date: "2025-02-10", Start: "08:00:00+00:00", End: "09:00:00+00:00"}
+	first := models.Course{ID: 2, Name: "First", Date: "2025-02-11", Start: "08:00:00+00:00", End: "12:00:00+00:00"}
+	second := models.Course{ID: 3, Name: "Second", Date: "2025-02-12", Start: "08:00:00+00:00", End: "12:00:00+00:00"}
+	third := models.Course{ID: 4, Name: "Third", Date: "2025-02-13", Start: "08:00:00+00:00", End: "12:00:00+00:00"}
+	late := models.Course{ID: 5, Name: "Late", Date: "2025-03-01", Start: "08:00:00+00:00", End: "12:00:00+00:00"}
+
+	moved := second
+	moved.Start = "13:00:00+00:00"
+	renamed := first
+	renamed.Name = "First (renamed)"
+	both := third
+	both.Name, both.Date = "Third (renamed)", "2025-02-14"
+
+	tests := []struct {
+		name string
+		prev []models.Course
+		next []models.Course
+		want []Kind
+	}{
+		{
+			name: "unchanged",
+			prev: []models.Course{first, second},
+			next: []models.Course{first, second},
+			want: nil,
+		},
+		{
+			name: "added",
+			prev: []models.Course{first},
+			next: []models.Course{first, second},
+			want: []Kind{Added},
+		},
+		{
+			name: "moved and renamed",
+			prev: []models.Course{first, second},
+			next: []models.Course{renamed, moved},
+			want: []Kind{Renamed, Moved},
+		},
+		{
+			name: "moved and renamed at once",
+			prev: []models.Course{third},
+			next: []models.Course{both},
+			want: []Kind{Moved, Renamed},
+		},
+		{
+			name: "removed within the horizon",
+			prev: []models.Course{first, second, third},
+			next: []models.Course{first, third},
+			want: []Kind{Removed},
+		},
+		{
+			name: "finished course is not removed",
+			prev: []models.Course{past, first},
+			next: []models.Course{first},
+			want: nil,
+		},
+		{
+			name: "course beyond the horizon slid out of the window",
+			prev: []models.Course{first, second, late},
+			next: []models.Course{first, second},
+			want: nil,
+		},
+		{
+			name: "everything cancelled",
+			prev: []models.Course{past, first},
+			next: nil,
+			want: []Kind{Removed},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compare(tt.prev, tt.next, now)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Compare() = %+v, want kinds %v", got, tt.want)
+			}
+			for i, c := range got {
+				if c.Kind != tt.want[i] {
+					t.Errorf("change %d kind = %s, want %s", i, c.Kind, tt.want[i])
+				}
+				if c.CourseID == 0 {
+					t.Errorf("change %d has no course ID", i)
+				}
+				if !c.DetectedAt.Equal(now) {
+					t.Errorf("change %d detected at %v, want %v", i, c.DetectedAt, now)
+				}
+			}
+		})
+	}
+}
+
+func TestChange_Course(t *testing.T) {
+	before := models.Course{ID: 1, Name: "Before"}
+	after := models.Course{ID: 1, Name: "After"}
+
+	if got := (Change{Before: &before, After: &after}).Course(); got.Name != "After" {
+		t.Errorf("Course() = %q, want After", got.Name)
+	}
+	if got := (Change{Before: &before}).Course(); got.Name != "Before" {
+		t.Errorf("Course() = %q, want Before", got.Name)
+	}
+}
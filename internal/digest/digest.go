@@ -0,0 +1,90 @@
+package digest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/export"
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/notify"
+	"github.com/LaulauChau/sws/internal/service"
+)
+
+// Digest is the agenda of a single day
+type Digest struct {
+	Day     time.Time
+	Courses []export.Record
+}
+
+// Compile gathers the courses taking place on the day of the given time,
+// in its location, sorted by start time
+func Compile(courses []models.Course, day time.Time) Digest {
+	d := Digest{Day: day}
+	for _, course := range courses {
+		start := service.CourseStart(course)
+		if start.IsZero() {
+			continue
+		}
+		if y, m, dd := start.In(day.Location()).Date(); y == day.Year() && m == day.Month() && dd == day.Day() {
+			d.Courses = append(d.Courses, export.NewRecord(course))
+		}
+	}
+
+	sort.SliceStable(d.Courses, func(i, j int) bool {
+		return d.Courses[i].StartsAt.Before(d.Courses[j].StartsAt)
+	})
+	return d
+}
+
+// Title returns the subject of the digest
+func (d Digest) Title() string {
+	return "Agenda du " + service.FormatLongDate(d.Day)
+}
+
+// Text renders the digest as plain text
+func (d Digest) Text() string {
+	var sb strings.Builder
+	sb.WriteString(d.Title() + "\n\n")
+
+	if len(d.Courses) == 0 {
+		sb.WriteString("Aucun cours aujourd'hui.\n")
+		return sb.String()
+	}
+
+	for _, c := range d.Courses {
+		sb.WriteString(fmt.Sprintf("%s – %s  %s", c.Start, c.End, c.Name))
+		if c.Code != "" {
+			sb.WriteString(" (code " + c.Code + ")")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// HTML renders the digest as an HTML email body
+func (d Digest) HTML(ctx context.Context) (string, error) {
+	var buf bytes.Buffer
+	if err := Email(d).Render(ctx, &buf); err != nil {
+		return "", fmt.Errorf("failed to render digest: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// Message builds the notification carrying the digest
+func (d Digest) Message(ctx context.Context) (notify.Message, error) {
+	html, err := d.HTML(ctx)
+	if err != nil {
+		return notify.Message{}, err
+	}
+
+	return notify.Message{
+		Title: d.Title(),
+		Body:  d.Text(),
+		HTML:  html,
+		Data:  d.Courses,
+	}, nil
+}
@@ -0,0 +1,133 @@
+package digest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/notify"
+)
+
+var paris, _ = time.LoadLocation("Europe/Paris")
+
+var courses = []models.Course{
+	{ID: 137227, Name: "Gestion de projet", Date: "2025-02-10", Start: "13:00:00+00:00", End: "16:30:00+00:00"},
+	{ID: 137393, Name: "Innover et entreprendre", Date: "2025-02-10", Start: "08:00:00+00:00", End: "12:00:00+00:00"},
+	{ID: 137500, Name: "Anglais", Date: "2025-02-11", Start: "08:00:00+00:00", End: "12:00:00+00:00"},
+	// 23:30 UTC is already the next day in Paris
+	{ID: 137501, Name: "Veille", Date: "2025-02-09", Start: "23:30:00+00:00", End: "23:45:00+00:00"},
+}
+
+func TestCompile(t *testing.T) {
+	d := Compile(courses, time.Date(2025, 2, 10, 7, 0, 0, 0, paris))
+
+	var names []string
+	for _, c := range d.Courses {
+		names = append(names, c.Name)
+	}
+	want := "Veille,Innover et entreprendre,Gestion de projet"
+	if strings.Join(names, ",") != want {
+		t.Errorf("Compile() = %v, want %s", names, want)
+	}
+}
+
+func TestDigest_Render(t *testing.T) {
+	d := Compile(courses, time.Date(2025, 2, 10, 7, 0, 0, 0, paris))
+
+	if d.Title() != "Agenda du lundi 10 février 2025" {
+		t.Errorf("unexpected title %q", d.Title())
+	}
+
+	text := d.Text()
+	if !strings.Contains(text, "09:00 – 13:00  Innover et entreprendre (code 09866)") {
+		t.Errorf("unexpected text digest:\n%s", text)
+	}
+
+	html, err := d.HTML(context.Background())
+	if err != nil {
+		t.Fatalf("HTML() error = %v", err)
+	}
+	for _, want := range []string{"<h1", "Innover et entreprendre", "09866"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML digest is missing %q", want)
+		}
+	}
+
+	empty := Compile(nil, time.Date(2025, 2, 10, 7, 0, 0, 0, paris))
+	if !strings.Contains(empty.Text(), "Aucun cours") {
+		t.Errorf("expected an empty digest to say so, got %q", empty.Text())
+	}
+}
+
+func TestJob_NextRun(t *testing.T) {
+	job, err := NewJob(nil, nil, "07:30", paris)
+	if err != nil {
+		t.Fatalf("NewJob() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "later today",
+			now:  time.Date(2025, 2, 10, 6, 0, 0, 0, paris),
+			want: time.Date(2025, 2, 10, 7, 30, 0, 0, paris),
+		},
+		{
+			name: "tomorrow",
+			now:  time.Date(2025, 2, 10, 7, 30, 0, 0, paris),
+			want: time.Date(2025, 2, 11, 7, 30, 0, 0, paris),
+		},
+		{
+			name: "across daylight saving change",
+			now:  time.Date(2025, 3, 29, 12, 0, 0, 0, paris),
+			want: time.Date(2025, 3, 30, 7, 30, 0, 0, paris),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := job.NextRun(tt.now); !got.Equal(tt.want) {
+				t.Errorf("NextRun() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewJob(nil, nil, "7h30", paris); err == nil {
+		t.Error("expected error with invalid time")
+	}
+}
+
+type recordingNotifier struct {
+	messages []notify.Message
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, msg notify.Message) error {
+	n.messages = append(n.messages, msg)
+	return nil
+}
+
+func TestJob_Send(t *testing.T) {
+	n := &recordingNotifier{}
+	fetch := func() ([]models.Course, error) { return courses, nil }
+
+	job, err := NewJob(fetch, n, "07:00", paris)
+	if err != nil {
+		t.Fatalf("NewJob() error = %v", err)
+	}
+	if err := job.Send(context.Background(), time.Date(2025, 2, 11, 6, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if len(n.messages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(n.messages))
+	}
+	msg := n.messages[0]
+	if msg.Title != "Agenda du mardi 11 février 2025" || !strings.Contains(msg.Body, "Anglais") || msg.HTML == "" {
+		t.Errorf("unexpected digest message %+v", msg)
+	}
+}
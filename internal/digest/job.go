@@ -0,0 +1,81 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/notify"
+)
+
+// CourseFetcher retrieves the upcoming courses
+type CourseFetcher func() ([]models.Course, error)
+
+// Job sends the digest of the day every day at a fixed time
+type Job struct {
+	fetch    CourseFetcher
+	notifier notify.Notifier
+	hour     int
+	minute   int
+	location *time.Location
+}
+
+// NewJob creates a job sending the digest every day at clock (HH:MM) in the location
+func NewJob(fetch CourseFetcher, notifier notify.Notifier, clock string, location *time.Location) (*Job, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest time %q: %v", clock, err)
+	}
+
+	return &Job{
+		fetch:    fetch,
+		notifier: notifier,
+		hour:     t.Hour(),
+		minute:   t.Minute(),
+		location: location,
+	}, nil
+}
+
+// NextRun returns the first scheduled time strictly after now
+func (j *Job) NextRun(now time.Time) time.Time {
+	now = now.In(j.location)
+	next := time.Date(now.Year(), now.Month(), now.Day(), j.hour, j.minute, 0, 0, j.location)
+	if !next.After(now) {
+		next = time.Date(now.Year(), now.Month(), now.Day()+1, j.hour, j.minute, 0, 0, j.location)
+	}
+	return next
+}
+
+// Send compiles the digest of the given day and delivers it
+func (j *Job) Send(ctx context.Context, day time.Time) error {
+	courses, err := j.fetch()
+	if err != nil {
+		return fmt.Errorf("failed to get courses: %v", err)
+	}
+
+	msg, err := Compile(courses, day.In(j.location)).Message(ctx)
+	if err != nil {
+		return err
+	}
+	return j.notifier.Notify(ctx, msg)
+}
+
+// Run sends the digest at every scheduled time until the context is cancelled
+func (j *Job) Run(ctx context.Context) {
+	for {
+		next := j.NextRun(time.Now())
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := j.Send(ctx, next); err != nil {
+			fmt.Printf("Error sending daily digest: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package events
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Event is a single Server-Sent Event
+type Event struct {
+	Name string
+	Data string
+}
+
+// Broker fans out published events to every subscriber
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+// NewBroker creates a new broker without subscribers
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber and returns its event channel
+func (b *Broker) Subscribe() chan Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, 8)
+	b.subscribers[ch] = struct{}{}
+	return ch
+}
+
+// Unsubscribe removes a subscriber and closes its channel
+func (b *Broker) Unsubscribe(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Publish sends an event to every subscriber, dropping it for subscribers that are too slow
+func (b *Broker) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Write encodes an event in the text/event-stream format
+func Write(w io.Writer, e Event) error {
+	var sb strings.Builder
+	if e.Name != "" {
+		sb.WriteString("event: " + e.Name + "\n")
+	}
+	for _, line := range strings.Split(e.Data, "\n") {
+		sb.WriteString("data: " + strings.TrimSuffix(line, "\r") + "\n")
+	}
+	sb.WriteString("\n")
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return fmt.Errorf("failed to write event: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBroker_Publish(t *testing.T) {
+	b := NewBroker()
+	first := b.Subscribe()
+	second := b.Subscribe()
+
+	b.Publish(Event{Name: "courses", Data: "<table></table>"})
+
+	for _, ch := range []chan Event{first, second} {
+		e := <-ch
+		if e.Name != "courses" || e.Data != "<table></table>" {
+			t.Errorf("got %+v, want courses event", e)
+		}
+	}
+
+	b.Unsubscribe(first)
+	if _, ok := <-first; ok {
+		t.Error("channel should be closed after unsubscribing")
+	}
+
+	// Unsubscribing twice must not panic
+	b.Unsubscribe(first)
+}
+
+func TestBroker_SlowSubscriber(t *testing.T) {
+	b := NewBroker()
+	ch := b.Subscribe()
+
+	// Publishing more events than the buffer holds must not block
+	for i := 0; i < cap(ch)+5; i++ {
+		b.Publish(Event{Name: "tick"})
+	}
+
+	if len(ch) != cap(ch) {
+		t.Errorf("got %d buffered events, want %d", len(ch), cap(ch))
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{
+			name:  "single line",
+			event: Event{Name: "tick", Data: "2025-02-10T08:00:00Z"},
+			want:  "event: tick\ndata: 2025-02-10T08:00:00Z\n\n",
+		},
+		{
+			name:  "multi line",
+			event: Event{Name: "courses", Data: "<div>\r\n<p>a</p>\n</div>"},
+			want:  "event: courses\ndata: <div>\ndata: <p>a</p>\ndata: </div>\n\n",
+		},
+		{
+			name:  "unnamed",
+			event: Event{Data: "hello"},
+			want:  "data: hello\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			if err := Write(&sb, tt.event); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if sb.String() != tt.want {
+				t.Errorf("Write() = %q, want %q", sb.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/LaulauChau/sws/internal/models"
+)
+
+var csvHeader = []string{"ID", "Nom", "Date", "Début", "Fin", "Durée", "Code"}
+
+// CSVOptions controls the CSV dialect
+type CSVOptions struct {
+	// Delimiter separates fields, French spreadsheet locales expect ';'
+	Delimiter rune
+	// BOM prefixes the output with a UTF-8 byte order mark so that
+	// spreadsheet applications detect the encoding
+	BOM bool
+}
+
+// ParseDelimiter parses a delimiter given as a single character or as "tab"
+func ParseDelimiter(s string) (rune, error) {
+	switch s {
+	case "":
+		return ',', nil
+	case "tab", `\t`:
+		return '\t', nil
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("invalid delimiter %q", s)
+	}
+	return r, nil
+}
+
+// WriteCSV writes the courses as CSV, one course per row after a header row
+func WriteCSV(w io.Writer, courses []models.Course, opts CSVOptions) error {
+	if opts.BOM {
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return fmt.Errorf("failed to write CSV: %v", err)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if opts.Delimiter != 0 {
+		cw.Comma = opts.Delimiter
+	}
+
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write CSV: %v", err)
+	}
+	for _, r := range NewRecords(courses) {
+		row := []string{strconv.Itoa(r.ID), r.Name, r.Date, r.Start, r.End, r.Duration, r.Code}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV: %v", err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %v", err)
+	}
+	return nil
+}
+
+// WriteJSONLines writes the courses as JSON lines, one record per line
+func WriteJSONLines(w io.Writer, courses []models.Course) error {
+	enc := json.NewEncoder(w)
+	for _, r := range NewRecords(courses) {
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("failed to write JSON lines: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/LaulauChau/sws/internal/models"
+)
+
+var testCourses = []models.Course{
+	{
+		ID:    137393,
+		Name:  "Innover et entreprendre; groupe [XDEV003-CTD / 2425S10-PAR1]",
+		Date:  "2025-02-10",
+		Start: "08:00:00+00:00",
+		End:   "11:30:00+00:00",
+	},
+	{
+		ID:    137227,
+		Name:  "Gestion de projet",
+		Date:  "2025-07-01",
+		Start: "13:00:00+00:00",
+		End:   "16:30:00+00:00",
+	},
+}
+
+func TestWriteCSV(t *testing.T) {
+	var sb strings.Builder
+	if err := WriteCSV(&sb, testCourses, CSVOptions{Delimiter: ';', BOM: true}); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	want := "\ufeff" +
+		"ID;Nom;Date;Début;Fin;Durée;Code\n" +
+		"137393;\"Innover et entreprendre; groupe [XDEV003-CTD / 2425S10-PAR1]\";10/02/2025;09:00;12:30;3h30;09866\n" +
+		"137227;Gestion de projet;01/07/2025;15:00;18:30;3h30;" + NewRecord(testCourses[1]).Code + "\n"
+	if sb.String() != want {
+		t.Errorf("WriteCSV() = %q, want %q", sb.String(), want)
+	}
+}
+
+func TestWriteJSONLines(t *testing.T) {
+	var sb strings.Builder
+	if err := WriteJSONLines(&sb, testCourses); err != nil {
+		t.Fatalf("WriteJSONLines() error = %v", err)
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(sb.String()))
+	var records []Record
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, r)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(records))
+	}
+	if records[0].Code != "09866" || records[0].DurationMinutes != 210 || records[0].Start != "09:00" {
+		t.Errorf("unexpected record %+v", records[0])
+	}
+	if records[0].Title != "Innover et entreprendre; groupe" || records[0].Module != "XDEV003" || records[0].Format != "CTD" ||
+		records[0].Period != "2425S10" || records[0].Group != "PAR1" || records[0].Campus != "PAR" {
+		t.Errorf("unexpected name fields %+v", records[0])
+	}
+	// Summer time in Paris is UTC+2
+	if records[1].Start != "15:00" {
+		t.Errorf("expected 15:00 start in summer, got %s", records[1].Start)
+	}
+}
+
+func TestParseDelimiter(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    rune
+		wantErr bool
+	}{
+		{in: "", want: ','},
+		{in: ";", want: ';'},
+		{in: "tab", want: '\t'},
+		{in: ";;", wantErr: true},
+		{in: "\"", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseDelimiter(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDelimiter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDelimiter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
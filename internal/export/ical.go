@@ -0,0 +1,120 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/service"
+)
+
+const icsTimeFormat = "20060102T150405"
+
+// parisTimezone is the VTIMEZONE definition for Europe/Paris (RFC 5545 section 3.6.5)
+var parisTimezone = []string{
+	"BEGIN:VTIMEZONE",
+	"TZID:Europe/Paris",
+	"X-LIC-LOCATION:Europe/Paris",
+	"BEGIN:DAYLIGHT",
+	"TZOFFSETFROM:+0100",
+	"TZOFFSETTO:+0200",
+	"TZNAME:CEST",
+	"DTSTART:19700329T020000",
+	"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU",
+	"END:DAYLIGHT",
+	"BEGIN:STANDARD",
+	"TZOFFSETFROM:+0200",
+	"TZOFFSETTO:+0100",
+	"TZNAME:CET",
+	"DTSTART:19701025T030000",
+	"RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU",
+	"END:STANDARD",
+	"END:VTIMEZONE",
+}
+
+// WriteICS writes the courses as an RFC 5545 calendar, stamped with the given time
+func WriteICS(w io.Writer, courses []models.Course, now time.Time) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//LaulauChau//sws//FR",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:Cours Sowesign",
+		"X-WR-TIMEZONE:Europe/Paris",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H",
+		"X-PUBLISHED-TTL:PT1H",
+	}
+	lines = append(lines, parisTimezone...)
+
+	for _, course := range courses {
+		start := service.CourseStart(course)
+		if course.ID == 0 || start.IsZero() {
+			continue
+		}
+		end := service.CourseEnd(course)
+		if end.Before(start) {
+			end = start
+		}
+
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			fmt.Sprintf("UID:course-%d@sws", course.ID),
+			"DTSTAMP:"+now.UTC().Format(icsTimeFormat)+"Z",
+			"DTSTART;TZID=Europe/Paris:"+start.In(service.ParisLocation()).Format(icsTimeFormat),
+			"DTEND;TZID=Europe/Paris:"+end.In(service.ParisLocation()).Format(icsTimeFormat),
+			"SUMMARY:"+escapeText(course.Name),
+		)
+		if _, _, _, code := service.GenerateFixedCode(course); code != "" {
+			lines = append(lines, "DESCRIPTION:"+escapeText("Code : "+code))
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	var sb strings.Builder
+	for _, line := range lines {
+		sb.WriteString(foldLine(line))
+	}
+
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return fmt.Errorf("failed to write calendar: %v", err)
+	}
+	return nil
+}
+
+// escapeText escapes a TEXT property value (RFC 5545 section 3.3.11)
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// foldLine splits a content line into chunks of at most 75 octets without
+// breaking UTF-8 sequences, and terminates it with CRLF (RFC 5545 section 3.1)
+func foldLine(line string) string {
+	var sb strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		sb.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space which counts towards the limit
+		limit = 74
+	}
+	sb.WriteString(line + "\r\n")
+	return sb.String()
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/models"
+)
+
+func TestWriteICS(t *testing.T) {
+	courses := []models.Course{
+		{
+			ID:    137393,
+			Name:  "Innover et entreprendre [XDEV003-CTD / 2425S10-PAR1]",
+			Date:  "2025-02-10",
+			Start: "08:00:00+00:00",
+			End:   "12:00:00+00:00",
+		},
+		{
+			ID:    0,
+			Name:  "Missing ID",
+			Date:  "2025-02-10",
+			Start: "08:00:00+00:00",
+		},
+	}
+
+	var sb strings.Builder
+	now := time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC)
+	if err := WriteICS(&sb, courses, now); err != nil {
+		t.Fatalf("WriteICS() error = %v", err)
+	}
+	got := sb.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"TZID:Europe/Paris\r\n",
+		"UID:course-137393@sws\r\n",
+		"DTSTAMP:20250201T100000Z\r\n",
+		"DTSTART;TZID=Europe/Paris:20250210T090000\r\n",
+		"DTEND;TZID=Europe/Paris:20250210T130000\r\n",
+		"SUMMARY:Innover et entreprendre [XDEV003-CTD / 2425S10-PAR1]\r\n",
+		"DESCRIPTION:Code : 09866\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("calendar is missing %q", want)
+		}
+	}
+
+	if strings.Count(got, "BEGIN:VEVENT") != 1 {
+		t.Errorf("expected courses without ID to be skipped")
+	}
+	if strings.Contains(strings.ReplaceAll(got, "\r\n", ""), "\n") {
+		t.Error("lines must be terminated with CRLF")
+	}
+}
+
+func Test_escapeText(t *testing.T) {
+	got := escapeText("a;b,c\\d\ne")
+	want := `a\;b\,c\\d\ne`
+	if got != want {
+		t.Errorf("escapeText() = %q, want %q", got, want)
+	}
+}
+
+func Test_foldLine(t *testing.T) {
+	line := "SUMMARY:" + strings.Repeat("é", 60)
+	folded := foldLine(line)
+
+	for _, part := range strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n") {
+		if len(part) > 75 {
+			t.Errorf("folded line is %d octets long", len(part))
+		}
+		if !strings.HasPrefix(part, "SUMMARY") && !strings.HasPrefix(part, " ") {
+			t.Errorf("continuation line %q must start with a space", part)
+		}
+	}
+
+	unfolded := strings.ReplaceAll(strings.TrimSuffix(folded, "\r\n"), "\r\n ", "")
+	if unfolded != line {
+		t.Errorf("unfolding gives %q, want %q", unfolded, line)
+	}
+}
@@ -0,0 +1,136 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/diff"
+	"github.com/LaulauChau/sws/internal/filter"
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/service"
+)
+
+// Record is a course flattened with its computed code and localized times
+type Record struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// Title, Module, Format, Period, Group and Campus are parsed from the
+	// name, see service.ParseCourseName
+	Title           string         `json:"title"`
+	Module          string         `json:"module,omitempty"`
+	Format          service.Format `json:"format,omitempty"`
+	Period          string         `json:"period,omitempty"`
+	Group           string         `json:"group,omitempty"`
+	Campus          string         `json:"campus,omitempty"`
+	Date            string         `json:"date"`
+	Start           string         `json:"start"`
+	End             string         `json:"end"`
+	Duration        string         `json:"duration"`
+	DurationMinutes int            `json:"durationMinutes"`
+	Code            string         `json:"code"`
+	StartsAt        time.Time      `json:"startsAt"`
+	EndsAt          time.Time      `json:"endsAt"`
+}
+
+// Detail is everything known about a course, shown by its page and the JSON API
+type Detail struct {
+	Record
+	// Upcoming reports whether the course is in the last fetched list
+	Upcoming bool `json:"upcoming"`
+	// Extra holds the fields Sowesign sent that Course does not map
+	Extra map[string]json.RawMessage `json:"extra,omitempty"`
+	// FirstSeen and LastSeen bound when the course was listed, when the
+	// history is recorded
+	FirstSeen *time.Time    `json:"firstSeen,omitempty"`
+	LastSeen  *time.Time    `json:"lastSeen,omitempty"`
+	Changes   []diff.Change `json:"changes"`
+}
+
+// NewRecord converts a course into an export record using Paris local time
+func NewRecord(course models.Course) Record {
+	name := service.ParseCourseName(course.Name)
+	r := Record{
+		ID:     course.ID,
+		Name:   course.Name,
+		Title:  name.Title,
+		Module: name.Module,
+		Format: name.Format,
+		Period: name.Period,
+		Group:  name.Group,
+		Campus: name.Campus,
+	}
+
+	start, end := service.CourseStart(course), service.CourseEnd(course)
+	if !start.IsZero() {
+		start = start.In(service.ParisLocation())
+		r.Date = start.Format("02/01/2006")
+		r.Start = start.Format("15:04")
+		r.StartsAt = start
+	}
+	if !end.IsZero() {
+		end = end.In(service.ParisLocation())
+		r.End = end.Format("15:04")
+		r.EndsAt = end
+	}
+	if !start.IsZero() && end.After(start) {
+		d := end.Sub(start)
+		r.DurationMinutes = int(d.Minutes())
+		r.Duration = formatDuration(d)
+	}
+
+	_, _, _, r.Code = service.GenerateFixedCode(course)
+	return r
+}
+
+// NewRecords converts every course into an export record
+func NewRecords(courses []models.Course) []Record {
+	records := make([]Record, 0, len(courses))
+	for _, course := range courses {
+		records = append(records, NewRecord(course))
+	}
+	return records
+}
+
+// Day is the records of the courses of a day, its date being YYYY-MM-DD or
+// empty for courses whose start cannot be parsed
+type Day struct {
+	Date    string   `json:"date"`
+	Courses []Record `json:"courses"`
+}
+
+// NewDays converts courses grouped by day into export records
+func NewDays(days []filter.Day) []Day {
+	out := make([]Day, 0, len(days))
+	for _, day := range days {
+		d := Day{Courses: NewRecords(day.Courses)}
+		if !day.Date.IsZero() {
+			d.Date = day.Date.Format("2006-01-02")
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// Module is the records of the courses of a module, its code being empty for
+// courses without one
+type Module struct {
+	Code    string   `json:"module"`
+	Title   string   `json:"title"`
+	Courses []Record `json:"courses"`
+}
+
+// NewModules converts courses grouped by module into export records
+func NewModules(modules []filter.Module) []Module {
+	out := make([]Module, 0, len(modules))
+	for _, module := range modules {
+		out = append(out, Module{Code: module.Code, Title: module.Title, Courses: NewRecords(module.Courses)})
+	}
+	return out
+}
+
+// formatDuration formats a duration the French way, e.g. 3h30
+func formatDuration(d time.Duration) string {
+	minutes := int(d.Minutes())
+	return fmt.Sprintf("%dh%02d", minutes/60, minutes%60)
+}
@@ -0,0 +1,179 @@
+package filter
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/service"
+)
+
+// TimeOfDay is a part of the day in which courses start
+type TimeOfDay string
+
+const (
+	// Morning courses start before noon
+	Morning TimeOfDay = "morning"
+	// Afternoon courses start between noon and 6 PM
+	Afternoon TimeOfDay = "afternoon"
+	// Evening courses start at 6 PM or later
+	Evening TimeOfDay = "evening"
+)
+
+// timeOfDay returns the part of the day of an hour
+func timeOfDay(hour int) TimeOfDay {
+	switch {
+	case hour < 12:
+		return Morning
+	case hour < 18:
+		return Afternoon
+	default:
+		return Evening
+	}
+}
+
+// Filter restricts the upcoming courses, zero fields matching every course
+type Filter struct {
+	// Name matches courses whose name contains it, case-insensitively
+	Name string
+	// Module matches courses of that module code, as in "XDEV003",
+	// case-insensitively
+	Module string
+	// Format matches courses taught in that format
+	Format service.Format
+	// From and To bound the day of the course in Paris, both inclusive
+	From time.Time
+	To   time.Time
+	// TimeOfDay matches courses starting in that part of the day in Paris
+	TimeOfDay TimeOfDay
+}
+
+// Parse reads a filter from the name, module, format, from, to and time
+// query parameters, dates being YYYY-MM-DD
+func Parse(values url.Values) (Filter, error) {
+	f := Filter{
+		Name:      strings.TrimSpace(values.Get("name")),
+		Module:    strings.TrimSpace(values.Get("module")),
+		TimeOfDay: TimeOfDay(values.Get("time")),
+	}
+
+	var err error
+	if f.From, err = ParseDate(values.Get("from")); err != nil {
+		return Filter{}, fmt.Errorf("invalid start date: %v", err)
+	}
+	if f.To, err = ParseDate(values.Get("to")); err != nil {
+		return Filter{}, fmt.Errorf("invalid end date: %v", err)
+	}
+	if format := values.Get("format"); format != "" {
+		var ok bool
+		if f.Format, ok = service.ParseFormat(format); !ok {
+			return Filter{}, fmt.Errorf("invalid format: %q", format)
+		}
+	}
+	switch f.TimeOfDay {
+	case "", Morning, Afternoon, Evening:
+	default:
+		return Filter{}, fmt.Errorf("invalid time of day: %q", f.TimeOfDay)
+	}
+	return f, nil
+}
+
+// ParseDate parses a YYYY-MM-DD date at midnight in Paris, allowing empty values
+func ParseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, service.ParisLocation())
+}
+
+// Values encodes the filter as query parameters, the reverse of Parse
+func (f Filter) Values() url.Values {
+	values := url.Values{}
+	if f.Name != "" {
+		values.Set("name", f.Name)
+	}
+	if f.Module != "" {
+		values.Set("module", f.Module)
+	}
+	if f.Format != "" {
+		values.Set("format", string(f.Format))
+	}
+	if !f.From.IsZero() {
+		values.Set("from", f.From.Format("2006-01-02"))
+	}
+	if !f.To.IsZero() {
+		values.Set("to", f.To.Format("2006-01-02"))
+	}
+	if f.TimeOfDay != "" {
+		values.Set("time", string(f.TimeOfDay))
+	}
+	return values
+}
+
+// IsZero reports whether the filter matches every course
+func (f Filter) IsZero() bool {
+	return f == Filter{}
+}
+
+// Match reports whether a course passes the filter. Courses whose start
+// cannot be parsed only pass filters without dates nor time of day
+func (f Filter) Match(course models.Course) bool {
+	if f.Name != "" && !strings.Contains(strings.ToLower(course.Name), strings.ToLower(f.Name)) {
+		return false
+	}
+	if f.Module != "" || f.Format != "" {
+		name := service.ParseCourseName(course.Name)
+		if f.Module != "" && !strings.EqualFold(name.Module, f.Module) {
+			return false
+		}
+		if f.Format != "" && name.Format != f.Format {
+			return false
+		}
+	}
+	if f.From.IsZero() && f.To.IsZero() && f.TimeOfDay == "" {
+		return true
+	}
+
+	start := service.CourseStart(course)
+	if start.IsZero() {
+		return false
+	}
+	start = start.In(service.ParisLocation())
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	if !f.From.IsZero() && day.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && day.After(f.To) {
+		return false
+	}
+	return f.TimeOfDay == "" || f.TimeOfDay == timeOfDay(start.Hour())
+}
+
+// Apply returns the courses passing the filter, in the same order
+func (f Filter) Apply(courses []models.Course) []models.Course {
+	if f.IsZero() {
+		return courses
+	}
+	matched := make([]models.Course, 0, len(courses))
+	for _, course := range courses {
+		if f.Match(course) {
+			matched = append(matched, course)
+		}
+	}
+	return matched
+}
+
+// Modules returns the distinct module codes of the courses, sorted
+func Modules(courses []models.Course) []string {
+	var codes []string
+	for _, course := range courses {
+		if code := service.ParseCourseName(course.Name).Module; code != "" && !slices.Contains(codes, code) {
+			codes = append(codes, code)
+		}
+	}
+	slices.Sort(codes)
+	return codes
+}
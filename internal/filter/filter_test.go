@@ -0,0 +1,199 @@
+package filter
+
+import (
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/service"
+)
+
+var courses = []models.Course{
+	{ID: 1, Name: "Innover et entreprendre [XDEV003-CTD / 2425S10-PAR1]", Date: "2025-02-10", Start: "08:00:00+00:00", End: "11:00:00+00:00"},
+	{ID: 2, Name: "Gestion de projet [XMAN001-CM / 2425S10-PAR1]", Date: "2025-02-10", Start: "12:30:00+00:00", End: "15:30:00+00:00"},
+	{ID: 3, Name: "Anglais", Date: "2025-02-11", Start: "17:30:00+00:00", End: "19:00:00+00:00"},
+	{ID: 4, Name: "Innover et entreprendre [XDEV003-CTD / 2425S10-PAR1]", Date: "2025-02-12", Start: "08:00:00+00:00", End: "11:00:00+00:00"},
+	{ID: 5, Name: "Sans horaire"},
+}
+
+func ids(courses []models.Course) []int {
+	out := []int{}
+	for _, c := range courses {
+		out = append(out, c.ID)
+	}
+	return out
+}
+
+func date(s string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02", s, service.ParisLocation())
+	return t
+}
+
+func TestFilter_Apply(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int
+	}{
+		{name: "zero", filter: Filter{}, want: []int{1, 2, 3, 4, 5}},
+		{name: "name", filter: Filter{Name: "INNOVER"}, want: []int{1, 4}},
+		{name: "module", filter: Filter{Module: "xdev003"}, want: []int{1, 4}},
+		{name: "module prefix", filter: Filter{Module: "XDEV"}, want: []int{}},
+		{name: "module with format", filter: Filter{Module: "XMAN001-CM"}, want: []int{}},
+		{name: "format", filter: Filter{Format: service.FormatCTD}, want: []int{1, 4}},
+		{name: "module and format", filter: Filter{Module: "XMAN001", Format: service.FormatCTD}, want: []int{}},
+		{name: "from", filter: Filter{From: date("2025-02-11")}, want: []int{3, 4}},
+		{name: "to inclusive", filter: Filter{To: date("2025-02-11")}, want: []int{1, 2, 3}},
+		{name: "range", filter: Filter{From: date("2025-02-11"), To: date("2025-02-11")}, want: []int{3}},
+		// Times are UTC, 8:00 being 9:00 and 17:30 being 18:30 in Paris
+		{name: "morning", filter: Filter{TimeOfDay: Morning}, want: []int{1, 4}},
+		{name: "afternoon", filter: Filter{TimeOfDay: Afternoon}, want: []int{2}},
+		{name: "evening", filter: Filter{TimeOfDay: Evening}, want: []int{3}},
+		{name: "combined", filter: Filter{Name: "innover", From: date("2025-02-11"), TimeOfDay: Morning}, want: []int{4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(tt.filter.Apply(courses)); !slices.Equal(got, tt.want) {
+				t.Errorf("Apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_Apply_ModuleSharingPrefix(t *testing.T) {
+	courses := []models.Course{
+		{ID: 1, Name: "Innover et entreprendre [XDEV003-CTD / 2425S10-PAR1]"},
+		{ID: 2, Name: "Projet [XDEV0031-TD / 2425S10-PAR1]"},
+	}
+	if got := ids(Filter{Module: "XDEV003"}.Apply(courses)); !slices.Equal(got, []int{1}) {
+		t.Errorf("Apply() = %v, want %v", got, []int{1})
+	}
+}
+
+func TestParse(t *testing.T) {
+	values := url.Values{
+		"name":   {" innover "},
+		"module": {"XDEV003"},
+		"format": {"CTD"},
+		"from":   {"2025-02-10"},
+		"to":     {"2025-02-14"},
+		"time":   {"morning"},
+	}
+	f, err := Parse(values)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := Filter{Name: "innover", Module: "XDEV003", Format: service.FormatCTD, From: date("2025-02-10"), To: date("2025-02-14"), TimeOfDay: Morning}
+	if f != want {
+		t.Errorf("Parse() = %+v, want %+v", f, want)
+	}
+
+	values.Set("name", "innover")
+	if got := f.Values().Encode(); got != values.Encode() {
+		t.Errorf("Values() = %q, want %q", got, values.Encode())
+	}
+
+	for _, invalid := range []url.Values{
+		{"from": {"10/02/2025"}},
+		{"to": {"tomorrow"}},
+		{"time": {"night"}},
+		{"format": {"cours"}},
+	} {
+		if _, err := Parse(invalid); err == nil {
+			t.Errorf("Parse(%v) error = nil, want an error", invalid)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"", time.Time{}, false},
+		{"2025-02-10", time.Date(2025, 2, 10, 0, 0, 0, 0, service.ParisLocation()), false},
+		{"10/02/2025", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDate(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDate(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestModules(t *testing.T) {
+	want := []string{"XDEV003", "XMAN001"}
+	if got := Modules(courses); !slices.Equal(got, want) {
+		t.Errorf("Modules() = %v, want %v", got, want)
+	}
+}
+
+func TestGroupByDay(t *testing.T) {
+	days := GroupByDay(courses)
+
+	var got [][]int
+	var dates []string
+	for _, day := range days {
+		got = append(got, ids(day.Courses))
+		dates = append(dates, dateLabel(day.Date))
+	}
+
+	wantDates := []string{"2025-02-10", "2025-02-11", "2025-02-12", ""}
+	if !slices.Equal(dates, wantDates) {
+		t.Errorf("GroupByDay() dates = %v, want %v", dates, wantDates)
+	}
+	wantIDs := [][]int{{1, 2}, {3}, {4}, {5}}
+	if !slices.EqualFunc(got, wantIDs, slices.Equal) {
+		t.Errorf("GroupByDay() = %v, want %v", got, wantIDs)
+	}
+}
+
+func TestGroupByModule(t *testing.T) {
+	modules := GroupByModule(courses)
+
+	var codes []string
+	var got [][]int
+	for _, module := range modules {
+		codes = append(codes, module.Code)
+		got = append(got, ids(module.Courses))
+	}
+
+	wantCodes := []string{"XDEV003", "XMAN001", ""}
+	if !slices.Equal(codes, wantCodes) {
+		t.Errorf("GroupByModule() codes = %v, want %v", codes, wantCodes)
+	}
+	wantIDs := [][]int{{1, 4}, {2}, {3, 5}}
+	if !slices.EqualFunc(got, wantIDs, slices.Equal) {
+		t.Errorf("GroupByModule() = %v, want %v", got, wantIDs)
+	}
+	if modules[0].Title != "Innover et entreprendre" {
+		t.Errorf("GroupByModule() title = %q, want %q", modules[0].Title, "Innover et entreprendre")
+	}
+}
+
+func TestParseGrouping(t *testing.T) {
+	for _, s := range []string{"", "day", "module"} {
+		if got, err := ParseGrouping(s); err != nil || got != Grouping(s) {
+			t.Errorf("ParseGrouping(%q) = %q, %v, want %q", s, got, err, s)
+		}
+	}
+	if _, err := ParseGrouping("week"); err == nil {
+		t.Error("ParseGrouping(\"week\") error = nil, want an error")
+	}
+}
+
+func dateLabel(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,90 @@
+package filter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/service"
+)
+
+// Grouping is how courses are split into sections
+type Grouping string
+
+const (
+	// NoGrouping lists courses without sections
+	NoGrouping Grouping = ""
+	// ByDay splits courses by day, see GroupByDay
+	ByDay Grouping = "day"
+	// ByModule splits courses by module, see GroupByModule
+	ByModule Grouping = "module"
+)
+
+// ParseGrouping reads the value of the group query parameter
+func ParseGrouping(s string) (Grouping, error) {
+	switch g := Grouping(s); g {
+	case NoGrouping, ByDay, ByModule:
+		return g, nil
+	default:
+		return "", fmt.Errorf("invalid grouping: %q", s)
+	}
+}
+
+// Day is the courses taking place on a day
+type Day struct {
+	// Date is midnight in Paris, zero for courses whose start cannot be parsed
+	Date    time.Time
+	Courses []models.Course
+}
+
+// GroupByDay splits courses by their day in Paris, in order of first
+// appearance, keeping the order of the courses within a day
+func GroupByDay(courses []models.Course) []Day {
+	days := []Day{}
+	index := make(map[string]int)
+	for _, course := range courses {
+		var date time.Time
+		if start := service.CourseStart(course); !start.IsZero() {
+			start = start.In(service.ParisLocation())
+			date = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+		}
+
+		key := date.Format(time.DateOnly)
+		i, ok := index[key]
+		if !ok {
+			i = len(days)
+			index[key] = i
+			days = append(days, Day{Date: date})
+		}
+		days[i].Courses = append(days[i].Courses, course)
+	}
+	return days
+}
+
+// Module is the courses of a module
+type Module struct {
+	// Code is the module code, empty for courses without one
+	Code string
+	// Title is the title of the first course of the module
+	Title   string
+	Courses []models.Course
+}
+
+// GroupByModule splits courses by their module code, in order of first
+// appearance, keeping the order of the courses within a module
+func GroupByModule(courses []models.Course) []Module {
+	modules := []Module{}
+	index := make(map[string]int)
+	for _, course := range courses {
+		name := service.ParseCourseName(course.Name)
+
+		i, ok := index[name.Module]
+		if !ok {
+			i = len(modules)
+			index[name.Module] = i
+			modules = append(modules, Module{Code: name.Module, Title: name.Title})
+		}
+		modules[i].Courses = append(modules[i].Courses, course)
+	}
+	return modules
+}
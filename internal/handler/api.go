@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/LaulauChau/sws/internal/diff"
+	"github.com/LaulauChau/sws/internal/export"
+	"github.com/LaulauChau/sws/internal/filter"
+	"github.com/LaulauChau/sws/internal/middleware"
+)
+
+// writeJSON encodes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONError responds with a JSON error message
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// parseLimit reads the limit query parameter, returning 0 when absent
+func parseLimit(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// HandleAPICourses returns the upcoming courses with their codes as JSON,
+// filtered like the courses table and grouped with group=day or group=module
+func (h *WebHandler) HandleAPICourses(w http.ResponseWriter, r *http.Request) {
+	query, err := parseCourseQuery(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid filters: "+err.Error())
+		return
+	}
+
+	courses, err := h.account(r).Client.LoadNextCoursesContext(r.Context())
+	if err != nil {
+		writeUpstreamError(w, r, err, "Failed to get courses")
+		return
+	}
+
+	courses = query.filter.Apply(courses)
+	switch query.group {
+	case filter.ByDay:
+		writeJSON(w, http.StatusOK, export.NewDays(filter.GroupByDay(courses)))
+	case filter.ByModule:
+		writeJSON(w, http.StatusOK, export.NewModules(filter.GroupByModule(courses)))
+	default:
+		writeJSON(w, http.StatusOK, export.NewRecords(courses))
+	}
+}
+
+// HandleAPIChanges returns the most recent schedule changes as JSON
+func (h *WebHandler) HandleAPIChanges(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimit(r)
+	if err != nil || limit < 0 {
+		writeJSONError(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	repo := h.account(r).Store
+	if repo == nil {
+		writeJSONError(w, http.StatusNotFound, "History is not recorded for this account")
+		return
+	}
+	changes, err := repo.ListChanges(r.Context(), limit)
+	if err != nil {
+		middleware.Logf(r, "Error getting changes: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get changes")
+		return
+	}
+	if changes == nil {
+		changes = []diff.Change{}
+	}
+
+	writeJSON(w, http.StatusOK, changes)
+}
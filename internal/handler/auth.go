@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/LaulauChau/sws/internal/auth"
+	"github.com/LaulauChau/sws/internal/middleware"
+	"github.com/LaulauChau/sws/internal/ratelimit"
+	"github.com/LaulauChau/sws/web/templates"
+)
+
+// safeRedirect returns the local path to go back to after logging in
+func safeRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// HandleLogin shows the login form and starts a session on valid credentials
+func (h *WebHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	next := safeRedirect(r.FormValue("next"))
+	if !h.auth.Enabled() {
+		http.Redirect(w, r, next, http.StatusSeeOther)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if _, ok := h.auth.SessionFromRequest(r); ok {
+			http.Redirect(w, r, next, http.StatusSeeOther)
+			return
+		}
+		h.renderLogin(w, r, http.StatusOK, next, "")
+	case http.MethodPost:
+		if !h.auth.CheckLoginCSRF(r) {
+			h.renderLogin(w, r, http.StatusForbidden, next, "Formulaire expiré, veuillez réessayer")
+			return
+		}
+		if h.sessions != nil {
+			h.loginSowesign(w, r, next)
+			return
+		}
+
+		username := r.PostFormValue("username")
+		if !h.auth.CheckPassword(username, r.PostFormValue("password")) {
+			h.renderLogin(w, r, http.StatusUnauthorized, next, "Utilisateur ou mot de passe incorrect")
+			return
+		}
+
+		session, err := h.auth.NewSession(username)
+		if err == nil {
+			err = h.auth.SetSessionCookie(w, r, session)
+		}
+		if err != nil {
+			middleware.Logf(r, "Error starting session: %v", err)
+			http.Error(w, "Failed to start session", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, next, http.StatusSeeOther)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// renderLoginLimited answers a sign in attempt rejected by a rate limit with
+// 429 and Retry-After, returning false when err is not a rate limit error
+func (h *WebHandler) renderLoginLimited(w http.ResponseWriter, r *http.Request, err error) bool {
+	retryAfter, ok := ratelimit.RetryAfter(err)
+	if !ok {
+		return false
+	}
+	ratelimit.SetRetryAfter(w, retryAfter)
+	h.renderLogin(w, r, http.StatusTooManyRequests, safeRedirect(r.FormValue("next")), "Trop de tentatives, réessayez plus tard")
+	return true
+}
+
+func (h *WebHandler) renderLogin(w http.ResponseWriter, r *http.Request, status int, next, message string) {
+	token, err := h.auth.LoginCSRFToken(w, r)
+	if err != nil {
+		middleware.Logf(r, "Error creating login token: %v", err)
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(status)
+	page := templates.Login(next, token, message)
+	if h.sessions != nil {
+		page = templates.SowesignLogin(next, token, message)
+	}
+	if err := page.Render(r.Context(), w); err != nil {
+		middleware.Logf(r, "Error rendering template: %v", err)
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+	}
+}
+
+// HandleLogout ends the session and goes back to the login page
+func (h *WebHandler) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if session, ok := auth.FromContext(r.Context()); ok {
+		h.auth.Revoke(session)
+		if h.sessions != nil {
+			h.sessions.Logout(session.ID)
+		}
+	}
+	h.auth.ClearSessionCookie(w, r)
+	http.Redirect(w, r, auth.LoginPath, http.StatusSeeOther)
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/a-h/templ"
+
+	"github.com/LaulauChau/sws/internal/calendar"
+	"github.com/LaulauChau/sws/internal/filter"
+	"github.com/LaulauChau/sws/internal/middleware"
+	"github.com/LaulauChau/sws/web/templates"
+)
+
+// HandleCalendarView shows the upcoming courses in a week or month calendar,
+// filtered like the courses table. The view parameter picks week, the
+// default, or month and the date parameter the day to show, today by default
+func (h *WebHandler) HandleCalendarView(w http.ResponseWriter, r *http.Request) {
+	query, err := parseCourseQuery(r)
+	if err != nil {
+		http.Error(w, "Invalid filters: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	view := r.URL.Query().Get("view")
+	if view != "" && view != templates.ViewWeek && view != templates.ViewMonth {
+		http.Error(w, "Invalid view", http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	day, err := filter.ParseDate(r.URL.Query().Get("date"))
+	if err != nil {
+		http.Error(w, "Invalid date", http.StatusBadRequest)
+		return
+	}
+	if day.IsZero() {
+		day = now
+	}
+
+	acc := h.account(r)
+	courses, err := acc.Client.LoadNextCoursesContext(r.Context())
+	if err != nil {
+		writeUpstreamError(w, r, err, "Failed to get courses")
+		return
+	}
+	courses = query.filter.Apply(courses)
+
+	// Navigation keeps the filters and the profile
+	base := query.filter.Values()
+	if profile := r.URL.Query().Get("profile"); profile != "" {
+		base.Set("profile", profile)
+	}
+
+	var component templ.Component
+	if view == templates.ViewMonth {
+		component = templates.CalendarMonth(calendar.NewMonth(courses, day, now), base)
+	} else {
+		component = templates.CalendarWeek(calendar.NewWeek(courses, day, now), base)
+	}
+	if !isPartialRequest(r) {
+		component = templates.Calendar(component)
+	}
+	ctx := templates.WithFetchedAt(h.pageContext(r, acc), acc.Client.CoursesFetchedAt())
+	if err := component.Render(ctx, w); err != nil {
+		middleware.Logf(r, "Error rendering template: %v", err)
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/LaulauChau/sws/internal/account"
+	"github.com/LaulauChau/sws/internal/diff"
+	"github.com/LaulauChau/sws/internal/export"
+	"github.com/LaulauChau/sws/internal/middleware"
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/store"
+	"github.com/LaulauChau/sws/web/templates"
+)
+
+var (
+	// errCourseNotFound is returned for courses neither upcoming nor recorded
+	errCourseNotFound = errors.New("course not found")
+	// errHistory wraps the errors of the history database
+	errHistory = errors.New("failed to read the course history")
+)
+
+// courseDetail gathers everything known about a course, from the upcoming
+// courses first and from the history otherwise, which still works when
+// Sowesign is unreachable
+func (h *WebHandler) courseDetail(r *http.Request, acc *account.Account, id int) (models.Course, export.Detail, error) {
+	courses, upstreamErr := acc.Client.LoadNextCoursesContext(r.Context())
+
+	var course models.Course
+	detail := export.Detail{Changes: []diff.Change{}}
+	for _, c := range courses {
+		if c.ID == id {
+			course, detail.Upcoming = c, true
+			detail.Extra = acc.Client.CourseExtras(id)
+			break
+		}
+	}
+
+	if acc.Store != nil {
+		records, err := acc.Store.ListCourses(r.Context(), store.Filter{ID: id, Limit: 1})
+		if err != nil {
+			return models.Course{}, export.Detail{}, fmt.Errorf("%w: %v", errHistory, err)
+		}
+		if len(records) > 0 {
+			if !detail.Upcoming {
+				course = records[0].Course
+			}
+			detail.FirstSeen, detail.LastSeen = &records[0].FirstSeen, &records[0].LastSeen
+		}
+
+		changes, err := acc.Store.CourseChanges(r.Context(), id)
+		if err != nil {
+			return models.Course{}, export.Detail{}, fmt.Errorf("%w: %v", errHistory, err)
+		}
+		if changes != nil {
+			detail.Changes = changes
+		}
+	}
+
+	switch {
+	case course.ID != 0:
+	case upstreamErr != nil:
+		return models.Course{}, export.Detail{}, upstreamErr
+	default:
+		return models.Course{}, export.Detail{}, errCourseNotFound
+	}
+	detail.Record = export.NewRecord(course)
+	return course, detail, nil
+}
+
+// HandleCourse shows everything known about a course
+func (h *WebHandler) HandleCourse(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid course ID", http.StatusBadRequest)
+		return
+	}
+
+	acc := h.account(r)
+	course, detail, err := h.courseDetail(r, acc, id)
+	switch {
+	case errors.Is(err, errCourseNotFound):
+		http.Error(w, "Course not found", http.StatusNotFound)
+		return
+	case errors.Is(err, errHistory):
+		middleware.Logf(r, "Error getting course %d: %v", id, err)
+		http.Error(w, "Failed to get course history", http.StatusInternalServerError)
+		return
+	case err != nil:
+		writeUpstreamError(w, r, err, "Failed to get course")
+		return
+	}
+
+	if err := templates.CourseDetail(course, detail).Render(h.pageContext(r, acc), w); err != nil {
+		middleware.Logf(r, "Error rendering template: %v", err)
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+	}
+}
+
+// HandleAPICourse returns everything known about a course as JSON
+func (h *WebHandler) HandleAPICourse(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid course ID")
+		return
+	}
+
+	_, detail, err := h.courseDetail(r, h.account(r), id)
+	switch {
+	case errors.Is(err, errCourseNotFound):
+		writeJSONError(w, http.StatusNotFound, "Course not found")
+	case errors.Is(err, errHistory):
+		middleware.Logf(r, "Error getting course %d: %v", id, err)
+		writeJSONError(w, http.StatusInternalServerError, "Failed to get course history")
+	case err != nil:
+		writeUpstreamError(w, r, err, "Failed to get course")
+	default:
+		writeJSON(w, http.StatusOK, detail)
+	}
+}
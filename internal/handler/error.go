@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/LaulauChau/sws/internal/middleware"
+	"github.com/LaulauChau/sws/web/templates"
+)
+
+// HandleServerError answers a request that failed unexpectedly, with a page
+// or a JSON error for the API
+func (h *WebHandler) HandleServerError(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		writeJSONError(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	if err := templates.ServerError(middleware.RequestIDFromContext(r.Context())).Render(r.Context(), w); err != nil {
+		middleware.Logf(r, "Error rendering error page: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/account"
+	"github.com/LaulauChau/sws/internal/events"
+)
+
+// tickInterval is how often connected pages are asked to refresh countdowns
+const tickInterval = time.Minute
+
+// PublishCourses tells every page connected to the given account that the
+// courses changed. Pages reload their table from /table, with their own filters
+func (h *WebHandler) PublishCourses(acc *account.Account) {
+	acc.Broker.Publish(events.Event{Name: "courses", Data: acc.Client.CoursesFetchedAt().UTC().Format(time.RFC3339)})
+}
+
+// HandleEvents streams course updates and periodic ticks as Server-Sent Events
+func (h *WebHandler) HandleEvents(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	broker := h.account(r).Broker
+	ch := broker.Subscribe()
+	defer broker.Unsubscribe(ch)
+
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		var e events.Event
+		select {
+		case <-r.Context().Done():
+			return
+		case e = <-ch:
+		case t := <-ticker.C:
+			e = events.Event{Name: "tick", Data: t.UTC().Format(time.RFC3339)}
+		}
+
+		if err := events.Write(w, e); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
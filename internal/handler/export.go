@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/account"
+	"github.com/LaulauChau/sws/internal/export"
+	"github.com/LaulauChau/sws/internal/middleware"
+)
+
+// feedAccount returns the account whose feed token the request carries
+func (h *WebHandler) feedAccount(r *http.Request) (*account.Account, bool) {
+	return h.accounts.ByFeedToken(r.URL.Query().Get("token"))
+}
+
+// HandleCalendar serves the upcoming courses as a subscribable iCalendar feed
+func (h *WebHandler) HandleCalendar(w http.ResponseWriter, r *http.Request) {
+	acc, ok := h.feedAccount(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	courses, err := acc.Client.LoadNextCoursesContext(r.Context())
+	if err != nil {
+		writeUpstreamError(w, r, err, "Failed to get courses")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="sws.ics"`)
+	if err := export.WriteICS(w, courses, time.Now()); err != nil {
+		middleware.Logf(r, "Error rendering calendar: %v", err)
+		http.Error(w, "Failed to render calendar", http.StatusInternalServerError)
+	}
+}
+
+// HandleExportCSV serves the upcoming courses as CSV, with an optional
+// delimiter and byte order mark for spreadsheet applications
+func (h *WebHandler) HandleExportCSV(w http.ResponseWriter, r *http.Request) {
+	acc, ok := h.feedAccount(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	delimiter, err := export.ParseDelimiter(r.URL.Query().Get("delimiter"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	courses, err := acc.Client.LoadNextCoursesContext(r.Context())
+	if err != nil {
+		writeUpstreamError(w, r, err, "Failed to get courses")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="sws.csv"`)
+	opts := export.CSVOptions{Delimiter: delimiter, BOM: r.URL.Query().Get("bom") == "1"}
+	if err := export.WriteCSV(w, courses, opts); err != nil {
+		middleware.Logf(r, "Error rendering CSV: %v", err)
+		http.Error(w, "Failed to render CSV", http.StatusInternalServerError)
+	}
+}
+
+// HandleExportJSONLines serves the upcoming courses as JSON lines
+func (h *WebHandler) HandleExportJSONLines(w http.ResponseWriter, r *http.Request) {
+	acc, ok := h.feedAccount(r)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	courses, err := acc.Client.LoadNextCoursesContext(r.Context())
+	if err != nil {
+		writeUpstreamError(w, r, err, "Failed to get courses")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/jsonl; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="sws.jsonl"`)
+	if err := export.WriteJSONLines(w, courses); err != nil {
+		middleware.Logf(r, "Error rendering JSON lines: %v", err)
+		http.Error(w, "Failed to render JSON lines", http.StatusInternalServerError)
+	}
+}
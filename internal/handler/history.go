@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/LaulauChau/sws/internal/filter"
+	"github.com/LaulauChau/sws/internal/middleware"
+	"github.com/LaulauChau/sws/internal/store"
+	"github.com/LaulauChau/sws/web/templates"
+)
+
+// isPartialRequest reports whether htmx asked for a fragment rather than a
+// full page, boosted links and forms navigating to full pages
+func isPartialRequest(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true" && r.Header.Get("HX-Boosted") != "true" &&
+		r.Header.Get("HX-History-Restore-Request") != "true"
+}
+
+// HandleHistory shows every course ever seen, filtered by date range and name
+func (h *WebHandler) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	acc := h.account(r)
+	if acc.Store == nil {
+		http.Error(w, "History is not recorded for this account", http.StatusNotFound)
+		return
+	}
+	q := r.URL.Query()
+	from, to, name := q.Get("from"), q.Get("to"), strings.TrimSpace(q.Get("name"))
+
+	query := store.Filter{Name: name}
+	var err error
+	if query.From, err = filter.ParseDate(from); err != nil {
+		http.Error(w, "Invalid start date", http.StatusBadRequest)
+		return
+	}
+	if query.To, err = filter.ParseDate(to); err != nil {
+		http.Error(w, "Invalid end date", http.StatusBadRequest)
+		return
+	}
+	if !query.To.IsZero() {
+		// The end date is inclusive
+		query.To = query.To.AddDate(0, 0, 1)
+	}
+
+	records, err := acc.Store.ListCourses(r.Context(), query)
+	if err != nil {
+		middleware.Logf(r, "Error getting history: %v", err)
+		http.Error(w, "Failed to get history", http.StatusInternalServerError)
+		return
+	}
+
+	component := templates.History(records, from, to, name)
+	if isPartialRequest(r) {
+		component = templates.HistoryTable(records)
+	}
+	if err := component.Render(h.pageContext(r, acc), w); err != nil {
+		middleware.Logf(r, "Error rendering template: %v", err)
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+	}
+}
+
+// HandleChanges shows the feed of detected schedule changes
+func (h *WebHandler) HandleChanges(w http.ResponseWriter, r *http.Request) {
+	acc := h.account(r)
+	if acc.Store == nil {
+		http.Error(w, "History is not recorded for this account", http.StatusNotFound)
+		return
+	}
+	changes, err := acc.Store.ListChanges(r.Context(), 200)
+	if err != nil {
+		middleware.Logf(r, "Error getting changes: %v", err)
+		http.Error(w, "Failed to get changes", http.StatusInternalServerError)
+		return
+	}
+
+	if err := templates.Changes(changes).Render(h.pageContext(r, acc), w); err != nil {
+		middleware.Logf(r, "Error rendering template: %v", err)
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+	}
+}
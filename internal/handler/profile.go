@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/account"
+)
+
+// profileCookie remembers the profile selected in the switcher
+const profileCookie = "sws_profile"
+
+// account returns the account of the Sowesign session, or the account
+// selected by the profile query parameter or cookie, falling back to the
+// default profile
+func (h *WebHandler) account(r *http.Request) *account.Account {
+	if acc, ok := sessionAccount(r); ok {
+		return acc
+	}
+	if name := r.URL.Query().Get("profile"); name != "" {
+		if acc, ok := h.accounts.Get(name); ok {
+			return acc
+		}
+	}
+	if c, err := r.Cookie(profileCookie); err == nil {
+		if acc, ok := h.accounts.Get(c.Value); ok {
+			return acc
+		}
+	}
+	return h.accounts.Default()
+}
+
+// HandleProfile switches the displayed profile and reloads the page
+func (h *WebHandler) HandleProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	acc, ok := h.accounts.Get(r.FormValue("profile"))
+	if !ok {
+		http.Error(w, "Unknown profile", http.StatusBadRequest)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     profileCookie,
+		Value:    acc.Name,
+		Path:     "/",
+		MaxAge:   int((365 * 24 * time.Hour).Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Refresh", "true")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
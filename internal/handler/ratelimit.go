@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/config"
+	"github.com/LaulauChau/sws/internal/middleware"
+	"github.com/LaulauChau/sws/internal/ratelimit"
+)
+
+// SetRateLimiter limits the requests of each client IP to the client rate
+// of the profile they use
+func (h *WebHandler) SetRateLimiter(limiter *ratelimit.Keyed) {
+	h.limiter = limiter
+}
+
+// RateLimit rejects the requests of clients going over the client rate of
+// their profile with 429 Too Many Requests
+func (h *WebHandler) RateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.limiter == nil {
+			next(w, r)
+			return
+		}
+
+		acc := h.account(r)
+		err := h.limiter.Allow(acc.Name+"|"+ratelimit.ClientIP(r), acc.Config().ProfileRateLimits().Client)
+		if err != nil {
+			writeUpstreamError(w, r, err, "")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// loginRate limits the sign in attempts of each client IP, slowing down the
+// guessing of passwords and PINs
+var loginRate = config.Rate{Requests: 5, Per: time.Minute, Burst: 5}
+
+// LoginRateLimit rejects the sign in attempts of clients going over
+// loginRate with 429 Too Many Requests
+func (h *WebHandler) LoginRateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.limiter == nil || r.Method != http.MethodPost {
+			next(w, r)
+			return
+		}
+
+		if err := h.limiter.Allow("login|"+ratelimit.ClientIP(r), loginRate); err != nil {
+			h.renderLoginLimited(w, r, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// writeUpstreamError answers a request whose call to Sowesign failed,
+// with 429 and Retry-After when it was rate limited
+func writeUpstreamError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	status := http.StatusInternalServerError
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		status = http.StatusBadGateway
+	}
+	if retryAfter, ok := ratelimit.RetryAfter(err); ok {
+		ratelimit.SetRetryAfter(w, retryAfter)
+		status, message = http.StatusTooManyRequests, "Too many requests, retry later"
+	} else {
+		middleware.Logf(r, "Error calling Sowesign: %v", err)
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		writeJSONError(w, status, message)
+		return
+	}
+	http.Error(w, message, status)
+}
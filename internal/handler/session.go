@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/LaulauChau/sws/internal/account"
+	"github.com/LaulauChau/sws/internal/auth"
+	"github.com/LaulauChau/sws/internal/middleware"
+	"github.com/LaulauChau/sws/internal/session"
+)
+
+type accountKey struct{}
+
+// SetSessions makes visitors sign in with their own Sowesign credentials,
+// each session getting the account of the manager instead of a profile
+func (h *WebHandler) SetSessions(sessions *session.Manager) {
+	h.sessions = sessions
+}
+
+// RequireAccount resolves the account of the session of authenticated
+// requests, sending visitors whose session was evicted back to the login page
+func (h *WebHandler) RequireAccount(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.sessions == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if s, ok := auth.FromContext(r.Context()); ok {
+			if acc, ok := h.sessions.Get(s.ID); ok {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), accountKey{}, acc)))
+				return
+			}
+		}
+
+		h.auth.ClearSessionCookie(w, r)
+		target := auth.LoginPath + "?next=" + url.QueryEscape(r.URL.RequestURI())
+		if r.Header.Get("HX-Request") == "true" {
+			w.Header().Set("HX-Redirect", target)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, target, http.StatusSeeOther)
+	})
+}
+
+// sessionAccount returns the account resolved by RequireAccount
+func sessionAccount(r *http.Request) (*account.Account, bool) {
+	acc, ok := r.Context().Value(accountKey{}).(*account.Account)
+	return acc, ok
+}
+
+// loginSowesign starts a session for a visitor whose Sowesign credentials
+// are accepted
+func (h *WebHandler) loginSowesign(w http.ResponseWriter, r *http.Request, next string) {
+	code := r.PostFormValue("code_etablissement")
+	identifiant := r.PostFormValue("identifiant")
+	pin := r.PostFormValue("pin")
+	if code == "" || identifiant == "" || pin == "" {
+		h.renderLogin(w, r, http.StatusBadRequest, next, "Tous les champs sont requis")
+		return
+	}
+
+	s, err := h.auth.NewSession(identifiant)
+	if err != nil {
+		middleware.Logf(r, "Error starting session: %v", err)
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.sessions.Login(s.ID, code, identifiant, pin); err != nil {
+		// Attempts count against the calls to Sowesign of the whole server
+		if h.renderLoginLimited(w, r, err) {
+			return
+		}
+		h.renderLogin(w, r, http.StatusUnauthorized, next, "Identifiants Sowesign refusés")
+		return
+	}
+	if err := h.auth.SetSessionCookie(w, r, s); err != nil {
+		h.sessions.Logout(s.ID)
+		middleware.Logf(r, "Error starting session: %v", err)
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
@@ -1,53 +1,168 @@
 package handler
 
 import (
+	"context"
 	"net/http"
 
-	"github.com/LaulauChau/sws/internal/client"
-	"github.com/LaulauChau/sws/internal/config"
+	"github.com/LaulauChau/sws/internal/account"
+	"github.com/LaulauChau/sws/internal/auth"
+	"github.com/LaulauChau/sws/internal/filter"
+	"github.com/LaulauChau/sws/internal/middleware"
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/ratelimit"
+	"github.com/LaulauChau/sws/internal/session"
 	"github.com/LaulauChau/sws/web/templates"
 )
 
 type WebHandler struct {
-	client *client.Client
+	accounts *account.Registry
+	auth     *auth.Authenticator
+	sessions *session.Manager
+	limiter  *ratelimit.Keyed
 }
 
-func NewWebHandler(cfg config.Config) *WebHandler {
+func NewWebHandler(accounts *account.Registry, authenticator *auth.Authenticator) *WebHandler {
 	return &WebHandler{
-		client: client.NewClient(cfg),
+		accounts: accounts,
+		auth:     authenticator,
 	}
 }
 
 func (h *WebHandler) HandleIndex(w http.ResponseWriter, r *http.Request) {
-	if err := h.client.GetToken(); err != nil {
-		http.Error(w, "Failed to get token", http.StatusInternalServerError)
+	query, err := parseCourseQuery(r)
+	if err != nil {
+		http.Error(w, "Invalid filters: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	acc := h.account(r)
+	if err := acc.Client.GetTokenContext(r.Context()); err != nil {
+		writeUpstreamError(w, r, err, "Failed to get token")
 		return
 	}
 
-	courses, err := h.client.GetNextCourses()
+	courses, err := acc.Client.GetNextCoursesContext(r.Context())
 	if err != nil {
-		http.Error(w, "Failed to get courses", http.StatusInternalServerError)
+		writeUpstreamError(w, r, err, "Failed to get courses")
 		return
 	}
 
-	if err := templates.Index(courses).Render(r.Context(), w); err != nil {
+	ctx := templates.WithFetchedAt(h.pageContext(r, acc), acc.Client.CoursesFetchedAt())
+	component := templates.Index(query.filter.Apply(courses), query.filter, query.group, filter.Modules(courses))
+	if err := component.Render(ctx, w); err != nil {
+		middleware.Logf(r, "Error rendering template: %v", err)
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
 	}
 }
 
 func (h *WebHandler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
-	if err := h.client.GetToken(); err != nil {
-		http.Error(w, "Failed to get token", http.StatusInternalServerError)
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query, err := parseCourseQuery(r)
+	if err != nil {
+		http.Error(w, "Invalid filters: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	courses, err := h.client.GetNextCourses()
+	acc := h.account(r)
+	if err := acc.Client.GetTokenContext(r.Context()); err != nil {
+		writeUpstreamError(w, r, err, "Failed to get token")
+		return
+	}
+
+	courses, err := acc.Client.GetNextCoursesContext(r.Context())
 	if err != nil {
-		http.Error(w, "Failed to get courses", http.StatusInternalServerError)
+		writeUpstreamError(w, r, err, "Failed to get courses")
 		return
 	}
 
-	if err := templates.CoursesTable(courses).Render(r.Context(), w); err != nil {
+	h.renderCoursesTable(w, r, acc, courses, query)
+}
+
+// HandleTable re-renders the courses table, preferably from the cache
+func (h *WebHandler) HandleTable(w http.ResponseWriter, r *http.Request) {
+	query, err := parseCourseQuery(r)
+	if err != nil {
+		http.Error(w, "Invalid filters: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	acc := h.account(r)
+	courses, err := acc.Client.LoadNextCoursesContext(r.Context())
+	if err != nil {
+		writeUpstreamError(w, r, err, "Failed to get courses")
+		return
+	}
+
+	h.renderCoursesTable(w, r, acc, courses, query)
+}
+
+// courseQuery is how the courses table is filtered and laid out
+type courseQuery struct {
+	filter filter.Filter
+	group  filter.Grouping
+}
+
+// parseCourseQuery reads the filters of the courses table from the query
+// string, or the form of POST requests, grouping by day or module with the
+// group parameter
+func parseCourseQuery(r *http.Request) (courseQuery, error) {
+	if err := r.ParseForm(); err != nil {
+		return courseQuery{}, err
+	}
+	f, err := filter.Parse(r.Form)
+	if err != nil {
+		return courseQuery{}, err
+	}
+	group, err := filter.ParseGrouping(r.Form.Get("group"))
+	if err != nil {
+		return courseQuery{}, err
+	}
+	return courseQuery{filter: f, group: group}, nil
+}
+
+// indexURL returns the URL of the index showing the courses of a query, in
+// the profile of the request
+func indexURL(r *http.Request, query courseQuery) string {
+	values := query.filter.Values()
+	if query.group != filter.NoGrouping {
+		values.Set("group", string(query.group))
+	}
+	if profile := r.URL.Query().Get("profile"); profile != "" {
+		values.Set("profile", profile)
+	}
+	if len(values) == 0 {
+		return "/"
+	}
+	return "/?" + values.Encode()
+}
+
+// renderCoursesTable renders the filtered courses table fragment, replacing
+// the URL of the page so that it can be shared or reloaded with the filters
+func (h *WebHandler) renderCoursesTable(w http.ResponseWriter, r *http.Request, acc *account.Account, courses []models.Course, query courseQuery) {
+	w.Header().Set("HX-Replace-Url", indexURL(r, query))
+	ctx := templates.WithFetchedAt(r.Context(), acc.Client.CoursesFetchedAt())
+	if err := templates.CoursesTable(query.filter.Apply(courses), query.group).Render(ctx, w); err != nil {
+		middleware.Logf(r, "Error rendering template: %v", err)
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
 	}
 }
+
+// pageContext prepares the context of a full page, which shows the profile
+// switcher and the logged in user
+func (h *WebHandler) pageContext(r *http.Request, acc *account.Account) context.Context {
+	ctx := r.Context()
+	if h.sessions == nil {
+		ctx = templates.WithProfiles(ctx, h.accounts.Names(), acc.Name)
+	}
+	if acc.Store == nil {
+		ctx = templates.WithoutHistory(ctx)
+	}
+	if session, ok := auth.FromContext(ctx); ok {
+		ctx = templates.WithUser(ctx, session.Username, h.auth.CSRFToken(session))
+	}
+	return ctx
+}
@@ -0,0 +1,70 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/client"
+	"github.com/LaulauChau/sws/internal/config"
+)
+
+// Config checks that the configuration in use is valid. A failed reload is
+// only reported, the previous configuration still being served
+func Config(current func() config.Config, reloadErr func() error) CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		if err := current().Validate(); err != nil {
+			return "", err
+		}
+		if err := reloadErr(); err != nil {
+			return fmt.Sprintf("last reload failed, the previous configuration is in use: %v", err), nil
+		}
+		return "", nil
+	}
+}
+
+// Token checks that the client obtained a token within maxAge, the
+// background refreshes obtaining one each time
+func Token(c *client.Client, maxAge func() time.Duration) CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		obtained, err := c.TokenStatus()
+		if obtained.IsZero() {
+			if err != nil {
+				return "", fmt.Errorf("no token obtained yet: %v", err)
+			}
+			return "", fmt.Errorf("no token obtained yet")
+		}
+
+		age := time.Since(obtained).Round(time.Second)
+		if age > maxAge() {
+			return "", fmt.Errorf("last token obtained %s ago: %v", age, err)
+		}
+		return fmt.Sprintf("token obtained %s ago", age), nil
+	}
+}
+
+// Courses checks that courses are cached, or else that Sowesign is
+// reachable to fetch them
+func Courses(c *client.Client) CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		// The statistics are read rather than the cache so that probes do
+		// not count as hits or misses
+		if stats := c.CacheStats(); stats.Initialized && stats.Age <= c.Config().CacheTTL {
+			return fmt.Sprintf("courses cached %s ago", stats.Age.Round(time.Second)), nil
+		}
+		if err := c.Ping(ctx); err != nil {
+			return "", fmt.Errorf("no courses cached and Sowesign is unreachable: %v", err)
+		}
+		return "no courses cached, Sowesign is reachable", nil
+	}
+}
+
+// Upstream checks that Sowesign is reachable
+func Upstream(c *client.Client) CheckFunc {
+	return func(ctx context.Context) (string, error) {
+		if err := c.Ping(ctx); err != nil {
+			return "", fmt.Errorf("Sowesign is unreachable: %v", err)
+		}
+		return "Sowesign is reachable", nil
+	}
+}
@@ -0,0 +1,154 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFunc checks one dependency, returning details shown in the report or
+// why it is unusable
+type CheckFunc func(ctx context.Context) (detail string, err error)
+
+// Result is the outcome of one check
+type Result struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the outcome of every check, ok when they all passed
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker runs named checks concurrently, each one within a timeout. Reports
+// are reused for a while so that frequent probes do not hammer Sowesign
+type Checker struct {
+	timeout  time.Duration
+	cacheFor time.Duration
+
+	mu     sync.Mutex
+	checks []check
+	report Report
+	ran    time.Time
+	now    func() time.Time
+}
+
+// NewChecker creates a checker giving each check timeout to complete and
+// reusing its reports for cacheFor
+func NewChecker(timeout, cacheFor time.Duration) *Checker {
+	return &Checker{
+		timeout:  timeout,
+		cacheFor: cacheFor,
+		now:      time.Now,
+	}
+}
+
+// Add registers a check, reported in the order of registration
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks = append(c.checks, check{name: name, fn: fn})
+	c.ran = time.Time{}
+}
+
+// Run runs every check, or returns the last report while it is fresh
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.ran.IsZero() && c.now().Sub(c.ran) < c.cacheFor {
+		return c.report
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK, Checks: make([]Result, len(c.checks))}
+	var wg sync.WaitGroup
+	for i, ch := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = run(ctx, ch)
+		}()
+	}
+	wg.Wait()
+
+	for _, r := range report.Checks {
+		if r.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	c.report, c.ran = report, c.now()
+	return report
+}
+
+// run runs one check, failing it when it outlives the context
+func run(ctx context.Context, ch check) Result {
+	type outcome struct {
+		detail string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		detail, err := ch.fn(ctx)
+		done <- outcome{detail, err}
+	}()
+
+	result := Result{Name: ch.name, Status: StatusOK}
+	select {
+	case o := <-done:
+		result.Detail = o.detail
+		if o.err != nil {
+			result.Status, result.Error = StatusFail, o.err.Error()
+		}
+	case <-ctx.Done():
+		result.Status, result.Error = StatusFail, "timed out"
+	}
+	return result
+}
+
+// Handler serves the report as JSON, with 503 Service Unavailable when a
+// check failed
+func (c *Checker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Run(r.Context())
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		writeReport(w, status, report)
+	})
+}
+
+// Live serves an ok report as long as the process answers
+func Live() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, http.StatusOK, Report{Status: StatusOK, Checks: []Result{}})
+	})
+}
+
+// writeReport encodes a report, which must never be cached
+func writeReport(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
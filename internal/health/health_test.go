@@ -0,0 +1,160 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/client"
+	"github.com/LaulauChau/sws/internal/config"
+	"github.com/LaulauChau/sws/internal/models"
+)
+
+func TestChecker_Handler(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     map[string]CheckFunc
+		wantStatus int
+		want       []Result
+	}{
+		{
+			name: "all checks pass",
+			checks: map[string]CheckFunc{
+				"config": func(ctx context.Context) (string, error) { return "", nil },
+			},
+			wantStatus: http.StatusOK,
+			want:       []Result{{Name: "config", Status: StatusOK}},
+		},
+		{
+			name: "failed check",
+			checks: map[string]CheckFunc{
+				"token": func(ctx context.Context) (string, error) { return "", errors.New("no token") },
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			want:       []Result{{Name: "token", Status: StatusFail, Error: "no token"}},
+		},
+		{
+			name: "slow check",
+			checks: map[string]CheckFunc{
+				"courses": func(ctx context.Context) (string, error) {
+					time.Sleep(time.Second)
+					return "late", nil
+				},
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			want:       []Result{{Name: "courses", Status: StatusFail, Error: "timed out"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(50*time.Millisecond, 0)
+			for name, fn := range tt.checks {
+				c.Add(name, fn)
+			}
+
+			rec := httptest.NewRecorder()
+			c.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Handler() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			var report Report
+			if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+				t.Fatalf("failed to decode report: %v", err)
+			}
+			if len(report.Checks) != len(tt.want) || report.Checks[0] != tt.want[0] {
+				t.Errorf("Handler() checks = %+v, want %+v", report.Checks, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecker_CachesReports(t *testing.T) {
+	var runs int
+	c := NewChecker(time.Second, time.Minute)
+	now := time.Date(2025, 1, 6, 8, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	c.Add("upstream", func(ctx context.Context) (string, error) {
+		runs++
+		return "", nil
+	})
+
+	c.Run(context.Background())
+	c.Run(context.Background())
+	if runs != 1 {
+		t.Errorf("expected the second run to reuse the report, got %d runs", runs)
+	}
+
+	now = now.Add(time.Minute)
+	c.Run(context.Background())
+	if runs != 2 {
+		t.Errorf("expected a stale report to be refreshed, got %d runs", runs)
+	}
+}
+
+func TestChecks(t *testing.T) {
+	upstreamStatus := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(upstreamStatus)
+		if r.Method == http.MethodPost {
+			json.NewEncoder(w).Encode(client.AuthResponse{Token: "test-token"})
+		} else if r.Method == http.MethodGet {
+			json.NewEncoder(w).Encode([]models.Course{{ID: 1}})
+		}
+	}))
+	defer server.Close()
+	client.SetBaseURLs(server.URL, server.URL, server.URL)
+
+	c := client.NewClient(config.NewTestConfig(), nil)
+	ctx := context.Background()
+	hour := func() time.Duration { return time.Hour }
+
+	if _, err := Token(c, hour)(ctx); err == nil || !strings.Contains(err.Error(), "no token obtained yet") {
+		t.Errorf("Token() before any token = %v, want no token error", err)
+	}
+	if detail, err := Courses(c)(ctx); err != nil || !strings.Contains(detail, "reachable") {
+		t.Errorf("Courses() with an empty cache = %q, %v, want Sowesign reachable", detail, err)
+	}
+
+	if _, err := c.LoadNextCourses(); err != nil {
+		t.Fatalf("LoadNextCourses() error = %v", err)
+	}
+	if detail, err := Token(c, hour)(ctx); err != nil || !strings.Contains(detail, "token obtained") {
+		t.Errorf("Token() after a refresh = %q, %v, want ok", detail, err)
+	}
+	if _, err := Token(c, func() time.Duration { return -time.Second })(ctx); err == nil {
+		t.Error("Token() should fail when the token is too old")
+	}
+	if detail, err := Courses(c)(ctx); err != nil || !strings.Contains(detail, "courses cached") {
+		t.Errorf("Courses() with cached courses = %q, %v, want ok", detail, err)
+	}
+
+	upstreamStatus = http.StatusBadGateway
+	if _, err := Upstream(c)(ctx); err == nil || !strings.Contains(err.Error(), "unreachable") {
+		t.Errorf("Upstream() = %v, want unreachable error", err)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	cfg := config.NewTestConfig()
+	current := func() config.Config { return cfg }
+
+	if _, err := Config(current, func() error { return nil })(context.Background()); err != nil {
+		t.Errorf("Config() = %v, want nil", err)
+	}
+	detail, err := Config(current, func() error { return errors.New("refresh_interval: invalid") })(context.Background())
+	if err != nil || !strings.Contains(detail, "last reload failed") {
+		t.Errorf("Config() after a failed reload = %q, %v, want ok with details", detail, err)
+	}
+
+	cfg.ListenAddr = ""
+	if _, err := Config(current, func() error { return nil })(context.Background()); err == nil {
+		t.Error("Config() should fail on an invalid configuration")
+	}
+}
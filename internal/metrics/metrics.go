@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/LaulauChau/sws/pkg/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "sws"
+
+// Metrics records how the service behaves. A nil *Metrics records nothing,
+// so that components work without metrics
+type Metrics struct {
+	gatherer prometheus.Gatherer
+
+	upstreamRequests *prometheus.CounterVec
+	upstreamDuration *prometheus.HistogramVec
+	tokenRefreshes   *prometheus.CounterVec
+	handlerDuration  *prometheus.HistogramVec
+	codeErrors       *prometheus.CounterVec
+	caches           *cacheCollector
+}
+
+// New creates the metrics and registers them in reg, which is also exposed
+// by Handler
+func New(reg interface {
+	prometheus.Registerer
+	prometheus.Gatherer
+}) *Metrics {
+	m := &Metrics{
+		gatherer: reg,
+		upstreamRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upstream_requests_total",
+			Help:      "Requests sent to Sowesign by endpoint and status code.",
+		}, []string{"endpoint", "status"}),
+		upstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "upstream_request_duration_seconds",
+			Help:      "Latency of the requests sent to Sowesign by endpoint and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"endpoint", "status"}),
+		tokenRefreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "token_refreshes_total",
+			Help:      "Sowesign token requests by result.",
+		}, []string{"result"}),
+		handlerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of the HTTP handlers by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "code"}),
+		codeErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "code_generation_errors_total",
+			Help:      "Courses whose code could not be generated by reason.",
+		}, []string{"reason"}),
+		caches: newCacheCollector(),
+	}
+
+	reg.MustRegister(m.upstreamRequests, m.upstreamDuration, m.tokenRefreshes, m.handlerDuration, m.codeErrors, m.caches)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.gatherer, promhttp.HandlerOpts{})
+}
+
+// ObserveUpstream records a request sent to a Sowesign endpoint, status 0
+// meaning that no response was received
+func (m *Metrics) ObserveUpstream(endpoint string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	code := "error"
+	if status > 0 {
+		code = strconv.Itoa(status)
+	}
+	m.upstreamRequests.WithLabelValues(endpoint, code).Inc()
+	m.upstreamDuration.WithLabelValues(endpoint, code).Observe(duration.Seconds())
+}
+
+// ObserveTokenRefresh records a token request
+func (m *Metrics) ObserveTokenRefresh(err error) {
+	if m == nil {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.tokenRefreshes.WithLabelValues(result).Inc()
+}
+
+// ObserveCodeError records a course whose code could not be generated
+func (m *Metrics) ObserveCodeError(reason string) {
+	if m == nil {
+		return
+	}
+	m.codeErrors.WithLabelValues(reason).Inc()
+}
+
+// InstrumentHandler records the latency of a handler under its route
+func (m *Metrics) InstrumentHandler(route string, next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+	return promhttp.InstrumentHandlerDuration(m.handlerDuration.MustCurryWith(prometheus.Labels{"route": route}), next)
+}
+
+// AddCache exposes the hits, misses and age of the course cache of a profile
+func (m *Metrics) AddCache(profile string, stats func() cache.Stats) {
+	if m == nil {
+		return
+	}
+	m.caches.add(profile, stats)
+}
+
+// cacheCollector reads the statistics of the caches at every scrape
+type cacheCollector struct {
+	hits, misses, age *prometheus.Desc
+
+	mu    sync.Mutex
+	stats map[string]func() cache.Stats
+}
+
+func newCacheCollector() *cacheCollector {
+	return &cacheCollector{
+		hits: prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", "hits_total"),
+			"Course lookups served from the cache by profile.", []string{"profile"}, nil),
+		misses: prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", "misses_total"),
+			"Course lookups missing or expired in the cache by profile.", []string{"profile"}, nil),
+		age: prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", "age_seconds"),
+			"Age of the cached courses by profile, absent when nothing is cached.", []string{"profile"}, nil),
+		stats: make(map[string]func() cache.Stats),
+	}
+}
+
+func (c *cacheCollector) add(profile string, stats func() cache.Stats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats[profile] = stats
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.age
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for profile, stats := range c.stats {
+		s := stats()
+		ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(s.Hits), profile)
+		ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(s.Misses), profile)
+		if s.Initialized {
+			ch <- prometheus.MustNewConstMetric(c.age, prometheus.GaugeValue, s.Age.Seconds(), profile)
+		}
+	}
+}
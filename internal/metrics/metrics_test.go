@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/pkg/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := New(reg)
+
+	m.ObserveUpstream("token", http.StatusOK, 20*time.Millisecond)
+	m.ObserveUpstream("token", 0, time.Second)
+	m.ObserveTokenRefresh(nil)
+	m.ObserveTokenRefresh(errors.New("unauthorized"))
+	m.ObserveTokenRefresh(nil)
+	m.ObserveCodeError("missing_id")
+	m.AddCache("default", func() cache.Stats {
+		return cache.Stats{Hits: 3, Misses: 1, Age: 90 * time.Second, Initialized: true}
+	})
+	m.AddCache("work", func() cache.Stats { return cache.Stats{Misses: 2} })
+
+	tests := []struct {
+		name      string
+		collector prometheus.Collector
+		want      string
+	}{
+		{
+			name:      "upstream requests",
+			collector: m.upstreamRequests,
+			want: `
+# HELP sws_upstream_requests_total Requests sent to Sowesign by endpoint and status code.
+# TYPE sws_upstream_requests_total counter
+sws_upstream_requests_total{endpoint="token",status="200"} 1
+sws_upstream_requests_total{endpoint="token",status="error"} 1
+`,
+		},
+		{
+			name:      "token refreshes",
+			collector: m.tokenRefreshes,
+			want: `
+# HELP sws_token_refreshes_total Sowesign token requests by result.
+# TYPE sws_token_refreshes_total counter
+sws_token_refreshes_total{result="failure"} 1
+sws_token_refreshes_total{result="success"} 2
+`,
+		},
+		{
+			name:      "cache",
+			collector: m.caches,
+			want: `
+# HELP sws_cache_age_seconds Age of the cached courses by profile, absent when nothing is cached.
+# TYPE sws_cache_age_seconds gauge
+sws_cache_age_seconds{profile="default"} 90
+# HELP sws_cache_hits_total Course lookups served from the cache by profile.
+# TYPE sws_cache_hits_total counter
+sws_cache_hits_total{profile="default"} 3
+sws_cache_hits_total{profile="work"} 0
+# HELP sws_cache_misses_total Course lookups missing or expired in the cache by profile.
+# TYPE sws_cache_misses_total counter
+sws_cache_misses_total{profile="default"} 1
+sws_cache_misses_total{profile="work"} 2
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := testutil.CollectAndCompare(tt.collector, strings.NewReader(tt.want)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	if got := testutil.ToFloat64(m.codeErrors.WithLabelValues("missing_id")); got != 1 {
+		t.Errorf("code_generation_errors_total = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.upstreamDuration); got != 2 {
+		t.Errorf("upstream_request_duration_seconds has %d series, want 2", got)
+	}
+}
+
+func TestMetrics_InstrumentHandler(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := New(reg)
+
+	h := m.InstrumentHandler("/table", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/table", nil))
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	want := `sws_http_request_duration_seconds_count{code="418",method="get",route="/table"} 1`
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("Handler() output does not contain %q:\n%s", want, rec.Body.String())
+	}
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	var m *Metrics
+
+	// A nil *Metrics records nothing without failing
+	m.ObserveUpstream("token", http.StatusOK, time.Second)
+	m.ObserveTokenRefresh(nil)
+	m.ObserveCodeError("missing_id")
+	m.AddCache("default", func() cache.Stats { return cache.Stats{} })
+	next := http.NotFoundHandler()
+	if h := m.InstrumentHandler("/", next); h == nil {
+		t.Error("InstrumentHandler() = nil, want the handler itself")
+	}
+}
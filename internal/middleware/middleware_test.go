@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	}), mark("outer"), mark("inner"))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := strings.Join(order, ","); got != "outer,inner,handler" {
+		t.Errorf("Chain() order = %s, want outer,inner,handler", got)
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	h := SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	for _, header := range []string{"Content-Security-Policy", "X-Content-Type-Options", "Referrer-Policy"} {
+		if rec.Header().Get(header) == "" {
+			t.Errorf("SecurityHeaders() should set %s", header)
+		}
+	}
+	if csp := rec.Header().Get("Content-Security-Policy"); strings.Contains(csp, "unsafe-") {
+		t.Errorf("Content-Security-Policy = %q, want no unsafe source", csp)
+	}
+	if rec.Header().Get("Strict-Transport-Security") != "" {
+		t.Error("SecurityHeaders() should not set HSTS without TLS")
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.TLS = &tls.ConnectionState{}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if rec.Header().Get("Strict-Transport-Security") == "" {
+		t.Error("SecurityHeaders() should set HSTS with TLS")
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	h := MaxBodySize(8)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		}
+	}))
+
+	tests := []struct {
+		name       string
+		body       string
+		chunked    bool
+		wantStatus int
+	}{
+		{name: "small body", body: "12345678", wantStatus: http.StatusOK},
+		{name: "declared large body", body: "123456789", wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "undeclared large body", body: "123456789", chunked: true, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.chunked {
+				r.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("MaxBodySize() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var got string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = RequestIDFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		want     string
+	}{
+		{name: "generated", incoming: "", want: ""},
+		{name: "from proxy", incoming: "abc-123", want: "abc-123"},
+		{name: "invalid from proxy", incoming: "abc\nforged log line", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				r.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			if got == "" || rec.Header().Get(RequestIDHeader) != got {
+				t.Errorf("RequestID() = %q, response header %q, want the same non-empty ID", got, rec.Header().Get(RequestIDHeader))
+			}
+			if tt.want != "" && got != tt.want {
+				t.Errorf("RequestID() = %q, want %q", got, tt.want)
+			}
+			if tt.incoming != "" && tt.want == "" && got == tt.incoming {
+				t.Errorf("RequestID() should not reuse the invalid ID %q", tt.incoming)
+			}
+		})
+	}
+}
+
+func TestRecover(t *testing.T) {
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), RequestID, Recover(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "error page "+RequestIDFromContext(r.Context()), http.StatusInternalServerError)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Recover() status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if want := "error page " + rec.Header().Get(RequestIDHeader); !strings.HasPrefix(rec.Body.String(), want) {
+		t.Errorf("Recover() body = %q, want the error page with the request ID", rec.Body.String())
+	}
+}
+
+func TestLogger_Flush(t *testing.T) {
+	h := Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush() error = %v, want the logger to support flushing", err)
+		}
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover turns panics of handlers into a 500 response rendered by
+// errorPage, logging the panic with the ID of the request
+func Recover(errorPage http.HandlerFunc) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					// Let the server abort the response
+					panic(v)
+				}
+
+				Logf(r, "panic serving %s %s: %v\n%s", r.Method, r.URL.Path, v, debug.Stack())
+				errorPage(w, r)
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -3,8 +3,12 @@ package mock
 import (
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/LaulauChau/sws/internal/models"
@@ -15,11 +19,32 @@ type Server struct {
 	*httptest.Server
 	TokenRequests  int
 	CourseRequests int
+
+	mu      sync.Mutex
+	courses []models.Course
 }
 
 // NewServer creates and returns a new mock server
 func NewServer() *Server {
-	s := &Server{}
+	now := time.Now()
+	s := &Server{
+		courses: []models.Course{
+			{
+				ID:    137393,
+				Name:  "Innover et entreprendre [XDEV003-CTD / 2425S10-PAR1]",
+				Date:  now.Format("2006-01-02"),
+				Start: "08:00:00+00:00",
+				End:   "12:00:00+00:00",
+			},
+			{
+				ID:    137227,
+				Name:  "Innover et entreprendre [XDEV003-CTD / 2425S10-PAR1]",
+				Date:  now.AddDate(0, 0, 1).Format("2006-01-02"),
+				Start: "13:00:00+00:00",
+				End:   "16:30:00+00:00",
+			},
+		},
+	}
 
 	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Add CORS headers for browser compatibility
@@ -96,30 +121,55 @@ func (s *Server) handleCoursesRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	now := time.Now()
-	courses := []models.Course{
-		{
-			ID:    137393,
-			Name:  "Innover et entreprendre [XDEV003-CTD / 2425S10-PAR1]",
-			Date:  now.Format("2006-01-02"),
-			Start: "08:00:00+00:00",
-			End:   "12:00:00+00:00",
-		},
-		{
-			ID:    137227,
-			Name:  "Innover et entreprendre [XDEV003-CTD / 2425S10-PAR1]",
-			Date:  now.AddDate(0, 0, 1).Format("2006-01-02"),
-			Start: "13:00:00+00:00",
-			End:   "16:30:00+00:00",
-		},
+	page, err := paginate(s.Courses(), r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	if err := json.NewEncoder(w).Encode(courses); err != nil {
+	if err := json.NewEncoder(w).Encode(page); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
 }
 
+// paginate returns the courses selected by the limit and offset query
+// parameters, every course when they are absent
+func paginate(courses []models.Course, query url.Values) ([]models.Course, error) {
+	offset, limit := 0, len(courses)
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid offset %q", v)
+		}
+		offset = min(n, len(courses))
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid limit %q", v)
+		}
+		limit = n
+	}
+	return courses[offset:min(offset+limit, len(courses))], nil
+}
+
+// Courses returns a copy of the courses currently served
+func (s *Server) Courses() []models.Course {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]models.Course(nil), s.courses...)
+}
+
+// SetCourses replaces the courses served, to simulate an evolving schedule
+func (s *Server) SetCourses(courses []models.Course) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.courses = append([]models.Course(nil), courses...)
+}
+
 // GetBaseURL returns the base URL of the mock server
 func (s *Server) GetBaseURL() string {
 	return s.URL
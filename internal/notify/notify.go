@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/config"
+)
+
+// Message is a notification delivered to the user
+type Message struct {
+	Title string `json:"title"`
+	// Body is the plain text content
+	Body string `json:"body"`
+	// HTML is an optional rich version of the body, used where supported
+	HTML string `json:"-"`
+	// Data carries structured details for machine consumers such as webhooks
+	Data any `json:"data,omitempty"`
+}
+
+// Notifier delivers messages to a target
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Multi delivers messages to several notifiers, attempting all of them
+type Multi []Notifier
+
+func (m Multi) Notify(ctx context.Context, msg Message) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Target is a notifier along with the name deliveries to it are tracked by
+type Target struct {
+	Name string
+	Notifier
+}
+
+// Targets returns the notifiers n delivers to, so that a failing one can be
+// retried alone. Webhooks, emails and ntfy are named after their kind, the
+// other notifiers of a Multi by their position, and a lone one has no name
+func Targets(n Notifier) []Target {
+	m, ok := n.(Multi)
+	if !ok {
+		return []Target{{Name: targetName(n), Notifier: n}}
+	}
+
+	targets := make([]Target, 0, len(m))
+	for i, n := range m {
+		name := targetName(n)
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		targets = append(targets, Target{Name: name, Notifier: n})
+	}
+	return targets
+}
+
+func targetName(n Notifier) string {
+	switch n.(type) {
+	case *Webhook:
+		return "webhook"
+	case *SMTP:
+		return "smtp"
+	case *Ntfy:
+		return "ntfy"
+	default:
+		return ""
+	}
+}
+
+// FromConfig builds a notifier delivering to every configured target, and
+// returns nil when none is configured
+func FromConfig(cfg config.Notifiers) Notifier {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	var m Multi
+	if cfg.WebhookURL != "" {
+		m = append(m, &Webhook{URL: cfg.WebhookURL, HTTPClient: httpClient})
+	}
+	if cfg.SMTPAddr != "" {
+		m = append(m, &SMTP{
+			Addr:     cfg.SMTPAddr,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+			To:       cfg.SMTPTo,
+		})
+	}
+	if cfg.NtfyURL != "" {
+		m = append(m, &Ntfy{URL: cfg.NtfyURL, Token: cfg.NtfyToken, HTTPClient: httpClient})
+	}
+
+	switch len(m) {
+	case 0:
+		return nil
+	case 1:
+		return m[0]
+	default:
+		return m
+	}
+}
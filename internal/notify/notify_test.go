@@ -0,0 +1,244 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/LaulauChau/sws/internal/config"
+)
+
+var testMessage = Message{
+	Title: "Cours dans 15 min : Innover et entreprendre",
+	Body:  "Innover et entreprendre\nCode : 09866",
+	Data:  map[string]int{"id": 137393},
+}
+
+func TestWebhook_Notify(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("invalid JSON payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	n := &Webhook{URL: server.URL, HTTPClient: server.Client()}
+	if err := n.Notify(context.Background(), testMessage); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if got["title"] != testMessage.Title || got["body"] != testMessage.Body {
+		t.Errorf("unexpected payload %v", got)
+	}
+	if data, ok := got["data"].(map[string]any); !ok || data["id"] != float64(137393) {
+		t.Errorf("expected structured data in payload, got %v", got["data"])
+	}
+}
+
+func TestWebhook_NotifyError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	n := &Webhook{URL: server.URL, HTTPClient: server.Client()}
+	if err := n.Notify(context.Background(), testMessage); err == nil {
+		t.Error("expected error when the webhook fails")
+	}
+}
+
+func TestNtfy_Notify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sws-topic" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("missing access token")
+		}
+
+		title, err := new(mime.WordDecoder).DecodeHeader(r.Header.Get("Title"))
+		if err != nil || title != testMessage.Title {
+			t.Errorf("got title %q (%v), want %q", title, err, testMessage.Title)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != testMessage.Body {
+			t.Errorf("got body %q, want %q", body, testMessage.Body)
+		}
+	}))
+	defer server.Close()
+
+	n := &Ntfy{URL: server.URL + "/sws-topic", Token: "secret", HTTPClient: server.Client()}
+	if err := n.Notify(context.Background(), testMessage); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+}
+
+// smtpServer is a minimal SMTP stand-in recording the received message
+type smtpServer struct {
+	listener net.Listener
+	messages chan string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{listener: l, messages: make(chan string, 1)}
+	go s.serve()
+	t.Cleanup(func() { l.Close() })
+	return s
+}
+
+func (s *smtpServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "DATA"):
+			reply("354 go ahead")
+			var sb strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				sb.WriteString(line)
+			}
+			s.messages <- sb.String()
+			reply("250 queued")
+		case strings.HasPrefix(cmd, "QUIT"):
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestSMTP_Notify(t *testing.T) {
+	server := newSMTPServer(t)
+
+	n := &SMTP{
+		Addr: server.listener.Addr().String(),
+		From: "sws@example.com",
+		To:   []string{"student@example.com"},
+	}
+	msg := testMessage
+	msg.HTML = "<p>Code : <b>09866</b></p>"
+	if err := n.Notify(context.Background(), msg); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	got := <-server.messages
+	for _, want := range []string{
+		"To: student@example.com\r\n",
+		"Subject: Cours dans 15 min",
+		"multipart/alternative",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Type: text/html; charset=utf-8",
+		"Code : 09866",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("email is missing %q:\n%s", want, got)
+		}
+	}
+}
+
+type recordingNotifier struct {
+	messages []Message
+	err      error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, msg Message) error {
+	n.messages = append(n.messages, msg)
+	return n.err
+}
+
+func TestMulti_Notify(t *testing.T) {
+	failing := &recordingNotifier{err: errors.New("unreachable")}
+	working := &recordingNotifier{}
+
+	err := Multi{failing, working}.Notify(context.Background(), testMessage)
+	if err == nil {
+		t.Error("expected the failure to be reported")
+	}
+	if len(working.messages) != 1 {
+		t.Error("expected the other notifiers to be attempted after a failure")
+	}
+}
+
+func TestTargets(t *testing.T) {
+	webhook := &Webhook{URL: "http://localhost"}
+	other := &recordingNotifier{}
+
+	tests := []struct {
+		name     string
+		notifier Notifier
+		want     []string
+	}{
+		{name: "single", notifier: webhook, want: []string{"webhook"}},
+		{name: "unknown", notifier: other, want: []string{""}},
+		{name: "multi", notifier: Multi{&SMTP{}, other, &Ntfy{}}, want: []string{"smtp", "1", "ntfy"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, target := range Targets(tt.notifier) {
+				got = append(got, target.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Targets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	if n := FromConfig(config.Notifiers{}); n != nil {
+		t.Errorf("expected no notifier without targets, got %T", n)
+	}
+	if n, ok := FromConfig(config.Notifiers{WebhookURL: "http://localhost"}).(*Webhook); !ok || n.URL != "http://localhost" {
+		t.Errorf("expected a webhook notifier, got %T", n)
+	}
+
+	n := FromConfig(config.Notifiers{
+		WebhookURL: "http://localhost",
+		NtfyURL:    "http://localhost/topic",
+	})
+	if m, ok := n.(Multi); !ok || len(m) != 2 {
+		t.Errorf("expected 2 notifiers, got %T", n)
+	}
+}
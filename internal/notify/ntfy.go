@@ -0,0 +1,34 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Ntfy pushes messages to an ntfy-style topic URL, e.g. https://ntfy.sh/my-topic
+type Ntfy struct {
+	URL string
+	// Token is an optional access token sent as a bearer token
+	Token      string
+	HTTPClient *http.Client
+}
+
+func (n *Ntfy) Notify(ctx context.Context, msg Message) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, strings.NewReader(msg.Body))
+	if err != nil {
+		return fmt.Errorf("failed to create ntfy request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	// Headers must be ASCII, ntfy decodes RFC 2047 encoded words
+	req.Header.Set("Title", mime.QEncoding.Encode("utf-8", msg.Title))
+	req.Header.Set("Tags", "alarm_clock")
+	if n.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+
+	return send(n.HTTPClient, req, "ntfy")
+}
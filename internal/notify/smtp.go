@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTP sends messages by email
+type SMTP struct {
+	// Addr is the host:port of the SMTP server
+	Addr string
+	// Username and Password enable PLAIN authentication when set, which
+	// requires TLS unless the server is on localhost
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (n *SMTP) Notify(ctx context.Context, msg Message) error {
+	host, _, err := net.SplitHostPort(n.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address %q: %v", n.Addr, err)
+	}
+
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, host)
+	}
+
+	body, err := n.buildMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	// net/smtp does not support contexts, so run it in the background and
+	// stop waiting when the context is done
+	errc := make(chan error, 1)
+	go func() {
+		errc <- smtp.SendMail(n.Addr, auth, n.From, n.To, body)
+	}()
+
+	select {
+	case err := <-errc:
+		if err != nil {
+			return fmt.Errorf("failed to send email notification: %v", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to send email notification: %v", ctx.Err())
+	}
+}
+
+// buildMessage renders the message as a MIME email, with an HTML
+// alternative when available
+func (n *SMTP) buildMessage(msg Message) ([]byte, error) {
+	var sb strings.Builder
+	sb.WriteString("From: " + n.From + "\r\n")
+	sb.WriteString("To: " + strings.Join(n.To, ", ") + "\r\n")
+	sb.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Title) + "\r\n")
+	sb.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	sb.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTML == "" {
+		if err := writePart(&sb, "text/plain", msg.Body); err != nil {
+			return nil, err
+		}
+		return []byte(sb.String()), nil
+	}
+
+	boundary := make([]byte, 12)
+	if _, err := rand.Read(boundary); err != nil {
+		return nil, fmt.Errorf("failed to generate MIME boundary: %v", err)
+	}
+	b := "sws-" + hex.EncodeToString(boundary)
+
+	sb.WriteString("Content-Type: multipart/alternative; boundary=\"" + b + "\"\r\n\r\n")
+	sb.WriteString("--" + b + "\r\n")
+	if err := writePart(&sb, "text/plain", msg.Body); err != nil {
+		return nil, err
+	}
+	sb.WriteString("\r\n--" + b + "\r\n")
+	if err := writePart(&sb, "text/html", msg.HTML); err != nil {
+		return nil, err
+	}
+	sb.WriteString("\r\n--" + b + "--\r\n")
+	return []byte(sb.String()), nil
+}
+
+// writePart writes the headers and quoted-printable content of a MIME part
+func writePart(sb *strings.Builder, contentType, content string) error {
+	sb.WriteString("Content-Type: " + contentType + "; charset=utf-8\r\n")
+	sb.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(sb)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(strings.ReplaceAll(content, "\r\n", "\n"), "\n", "\r\n"))); err != nil {
+		return fmt.Errorf("failed to encode email: %v", err)
+	}
+	if err := qp.Close(); err != nil {
+		return fmt.Errorf("failed to encode email: %v", err)
+	}
+	return nil
+}
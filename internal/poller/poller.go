@@ -0,0 +1,114 @@
+package poller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/client"
+	"github.com/LaulauChau/sws/internal/models"
+)
+
+// ChangeFunc is called with the previous and the new course list whenever they differ
+type ChangeFunc func(prev, next []models.Course)
+
+// RefreshFunc is called with the course list after every successful refresh
+type RefreshFunc func(courses []models.Course)
+
+// Poller periodically refreshes the course list in the background
+type Poller struct {
+	client   *client.Client
+	interval time.Duration
+
+	mu         sync.Mutex
+	courses    []models.Course
+	polled     bool
+	handlers   []ChangeFunc
+	refreshers []RefreshFunc
+}
+
+// NewPoller creates a poller refreshing the courses of the given client at every interval
+func NewPoller(c *client.Client, interval time.Duration) *Poller {
+	return &Poller{
+		client:   c,
+		interval: interval,
+	}
+}
+
+// Seed sets the course list that the next refresh is compared against, e.g.
+// the last snapshot persisted before a restart
+func (p *Poller) Seed(courses []models.Course) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.courses, p.polled = courses, true
+}
+
+// OnChange registers a function called whenever a refresh detects a change.
+// The first refresh of an unseeded poller only establishes the baseline.
+func (p *Poller) OnChange(fn ChangeFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.handlers = append(p.handlers, fn)
+}
+
+// OnRefresh registers a function called after every successful refresh
+func (p *Poller) OnRefresh(fn RefreshFunc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.refreshers = append(p.refreshers, fn)
+}
+
+// Poll refreshes the course list once and notifies the handlers if it changed
+func (p *Poller) Poll() error {
+	if err := p.client.GetToken(); err != nil {
+		return fmt.Errorf("failed to get token: %v", err)
+	}
+
+	courses, err := p.client.FetchNextCourses()
+	if err != nil {
+		return fmt.Errorf("failed to get courses: %v", err)
+	}
+
+	p.mu.Lock()
+	prev, polled := p.courses, p.polled
+	p.courses, p.polled = courses, true
+	handlers := slices.Clone(p.handlers)
+	refreshers := slices.Clone(p.refreshers)
+	p.mu.Unlock()
+
+	for _, fn := range refreshers {
+		fn(courses)
+	}
+
+	if !polled || slices.Equal(prev, courses) {
+		return nil
+	}
+
+	for _, fn := range handlers {
+		fn(prev, courses)
+	}
+	return nil
+}
+
+// Run polls at every interval until the context is cancelled
+func (p *Poller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Poll(); err != nil {
+			fmt.Printf("Error refreshing courses: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package poller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/client"
+	"github.com/LaulauChau/sws/internal/config"
+	"github.com/LaulauChau/sws/internal/mock"
+	"github.com/LaulauChau/sws/internal/models"
+)
+
+func TestPoller_Poll(t *testing.T) {
+	server := mock.NewServer()
+	defer server.Close()
+
+	client.SetBaseURLs(server.URL+"/api/portal/authentication/token",
+		server.URL+"/api/student-app/future-courses",
+		server.URL+"/api/trainer-app/current-courses")
+
+	p := NewPoller(client.NewClient(config.NewTestConfig(), nil), time.Hour)
+
+	var calls, refreshes int
+	var got []models.Course
+	p.OnChange(func(prev, next []models.Course) {
+		calls++
+		got = next
+	})
+	p.OnRefresh(func(courses []models.Course) {
+		refreshes++
+	})
+
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("expected the first poll to only establish the baseline, got %d calls", calls)
+	}
+
+	// An identical course list must not trigger a notification
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("expected no notification for unchanged courses, got %d calls", calls)
+	}
+
+	server.SetCourses(server.Courses()[:1])
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 change notification, got %d calls", calls)
+	}
+	if len(got) != 1 {
+		t.Errorf("expected 1 course, got %d", len(got))
+	}
+
+	if refreshes != 3 {
+		t.Errorf("expected a refresh notification for every poll, got %d", refreshes)
+	}
+	if server.CourseRequests != 3 {
+		t.Errorf("expected every poll to bypass the cache, got %d course requests", server.CourseRequests)
+	}
+}
+
+func TestPoller_Seed(t *testing.T) {
+	server := mock.NewServer()
+	defer server.Close()
+
+	client.SetBaseURLs(server.URL+"/api/portal/authentication/token",
+		server.URL+"/api/student-app/future-courses",
+		server.URL+"/api/trainer-app/current-courses")
+
+	p := NewPoller(client.NewClient(config.NewTestConfig(), nil), time.Hour)
+	p.Seed(server.Courses()[1:])
+
+	var prev []models.Course
+	p.OnChange(func(before, next []models.Course) {
+		prev = before
+	})
+
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+	if len(prev) != 1 {
+		t.Errorf("expected the first poll to be compared against the seed, got %v", prev)
+	}
+}
+
+func TestPoller_PollError(t *testing.T) {
+	p := NewPoller(client.NewClient(config.Config{}, nil), time.Hour)
+	p.OnChange(func(prev, next []models.Course) {
+		t.Error("handler should not be called when polling fails")
+	})
+
+	if err := p.Poll(); err == nil {
+		t.Error("expected error with empty credentials")
+	}
+}
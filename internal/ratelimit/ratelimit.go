@@ -0,0 +1,213 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/config"
+	"golang.org/x/time/rate"
+)
+
+// Error is returned when a request is rejected until RetryAfter has passed
+type Error struct {
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rate limit exceeded, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// RetryAfter returns how long to wait before retrying a request rejected with
+// an Error, and whether err is one
+func RetryAfter(err error) (time.Duration, bool) {
+	var limitErr *Error
+	if errors.As(err, &limitErr) {
+		return limitErr.RetryAfter, true
+	}
+	return 0, false
+}
+
+// SetRetryAfter sets the Retry-After header, in whole seconds
+func SetRetryAfter(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(d.Seconds())))))
+}
+
+// Bucket converts a configured rate to the parameters of a token bucket, an
+// unlimited one when the rate is disabled
+func Bucket(r config.Rate) (rate.Limit, int) {
+	if !r.Enabled() {
+		return rate.Inf, 0
+	}
+	burst := r.Burst
+	if burst <= 0 {
+		burst = r.Requests
+	}
+	return rate.Every(r.Per / time.Duration(r.Requests)), burst
+}
+
+// NewLimiter creates a token bucket limiting requests to the configured rate
+func NewLimiter(r config.Rate) *rate.Limiter {
+	return rate.NewLimiter(Bucket(r))
+}
+
+// SetRate changes the rate of a bucket, e.g. after a reload
+func SetRate(l *rate.Limiter, r config.Rate, now time.Time) {
+	limit, burst := Bucket(r)
+	l.SetLimitAt(now, limit)
+	l.SetBurstAt(now, burst)
+}
+
+// Reserve takes a token from the bucket, or returns an Error with the time
+// until one is available without taking it
+func Reserve(l *rate.Limiter, now time.Time) error {
+	return ReserveAll(now, l)
+}
+
+// ReserveAll takes a token from every bucket, nil ones being skipped, or
+// from none of them when one is empty
+func ReserveAll(now time.Time, limiters ...*rate.Limiter) error {
+	var taken []*rate.Reservation
+	for _, l := range limiters {
+		if l == nil {
+			continue
+		}
+		res := l.ReserveN(now, 1)
+		var err error
+		if !res.OK() {
+			err = &Error{RetryAfter: time.Minute}
+		} else if d := res.DelayFrom(now); d > 0 {
+			res.CancelAt(now)
+			err = &Error{RetryAfter: d}
+		}
+		if err != nil {
+			for _, t := range taken {
+				t.CancelAt(now)
+			}
+			return err
+		}
+		taken = append(taken, res)
+	}
+	return nil
+}
+
+// Delay takes a token from every bucket, nil ones being skipped, even when
+// one is empty, and returns how long to wait before using them along with a
+// function giving them back. It fails when a bucket never grants a token
+func Delay(now time.Time, limiters ...*rate.Limiter) (time.Duration, func(), error) {
+	var taken []*rate.Reservation
+	cancel := func() {
+		for _, t := range taken {
+			t.CancelAt(now)
+		}
+	}
+	var delay time.Duration
+	for _, l := range limiters {
+		if l == nil {
+			continue
+		}
+		res := l.ReserveN(now, 1)
+		if !res.OK() {
+			cancel()
+			return 0, nil, &Error{RetryAfter: time.Minute}
+		}
+		taken = append(taken, res)
+		delay = max(delay, res.DelayFrom(now))
+	}
+	return delay, cancel, nil
+}
+
+// Keyed limits requests per key, e.g. per client IP, with one token bucket
+// each
+type Keyed struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	limiter *rate.Limiter
+	rate    config.Rate
+}
+
+// NewKeyed creates a limiter without any bucket
+func NewKeyed() *Keyed {
+	return &Keyed{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of the key, limited to r, returning an
+// Error when it is empty
+func (k *Keyed) Allow(key string, r config.Rate) error {
+	if !r.Enabled() {
+		return nil
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now()
+	b, ok := k.buckets[key]
+	if !ok {
+		b = &bucket{limiter: NewLimiter(r), rate: r}
+		k.buckets[key] = b
+	} else if b.rate != r {
+		// The configuration was reloaded
+		SetRate(b.limiter, r, now)
+		b.rate = r
+	}
+	return Reserve(b.limiter, now)
+}
+
+// Evict forgets the buckets that are full again, which behave like new ones
+func (k *Keyed) Evict() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now()
+	for key, b := range k.buckets {
+		if b.limiter.TokensAt(now) >= float64(b.limiter.Burst()) {
+			delete(k.buckets, key)
+		}
+	}
+}
+
+// Len returns the number of buckets
+func (k *Keyed) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return len(k.buckets)
+}
+
+// Run evicts the idle buckets at every interval until the context is cancelled
+func (k *Keyed) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			k.Evict()
+		}
+	}
+}
+
+// ClientIP returns the IP address of the client of a request
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
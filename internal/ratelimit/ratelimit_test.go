@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/config"
+	"golang.org/x/time/rate"
+)
+
+func TestKeyed_Allow(t *testing.T) {
+	k := NewKeyed()
+	now := time.Date(2025, 2, 10, 8, 0, 0, 0, time.UTC)
+	k.now = func() time.Time { return now }
+	r := config.Rate{Requests: 6, Per: time.Minute, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		if err := k.Allow("a", r); err != nil {
+			t.Fatalf("Allow() #%d error = %v, want the burst to be allowed", i, err)
+		}
+	}
+	err := k.Allow("a", r)
+	retryAfter, ok := RetryAfter(err)
+	if !ok || retryAfter != 10*time.Second {
+		t.Errorf("Allow() error = %v, want to retry in 10s", err)
+	}
+	if err := k.Allow("b", r); err != nil {
+		t.Errorf("Allow() error = %v, want keys to be limited separately", err)
+	}
+
+	now = now.Add(10 * time.Second)
+	if err := k.Allow("a", r); err != nil {
+		t.Errorf("Allow() error = %v, want a token after 10s", err)
+	}
+
+	if err := k.Allow("a", config.Rate{}); err != nil {
+		t.Errorf("Allow() error = %v, want a disabled rate to allow everything", err)
+	}
+
+	// A reloaded rate applies to existing buckets
+	if err := k.Allow("a", config.Rate{Requests: 1, Per: time.Hour, Burst: 1}); err == nil {
+		t.Error("Allow() should apply a changed rate")
+	}
+}
+
+func TestKeyed_Evict(t *testing.T) {
+	k := NewKeyed()
+	now := time.Date(2025, 2, 10, 8, 0, 0, 0, time.UTC)
+	k.now = func() time.Time { return now }
+	r := config.Rate{Requests: 1, Per: time.Minute, Burst: 1}
+
+	k.Allow("a", r)
+	now = now.Add(30 * time.Second)
+	k.Allow("b", r)
+	now = now.Add(40 * time.Second)
+
+	k.Evict()
+	if got := k.Len(); got != 1 {
+		t.Errorf("Len() = %d after Evict(), want only the bucket still refilling", got)
+	}
+}
+
+func TestReserveAll(t *testing.T) {
+	now := time.Date(2025, 2, 10, 8, 0, 0, 0, time.UTC)
+	shared := NewLimiter(config.Rate{Requests: 2, Per: time.Minute, Burst: 2})
+	profile := NewLimiter(config.Rate{Requests: 1, Per: time.Minute, Burst: 1})
+
+	if err := ReserveAll(now, profile, nil, shared); err != nil {
+		t.Fatalf("ReserveAll() error = %v, want a token from both buckets", err)
+	}
+	// The token taken from the shared bucket is given back when the profile
+	// one is empty
+	if err := ReserveAll(now, shared, profile); err == nil {
+		t.Fatal("ReserveAll() should fail when a bucket is empty")
+	}
+	if err := Reserve(shared, now); err != nil {
+		t.Errorf("Reserve() error = %v, want the token left in the shared bucket", err)
+	}
+	if err := Reserve(shared, now); err == nil {
+		t.Error("Reserve() should fail once the shared bucket is empty")
+	}
+}
+
+func TestDelay(t *testing.T) {
+	now := time.Date(2025, 2, 10, 8, 0, 0, 0, time.UTC)
+	shared := NewLimiter(config.Rate{Requests: 2, Per: time.Minute, Burst: 2})
+	profile := NewLimiter(config.Rate{Requests: 1, Per: time.Minute, Burst: 1})
+
+	if d, _, err := Delay(now, profile, nil, shared); d != 0 || err != nil {
+		t.Fatalf("Delay() = %v, %v, want no delay", d, err)
+	}
+	// The profile bucket refills a token a minute
+	d, cancel, err := Delay(now, shared, profile)
+	if d != time.Minute || err != nil {
+		t.Fatalf("Delay() = %v, %v, want %v", d, err, time.Minute)
+	}
+	cancel()
+	if err := Reserve(shared, now); err != nil {
+		t.Errorf("Reserve() error = %v, want the token given back to the shared bucket", err)
+	}
+
+	if _, _, err := Delay(now, rate.NewLimiter(rate.Every(time.Minute), 0)); err == nil {
+		t.Error("Delay() should fail for a bucket granting no token")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	err := fmt.Errorf("failed to get token: %w", &Error{RetryAfter: 1500 * time.Millisecond})
+	d, ok := RetryAfter(err)
+	if !ok || d != 1500*time.Millisecond {
+		t.Errorf("RetryAfter() = %v, %v, want 1.5s, true", d, ok)
+	}
+	if _, ok := RetryAfter(errors.New("other")); ok {
+		t.Error("RetryAfter() should not match other errors")
+	}
+
+	rec := httptest.NewRecorder()
+	SetRetryAfter(rec, d)
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+}
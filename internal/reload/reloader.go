@@ -0,0 +1,161 @@
+package reload
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/config"
+)
+
+// LoadFunc loads and validates the configuration
+type LoadFunc func() (config.Config, error)
+
+// ApplyFunc is called with the previous and the new configuration after a
+// successful reload
+type ApplyFunc func(old, new config.Config)
+
+// Reloader reloads the configuration at runtime, keeping the current one
+// when the new one is invalid
+type Reloader struct {
+	load LoadFunc
+	// reloading serializes reloads so that appliers see consistent transitions
+	reloading sync.Mutex
+
+	mu       sync.Mutex
+	current  config.Config
+	appliers []ApplyFunc
+	// err is the error of the last reload
+	err error
+}
+
+// NewReloader creates a reloader starting from the current configuration
+func NewReloader(current config.Config, load LoadFunc) *Reloader {
+	return &Reloader{
+		load:    load,
+		current: current,
+	}
+}
+
+// OnReload registers a function applying a new configuration
+func (r *Reloader) OnReload(fn ApplyFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.appliers = append(r.appliers, fn)
+}
+
+// Current returns the configuration in use
+func (r *Reloader) Current() config.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
+
+// Err returns the error of the last reload, nil when it succeeded or no
+// reload happened yet
+func (r *Reloader) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// Reload loads the configuration again and applies it when it changed,
+// returning the changed settings. The current configuration is kept when
+// loading fails
+func (r *Reloader) Reload() ([]string, error) {
+	r.reloading.Lock()
+	defer r.reloading.Unlock()
+
+	next, err := r.load()
+	r.mu.Lock()
+	r.err = err
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	prev := r.Current()
+	changes, err := config.Changes(prev, next)
+	if err != nil || len(changes) == 0 {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.current = next
+	appliers := slices.Clone(r.appliers)
+	r.mu.Unlock()
+
+	for _, fn := range appliers {
+		fn(prev, next)
+	}
+	return changes, nil
+}
+
+// Run reloads the configuration on SIGHUP and whenever one of the files
+// changes, checking them at every interval, until the context is cancelled
+func (r *Reloader) Run(ctx context.Context, interval time.Duration, paths ...string) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	states := statFiles(paths)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			r.reloadAndLog("SIGHUP received")
+		case <-ticker.C:
+			if next := statFiles(paths); !slices.Equal(states, next) {
+				states = next
+				r.reloadAndLog("configuration files changed")
+			}
+		}
+	}
+}
+
+// reloadAndLog reloads the configuration and logs the outcome
+func (r *Reloader) reloadAndLog(reason string) {
+	changes, err := r.Reload()
+	switch {
+	case err != nil:
+		fmt.Printf("Reloading configuration (%s) failed, keeping the current one:\n%v\n", reason, err)
+	case len(changes) == 0:
+		fmt.Printf("Configuration reloaded (%s), nothing changed\n", reason)
+	default:
+		fmt.Printf("Configuration reloaded (%s):\n", reason)
+		for _, change := range changes {
+			fmt.Printf("  %s\n", change)
+		}
+	}
+}
+
+// fileState identifies a version of a watched file
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// statFiles returns the state of every file, missing files included so that
+// their creation is noticed
+func statFiles(paths []string) []fileState {
+	states := make([]fileState, len(paths))
+	for i, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			states[i] = fileState{exists: true, size: info.Size(), modTime: info.ModTime()}
+		}
+	}
+	return states
+}
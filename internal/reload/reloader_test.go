@@ -0,0 +1,96 @@
+package reload
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/config"
+)
+
+func TestReloader_Reload(t *testing.T) {
+	current := config.NewTestConfig()
+	next := current
+	var loadErr error
+	r := NewReloader(current, func() (config.Config, error) {
+		return next, loadErr
+	})
+
+	var applied int
+	r.OnReload(func(old, new config.Config) {
+		applied++
+		if old.RefreshInterval != current.RefreshInterval || new.RefreshInterval != time.Minute {
+			t.Errorf("OnReload() got %s -> %s, want %s -> 1m", old.RefreshInterval, new.RefreshInterval, current.RefreshInterval)
+		}
+	})
+
+	// Unchanged configurations are not applied
+	if changes, err := r.Reload(); err != nil || len(changes) != 0 || applied != 0 {
+		t.Errorf("Reload() = %v, %v with %d applies, want no change", changes, err, applied)
+	}
+
+	next.RefreshInterval = time.Minute
+	changes, err := r.Reload()
+	if err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	if len(changes) != 1 || applied != 1 {
+		t.Errorf("Reload() = %v with %d applies, want one change applied once", changes, applied)
+	}
+	if r.Current().RefreshInterval != time.Minute {
+		t.Errorf("Current().RefreshInterval = %s, want 1m", r.Current().RefreshInterval)
+	}
+
+	// Invalid configurations are rejected and the current one is kept
+	next.RefreshInterval = time.Hour
+	loadErr = fmt.Errorf("refresh_interval: invalid")
+	if _, err := r.Reload(); err == nil {
+		t.Error("Reload() should fail when loading fails")
+	}
+	if r.Current().RefreshInterval != time.Minute || applied != 1 {
+		t.Error("Reload() should keep the current configuration when loading fails")
+	}
+	if r.Err() != loadErr {
+		t.Errorf("Err() = %v, want %v", r.Err(), loadErr)
+	}
+
+	next.RefreshInterval, loadErr = time.Minute, nil
+	if _, err := r.Reload(); err != nil || r.Err() != nil {
+		t.Errorf("Err() = %v after a successful reload, want nil", r.Err())
+	}
+}
+
+func TestReloader_RunWatchesFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sws.yaml")
+
+	var loads atomic.Int32
+	r := NewReloader(config.NewTestConfig(), func() (config.Config, error) {
+		loads.Add(1)
+		return config.NewTestConfig(), nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx, 5*time.Millisecond, path)
+
+	time.Sleep(20 * time.Millisecond)
+	if loads.Load() != 0 {
+		t.Fatalf("expected no reload before the file changes, got %d", loads.Load())
+	}
+
+	if err := os.WriteFile(path, []byte("refresh_interval: 1m\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for loads.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if loads.Load() == 0 {
+		t.Error("expected a reload after the file was created")
+	}
+}
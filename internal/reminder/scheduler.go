@@ -0,0 +1,148 @@
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/export"
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/notify"
+	"github.com/LaulauChau/sws/internal/service"
+)
+
+// CourseSource provides the cached course list
+type CourseSource interface {
+	CachedNextCourses() ([]models.Course, bool)
+}
+
+// Ledger remembers which reminders were sent, so that each course triggers
+// at most one reminder per offset and notification target, even across
+// restarts
+type Ledger interface {
+	ClaimReminder(ctx context.Context, courseID int, offset time.Duration, target string, at time.Time) (bool, error)
+	ReleaseReminder(ctx context.Context, courseID int, offset time.Duration, target string) error
+}
+
+// Scheduler sends reminders a configured time before each course starts
+type Scheduler struct {
+	source  CourseSource
+	targets []notify.Target
+	ledger  Ledger
+	offsets []time.Duration
+	now     func() time.Time
+}
+
+// NewScheduler creates a scheduler sending reminders for each offset before a course
+func NewScheduler(source CourseSource, notifier notify.Notifier, ledger Ledger, offsets []time.Duration) *Scheduler {
+	return &Scheduler{
+		source:  source,
+		targets: notify.Targets(notifier),
+		ledger:  ledger,
+		offsets: offsets,
+		now:     time.Now,
+	}
+}
+
+// Check sends the reminders that are due. A reminder is due once its offset
+// before the course is reached, until the course starts.
+func (s *Scheduler) Check(ctx context.Context) error {
+	courses, ok := s.source.CachedNextCourses()
+	if !ok {
+		return nil
+	}
+
+	now := s.now()
+	var errs []string
+	for _, course := range courses {
+		start := service.CourseStart(course)
+		if course.ID == 0 || start.IsZero() || !now.Before(start) {
+			continue
+		}
+
+		for _, offset := range s.offsets {
+			if now.Before(start.Add(-offset)) {
+				continue
+			}
+			if err := s.remind(ctx, course, offset, start.Sub(now), now); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to send reminders: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// remind sends the reminder of a course to every target it wasn't sent to,
+// a failing target being retried by the next check
+func (s *Scheduler) remind(ctx context.Context, course models.Course, offset, remaining time.Duration, now time.Time) error {
+	msg := NewMessage(course, remaining)
+
+	var errs []string
+	for _, target := range s.targets {
+		if err := s.remindTarget(ctx, course.ID, offset, target, msg, now); err != nil {
+			if target.Name != "" {
+				err = fmt.Errorf("%s: %v", target.Name, err)
+			}
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("course %d: %s", course.ID, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func (s *Scheduler) remindTarget(ctx context.Context, courseID int, offset time.Duration, target notify.Target, msg notify.Message, now time.Time) error {
+	claimed, err := s.ledger.ClaimReminder(ctx, courseID, offset, target.Name, now)
+	if err != nil || !claimed {
+		return err
+	}
+
+	if err := target.Notify(ctx, msg); err != nil {
+		if rerr := s.ledger.ReleaseReminder(ctx, courseID, offset, target.Name); rerr != nil {
+			return fmt.Errorf("%v (and %v)", err, rerr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Run checks for due reminders at every interval until the context is cancelled
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Check(ctx); err != nil {
+			fmt.Printf("Error sending reminders: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// NewMessage builds the reminder for a course starting in the given duration
+func NewMessage(course models.Course, remaining time.Duration) notify.Message {
+	r := export.NewRecord(course)
+
+	lines := []string{course.Name, fmt.Sprintf("%s de %s à %s", r.Date, r.Start, r.End)}
+	if r.Code != "" {
+		lines = append(lines, "Code : "+r.Code)
+	}
+
+	return notify.Message{
+		Title: fmt.Sprintf("Cours dans %d min : %s", int(remaining.Round(time.Minute).Minutes()), course.Name),
+		Body:  strings.Join(lines, "\n"),
+		Data:  r,
+	}
+}
@@ -0,0 +1,174 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/notify"
+)
+
+type staticSource []models.Course
+
+func (s staticSource) CachedNextCourses() ([]models.Course, bool) {
+	return s, true
+}
+
+type reminderKey struct {
+	courseID int
+	offset   time.Duration
+	target   string
+}
+
+type memoryLedger map[reminderKey]bool
+
+func (l memoryLedger) ClaimReminder(ctx context.Context, courseID int, offset time.Duration, target string, at time.Time) (bool, error) {
+	key := reminderKey{courseID, offset, target}
+	if l[key] {
+		return false, nil
+	}
+	l[key] = true
+	return true, nil
+}
+
+func (l memoryLedger) ReleaseReminder(ctx context.Context, courseID int, offset time.Duration, target string) error {
+	delete(l, reminderKey{courseID, offset, target})
+	return nil
+}
+
+type recordingNotifier struct {
+	messages []notify.Message
+	err      error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, msg notify.Message) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.messages = append(n.messages, msg)
+	return nil
+}
+
+var course = models.Course{
+	ID:    137393,
+	Name:  "Innover et entreprendre",
+	Date:  "2025-02-10",
+	Start: "08:00:00+00:00",
+	End:   "12:00:00+00:00",
+}
+
+func newTestScheduler(n notify.Notifier, ledger Ledger, now time.Time) *Scheduler {
+	s := NewScheduler(staticSource{course}, n, ledger, []time.Duration{15 * time.Minute, time.Hour})
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestScheduler_Check(t *testing.T) {
+	start := time.Date(2025, 2, 10, 8, 0, 0, 0, time.UTC)
+	ledger := memoryLedger{}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want int
+	}{
+		{name: "too early", now: start.Add(-2 * time.Hour), want: 0},
+		{name: "one hour before", now: start.Add(-59 * time.Minute), want: 1},
+		{name: "same offset is not repeated", now: start.Add(-30 * time.Minute), want: 0},
+		{name: "fifteen minutes before", now: start.Add(-10 * time.Minute), want: 1},
+		{name: "after the start", now: start.Add(time.Minute), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &recordingNotifier{}
+			if err := newTestScheduler(n, ledger, tt.now).Check(context.Background()); err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if len(n.messages) != tt.want {
+				t.Errorf("got %d reminders, want %d", len(n.messages), tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduler_CheckRestart(t *testing.T) {
+	start := time.Date(2025, 2, 10, 8, 0, 0, 0, time.UTC)
+	ledger := memoryLedger{}
+
+	// Late start: both offsets are already due, each fires once
+	n := &recordingNotifier{}
+	if err := newTestScheduler(n, ledger, start.Add(-5*time.Minute)).Check(context.Background()); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(n.messages) != 2 {
+		t.Fatalf("got %d reminders, want 2", len(n.messages))
+	}
+
+	// A new scheduler sharing the ledger, as after a restart, sends nothing
+	n = &recordingNotifier{}
+	if err := newTestScheduler(n, ledger, start.Add(-4*time.Minute)).Check(context.Background()); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(n.messages) != 0 {
+		t.Errorf("got %d reminders after restart, want 0", len(n.messages))
+	}
+}
+
+func TestScheduler_CheckRetry(t *testing.T) {
+	start := time.Date(2025, 2, 10, 8, 0, 0, 0, time.UTC)
+	ledger := memoryLedger{}
+
+	failing := &recordingNotifier{err: errors.New("unreachable")}
+	if err := newTestScheduler(failing, ledger, start.Add(-10*time.Minute)).Check(context.Background()); err == nil {
+		t.Fatal("expected delivery errors to be reported")
+	}
+
+	n := &recordingNotifier{}
+	if err := newTestScheduler(n, ledger, start.Add(-9*time.Minute)).Check(context.Background()); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(n.messages) != 2 {
+		t.Errorf("expected failed reminders to be retried, got %d", len(n.messages))
+	}
+}
+
+func TestScheduler_CheckRetryFailingTarget(t *testing.T) {
+	start := time.Date(2025, 2, 10, 8, 0, 0, 0, time.UTC)
+	ledger := memoryLedger{}
+	failing := &recordingNotifier{err: errors.New("unreachable")}
+	working := &recordingNotifier{}
+	n := notify.Multi{failing, working}
+
+	if err := newTestScheduler(n, ledger, start.Add(-10*time.Minute)).Check(context.Background()); err == nil {
+		t.Fatal("expected delivery errors to be reported")
+	}
+	if len(working.messages) != 2 {
+		t.Fatalf("got %d reminders on the working target, want 2", len(working.messages))
+	}
+
+	// Only the failed target is retried
+	failing.err = nil
+	if err := newTestScheduler(n, ledger, start.Add(-9*time.Minute)).Check(context.Background()); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(failing.messages) != 2 || len(working.messages) != 2 {
+		t.Errorf("got %d and %d reminders, want the 2 reminders on each target", len(failing.messages), len(working.messages))
+	}
+}
+
+func TestNewMessage(t *testing.T) {
+	msg := NewMessage(course, 15*time.Minute)
+
+	if msg.Title != "Cours dans 15 min : Innover et entreprendre" {
+		t.Errorf("unexpected title %q", msg.Title)
+	}
+	for _, want := range []string{"10/02/2025 de 09:00 à 13:00", "Code : 09866"} {
+		if !strings.Contains(msg.Body, want) {
+			t.Errorf("body %q is missing %q", msg.Body, want)
+		}
+	}
+}
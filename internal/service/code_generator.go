@@ -13,6 +13,25 @@ const maxModulo = 7872
 
 var arrayCharsNumeric = []string{"8", "3", "4", "9", "1", "6", "2", "5", "7"}
 
+// Reasons returned by CodeError
+const (
+	CodeErrorMissingID    = "missing_id"
+	CodeErrorInvalidStart = "invalid_start"
+)
+
+// CodeError returns why the code of a course cannot be generated, empty when
+// it can
+func CodeError(course models.Course) string {
+	switch {
+	case course.ID == 0:
+		return CodeErrorMissingID
+	case CourseStart(course).IsZero():
+		return CodeErrorInvalidStart
+	default:
+		return ""
+	}
+}
+
 func isTesting() bool {
 	return flag.Lookup("test.v") != nil
 }
@@ -38,16 +57,9 @@ func fillWithZero(s string) string {
 }
 
 func getCourseStartUTC(course models.Course) time.Time {
-	if course.Date == "" || course.Start == "" {
-		return time.Time{}
-	}
-
-	start := strings.Split(course.Start, "+")[0]
-
-	t, err := time.Parse("2006-01-02T15:04:05", course.Date+"T"+start)
+	t, err := parseCourseTime(course.Date, course.Start)
 	if err != nil && !isTesting() {
 		fmt.Printf("Error parsing time: %v\n", err)
-		return time.Time{}
 	}
 	return t
 }
@@ -66,12 +78,7 @@ func GenerateFixedCode(course models.Course) (string, string, string, string) {
 	o := encode(arrayCharsNumeric, r%maxModulo)
 	fixedCode := fillWithZero(o)
 
-	paris, err := time.LoadLocation("Europe/Paris")
-	if err != nil && !isTesting() {
-		fmt.Printf("Error loading timezone: %v\n", err)
-		return "", "", "", ""
-	}
-	startTimeParis := startTime.In(paris)
+	startTimeParis := startTime.In(ParisLocation())
 
 	return course.Name,
 		startTimeParis.Format("02/01/2006"),
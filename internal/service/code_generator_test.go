@@ -130,6 +130,39 @@ func TestGenerateFixedCode(t *testing.T) {
 			wantCode:  "09866",
 			wantEmpty: false,
 		},
+		{
+			name: "offset with colon",
+			course: models.Course{
+				ID:    137393,
+				Name:  "Test Course",
+				Date:  "2025-02-10",
+				Start: "09:00:00+01:00",
+			},
+			wantCode:  "03826",
+			wantEmpty: false,
+		},
+		{
+			name: "offset without colon",
+			course: models.Course{
+				ID:    137393,
+				Name:  "Test Course",
+				Date:  "2025-02-10",
+				Start: "09:00:00+0100",
+			},
+			wantCode:  "03826",
+			wantEmpty: false,
+		},
+		{
+			name: "offset hours only",
+			course: models.Course{
+				ID:    137393,
+				Name:  "Test Course",
+				Date:  "2025-02-10",
+				Start: "09:00:00+01",
+			},
+			wantCode:  "03826",
+			wantEmpty: false,
+		},
 		{
 			name: "zero ID",
 			course: models.Course{
@@ -165,3 +198,23 @@ func TestGenerateFixedCode(t *testing.T) {
 		})
 	}
 }
+
+func TestCodeError(t *testing.T) {
+	tests := []struct {
+		name   string
+		course models.Course
+		want   string
+	}{
+		{name: "valid", course: models.Course{ID: 1, Date: "2025-02-10", Start: "08:00:00+00:00"}, want: ""},
+		{name: "missing ID", course: models.Course{Name: "No ID", Date: "2025-02-10", Start: "08:00:00+00:00"}, want: CodeErrorMissingID},
+		{name: "missing start", course: models.Course{ID: 1, Date: "2025-02-10"}, want: CodeErrorInvalidStart},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CodeError(tt.course); got != tt.want {
+				t.Errorf("CodeError() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
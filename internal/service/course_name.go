@@ -0,0 +1,105 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Format is the teaching format of a course
+type Format string
+
+const (
+	// FormatCM is a lecture, cours magistral
+	FormatCM Format = "CM"
+	// FormatTD is a tutorial, travaux dirigés
+	FormatTD Format = "TD"
+	// FormatCTD mixes lecture and tutorial
+	FormatCTD Format = "CTD"
+	// FormatTP is a lab, travaux pratiques
+	FormatTP Format = "TP"
+)
+
+// Label returns the French name of the format, or the format itself when unknown
+func (f Format) Label() string {
+	switch f {
+	case FormatCM:
+		return "Cours magistral"
+	case FormatTD:
+		return "Travaux dirigés"
+	case FormatCTD:
+		return "Cours et travaux dirigés"
+	case FormatTP:
+		return "Travaux pratiques"
+	default:
+		return string(f)
+	}
+}
+
+// ParseFormat returns the known format written s, case-insensitively
+func ParseFormat(s string) (Format, bool) {
+	switch f := Format(strings.ToUpper(s)); f {
+	case FormatCM, FormatTD, FormatCTD, FormatTP:
+		return f, true
+	default:
+		return "", false
+	}
+}
+
+// CourseName is a course name split into the fields Sowesign packs into it,
+// e.g. "Innover et entreprendre [XDEV003-CTD / 2425S10-PAR1]" for module
+// XDEV003 taught as CTD in period 2425S10 to group PAR1 of campus PAR.
+// Fields missing from the name are empty
+type CourseName struct {
+	Title  string
+	Module string
+	Format Format
+	Period string
+	Group  string
+	// Campus is the letters starting the group
+	Campus string
+}
+
+// ParseCourseName splits a course name. Names without brackets at the end
+// are only a title
+func ParseCourseName(name string) CourseName {
+	name = strings.TrimSpace(name)
+	open := strings.LastIndex(name, "[")
+	inside, ok := strings.CutSuffix(name[max(open, 0):], "]")
+	if open < 0 || !ok {
+		return CourseName{Title: name}
+	}
+
+	n := CourseName{Title: strings.TrimSpace(name[:open])}
+	module, class, _ := strings.Cut(inside[1:], "/")
+
+	n.Module = strings.TrimSpace(module)
+	if i := strings.LastIndex(n.Module, "-"); i >= 0 {
+		if format, ok := ParseFormat(n.Module[i+1:]); ok {
+			n.Module, n.Format = strings.TrimSpace(n.Module[:i]), format
+		}
+	}
+
+	class = strings.TrimSpace(class)
+	if period, group, ok := strings.Cut(class, "-"); ok {
+		n.Period, n.Group = strings.TrimSpace(period), strings.TrimSpace(group)
+	} else {
+		n.Group = class
+	}
+	n.Campus = n.Group
+	if i := strings.IndexFunc(n.Group, func(r rune) bool { return !unicode.IsLetter(r) }); i >= 0 {
+		n.Campus = n.Group[:i]
+	}
+	return n
+}
+
+// Code returns the module code as written in the name, with its format,
+// e.g. "XDEV003-CTD"
+func (n CourseName) Code() string {
+	if n.Format == "" {
+		return n.Module
+	}
+	if n.Module == "" {
+		return string(n.Format)
+	}
+	return n.Module + "-" + string(n.Format)
+}
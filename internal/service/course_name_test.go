@@ -0,0 +1,110 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCourseName(t *testing.T) {
+	tests := []struct {
+		name string
+		want CourseName
+	}{
+		{
+			name: "Innover et entreprendre [XDEV003-CTD / 2425S10-PAR1]",
+			want: CourseName{Title: "Innover et entreprendre", Module: "XDEV003", Format: FormatCTD, Period: "2425S10", Group: "PAR1", Campus: "PAR"},
+		},
+		{
+			name: "Gestion de projet [XMAN001-cm/2425S10-LYON-A]",
+			want: CourseName{Title: "Gestion de projet", Module: "XMAN001", Format: FormatCM, Period: "2425S10", Group: "LYON-A", Campus: "LYON"},
+		},
+		{
+			name: "Anglais [LANG01-B2]",
+			want: CourseName{Title: "Anglais", Module: "LANG01-B2"},
+		},
+		{
+			name: "Anglais [LANG01 / PAR]",
+			want: CourseName{Title: "Anglais", Module: "LANG01", Group: "PAR", Campus: "PAR"},
+		},
+		{
+			name: "[TD] Algorithmique [ALGO-TD / 2425S10-PAR2]",
+			want: CourseName{Title: "[TD] Algorithmique", Module: "ALGO", Format: FormatTD, Period: "2425S10", Group: "PAR2", Campus: "PAR"},
+		},
+		{
+			name: "  Réunion de rentrée  ",
+			want: CourseName{Title: "Réunion de rentrée"},
+		},
+		{
+			name: "Anglais [LANG01",
+			want: CourseName{Title: "Anglais [LANG01"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseCourseName(tt.name); got != tt.want {
+				t.Errorf("ParseCourseName() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCourseName_Code(t *testing.T) {
+	tests := []struct {
+		name CourseName
+		want string
+	}{
+		{name: CourseName{Module: "XDEV003", Format: FormatCTD}, want: "XDEV003-CTD"},
+		{name: CourseName{Module: "XDEV003"}, want: "XDEV003"},
+		{name: CourseName{Format: FormatCM}, want: "CM"},
+		{name: CourseName{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.name.Code(); got != tt.want {
+			t.Errorf("Code() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func FuzzParseCourseName(f *testing.F) {
+	for _, name := range []string{
+		"Innover et entreprendre [XDEV003-CTD / 2425S10-PAR1]",
+		"Anglais [LANG01]",
+		"[a] b [c-d / e-f-g]",
+		"x [-CM/-]",
+		"[]",
+		"",
+	} {
+		f.Add(name)
+	}
+
+	f.Fuzz(func(t *testing.T, name string) {
+		n := ParseCourseName(name)
+
+		for field, value := range map[string]string{
+			"Title": n.Title, "Module": n.Module, "Period": n.Period, "Group": n.Group, "Campus": n.Campus,
+		} {
+			if value != strings.TrimSpace(value) {
+				t.Errorf("ParseCourseName(%q).%s = %q, want no surrounding spaces", name, field, value)
+			}
+			if !strings.Contains(name, value) {
+				t.Errorf("ParseCourseName(%q).%s = %q, want a part of the name", name, field, value)
+			}
+		}
+		if strings.Contains(n.Module, "/") || strings.Contains(n.Period, "-") {
+			t.Errorf("ParseCourseName(%q) = %+v, want no separator in the module and period", name, n)
+		}
+		if n.Format != "" {
+			if format, ok := ParseFormat(string(n.Format)); !ok || format != n.Format {
+				t.Errorf("ParseCourseName(%q).Format = %q, want a known format", name, n.Format)
+			}
+		}
+		if !strings.HasPrefix(n.Group, n.Campus) {
+			t.Errorf("ParseCourseName(%q).Campus = %q, want a prefix of the group %q", name, n.Campus, n.Group)
+		}
+		if !strings.HasSuffix(strings.TrimSpace(name), "]") && n != (CourseName{Title: strings.TrimSpace(name)}) {
+			t.Errorf("ParseCourseName(%q) = %+v, want only a title", name, n)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	// Embeds the timezone database so that Europe/Paris is always available
+	_ "time/tzdata"
+
+	"github.com/LaulauChau/sws/internal/models"
+)
+
+var (
+	parisOnce sync.Once
+	paris     *time.Location
+)
+
+// ParisLocation returns the Europe/Paris timezone in which courses take place.
+// It panics if the timezone cannot be loaded, as a fixed offset would shift
+// every course by an hour half of the year
+func ParisLocation() *time.Location {
+	parisOnce.Do(func() {
+		loc, err := time.LoadLocation("Europe/Paris")
+		if err != nil {
+			panic(fmt.Sprintf("failed to load Europe/Paris timezone: %v", err))
+		}
+		paris = loc
+	})
+	return paris
+}
+
+// CourseStart returns the start time of a course, or the zero time if it cannot be parsed
+func CourseStart(course models.Course) time.Time {
+	t, _ := parseCourseTime(course.Date, course.Start)
+	return t
+}
+
+// CourseEnd returns the end time of a course, or the zero time if it cannot be parsed
+func CourseEnd(course models.Course) time.Time {
+	t, _ := parseCourseTime(course.Date, course.End)
+	return t
+}
+
+// parseCourseTime returns the time of a course, the zero time without a date
+// or a clock. The "+offset" suffix of the clock is dropped, as the attendance
+// codes have always been generated from the clock as sent
+func parseCourseTime(date, clock string) (time.Time, error) {
+	if date == "" || clock == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse("2006-01-02T15:04:05", date+"T"+strings.Split(clock, "+")[0])
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t, nil
+}
+
+// CourseStatus describes where a course stands relative to a point in time
+type CourseStatus int
+
+const (
+	StatusUnknown CourseStatus = iota
+	StatusUpcoming
+	StatusInProgress
+	StatusFinished
+)
+
+// GetCourseStatus returns the status of a course at the given time
+func GetCourseStatus(course models.Course, now time.Time) CourseStatus {
+	start, end := CourseStart(course), CourseEnd(course)
+	switch {
+	case start.IsZero():
+		return StatusUnknown
+	case now.Before(start):
+		return StatusUpcoming
+	case end.IsZero() || now.Before(end):
+		return StatusInProgress
+	default:
+		return StatusFinished
+	}
+}
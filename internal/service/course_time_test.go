@@ -0,0 +1,139 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/models"
+)
+
+func TestCourseStartEnd(t *testing.T) {
+	course := models.Course{
+		Date:  "2025-02-10",
+		Start: "08:00:00+00:00",
+		End:   "12:30:00+00:00",
+	}
+
+	wantStart := time.Date(2025, 2, 10, 8, 0, 0, 0, time.UTC)
+	if got := CourseStart(course); !got.Equal(wantStart) {
+		t.Errorf("CourseStart() = %v, want %v", got, wantStart)
+	}
+
+	wantEnd := time.Date(2025, 2, 10, 12, 30, 0, 0, time.UTC)
+	if got := CourseEnd(course); !got.Equal(wantEnd) {
+		t.Errorf("CourseEnd() = %v, want %v", got, wantEnd)
+	}
+
+	if got := CourseEnd(models.Course{Date: "2025-02-10"}); !got.IsZero() {
+		t.Errorf("CourseEnd() = %v, want zero time", got)
+	}
+}
+
+func Test_parseCourseTime(t *testing.T) {
+	tests := []struct {
+		name  string
+		date  string
+		clock string
+		want  time.Time
+	}{
+		{
+			name:  "UTC offset",
+			date:  "2025-02-10",
+			clock: "08:00:00+00:00",
+			want:  time.Date(2025, 2, 10, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "offset dropped",
+			date:  "2025-02-10",
+			clock: "09:00:00+01:00",
+			want:  time.Date(2025, 2, 10, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "no offset",
+			date:  "2025-02-10",
+			clock: "08:00:00",
+			want:  time.Date(2025, 2, 10, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "invalid date format",
+			date:  "invalid",
+			clock: "08:00:00+00:00",
+		},
+		{
+			name:  "empty date",
+			clock: "08:00:00+00:00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := parseCourseTime(tt.date, tt.clock); !got.Equal(tt.want) {
+				t.Errorf("parseCourseTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParisLocation(t *testing.T) {
+	// Paris is UTC+1 in winter and UTC+2 in summer
+	winter := time.Date(2025, 2, 10, 8, 0, 0, 0, time.UTC).In(ParisLocation())
+	summer := time.Date(2025, 7, 10, 8, 0, 0, 0, time.UTC).In(ParisLocation())
+	if winter.Hour() != 9 || summer.Hour() != 10 {
+		t.Errorf("ParisLocation() hours = %d and %d, want 9 and 10", winter.Hour(), summer.Hour())
+	}
+}
+
+func TestGetCourseStatus(t *testing.T) {
+	course := models.Course{
+		Date:  "2025-02-10",
+		Start: "08:00:00+00:00",
+		End:   "12:00:00+00:00",
+	}
+
+	tests := []struct {
+		name   string
+		course models.Course
+		now    time.Time
+		want   CourseStatus
+	}{
+		{
+			name:   "before start",
+			course: course,
+			now:    time.Date(2025, 2, 10, 7, 0, 0, 0, time.UTC),
+			want:   StatusUpcoming,
+		},
+		{
+			name:   "during course",
+			course: course,
+			now:    time.Date(2025, 2, 10, 9, 0, 0, 0, time.UTC),
+			want:   StatusInProgress,
+		},
+		{
+			name:   "after end",
+			course: course,
+			now:    time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC),
+			want:   StatusFinished,
+		},
+		{
+			name:   "invalid date",
+			course: models.Course{Date: "invalid", Start: "08:00:00+00:00"},
+			now:    time.Date(2025, 2, 10, 9, 0, 0, 0, time.UTC),
+			want:   StatusUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetCourseStatus(tt.course, tt.now); got != tt.want {
+				t.Errorf("GetCourseStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatLongDate(t *testing.T) {
+	got := FormatLongDate(time.Date(2025, 2, 10, 9, 0, 0, 0, time.UTC))
+	if got != "lundi 10 février 2025" {
+		t.Errorf("FormatLongDate() = %q, want %q", got, "lundi 10 février 2025")
+	}
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+var (
+	frenchWeekdays = []string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"}
+	frenchMonths   = []string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"}
+)
+
+// FrenchWeekday returns the French name of the day of the week
+func FrenchWeekday(t time.Time) string {
+	return frenchWeekdays[t.Weekday()]
+}
+
+// FrenchMonth returns the French name of the month
+func FrenchMonth(t time.Time) string {
+	return frenchMonths[t.Month()-1]
+}
+
+// FormatLongDate formats a date in French, e.g. "lundi 10 février 2025"
+func FormatLongDate(t time.Time) string {
+	return fmt.Sprintf("%s %d %s %d", FrenchWeekday(t), t.Day(), FrenchMonth(t), t.Year())
+}
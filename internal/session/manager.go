@@ -0,0 +1,150 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/account"
+	"github.com/LaulauChau/sws/internal/config"
+	"github.com/LaulauChau/sws/internal/metrics"
+	"golang.org/x/time/rate"
+)
+
+// Manager keeps an isolated account for every visitor signed in with their
+// own Sowesign credentials. Credentials only live in memory and are
+// forgotten when the session ends or stays idle for too long
+type Manager struct {
+	mu       sync.Mutex
+	base     config.Config
+	sessions map[string]*entry
+	metrics  *metrics.Metrics
+	// upstream is the bucket of the calls to Sowesign shared with the other
+	// accounts, which sign in attempts take from too
+	upstream *rate.Limiter
+	now      func() time.Time
+}
+
+type entry struct {
+	account  *account.Account
+	lastSeen time.Time
+}
+
+// NewManager creates a manager whose accounts use the settings of base, e.g.
+// the cache TTL, with the credentials of each visitor, and upstream, the
+// bucket of the calls to Sowesign of the server
+func NewManager(base config.Config, upstream *rate.Limiter) *Manager {
+	return &Manager{
+		base:     base,
+		sessions: make(map[string]*entry),
+		upstream: upstream,
+		now:      time.Now,
+	}
+}
+
+// SetConfig swaps the settings used by new sessions, e.g. after a reload
+func (m *Manager) SetConfig(base config.Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.base = base
+}
+
+// SetMetrics records the calls to Sowesign of the accounts of new sessions
+func (m *Manager) SetMetrics(metrics *metrics.Metrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.metrics = metrics
+}
+
+// Login validates the credentials against Sowesign and, on success, binds a
+// new account to the session
+func (m *Manager) Login(id, codeEtablissement, identifiant, pin string) (*account.Account, error) {
+	m.mu.Lock()
+	cfg, metrics := m.base, m.metrics
+	m.mu.Unlock()
+
+	cfg.Profile = identifiant
+	cfg.CodeEtablissement = codeEtablissement
+	cfg.Identifiant = identifiant
+	cfg.PIN = pin
+	// Feeds need persistent credentials, visitors have none
+	cfg.FeedToken = ""
+	cfg.Profiles = nil
+
+	acc := account.New(cfg, nil, m.upstream)
+	acc.Client.SetMetrics(metrics)
+	if err := acc.Client.GetToken(); err != nil {
+		return nil, fmt.Errorf("failed to sign in: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[id] = &entry{account: acc, lastSeen: m.now()}
+	return acc, nil
+}
+
+// Get returns the account of a session and marks it as active
+func (m *Manager) Get(id string) (*account.Account, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.sessions[id]
+	if !ok {
+		return nil, false
+	}
+	e.lastSeen = m.now()
+	return e.account, true
+}
+
+// Logout forgets the account of a session
+func (m *Manager) Logout(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, id)
+}
+
+// Len returns the number of active sessions
+func (m *Manager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.sessions)
+}
+
+// Evict forgets the sessions unused for longer than the idle timeout,
+// returning how many were evicted
+func (m *Manager) Evict() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deadline := m.now().Add(-m.base.Auth.IdleTimeout)
+	evicted := 0
+	for id, e := range m.sessions {
+		if e.lastSeen.Before(deadline) {
+			delete(m.sessions, id)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// Run evicts idle sessions at every interval until the context is cancelled
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n := m.Evict(); n > 0 {
+				fmt.Printf("Evicted %d idle sessions\n", n)
+			}
+		}
+	}
+}
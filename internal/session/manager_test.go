@@ -0,0 +1,124 @@
+package session
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/client"
+	"github.com/LaulauChau/sws/internal/config"
+	"github.com/LaulauChau/sws/internal/ratelimit"
+)
+
+// newTestServer accepts the credentials code/alice/1234 only
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	valid := "JBAuth " + base64.StdEncoding.EncodeToString([]byte("code"+"alice"+"1234"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != valid {
+			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(client.AuthResponse{Token: "test-token"})
+	}))
+	t.Cleanup(server.Close)
+
+	client.SetBaseURLs(server.URL, server.URL, server.URL)
+	return server
+}
+
+func TestManager_Login(t *testing.T) {
+	newTestServer(t)
+	m := NewManager(config.NewTestConfig(), nil)
+
+	if _, err := m.Login("s1", "code", "alice", "wrong"); err == nil {
+		t.Error("Login() should reject invalid credentials")
+	}
+	if _, ok := m.Get("s1"); ok {
+		t.Error("Get() should not find a session whose login failed")
+	}
+
+	acc, err := m.Login("s1", "code", "alice", "1234")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if acc.Store != nil {
+		t.Error("Login() should not persist anything for the session")
+	}
+	if cfg := acc.Config(); cfg.PIN != "1234" || cfg.FeedToken != "" || len(cfg.Profiles) != 0 {
+		t.Errorf("Login() config = %+v, want only the session credentials", cfg)
+	}
+
+	got, ok := m.Get("s1")
+	if !ok || got != acc {
+		t.Errorf("Get() = %v, %v, want the account of the session", got, ok)
+	}
+
+	// Sessions are isolated
+	other, err := m.Login("s2", "code", "alice", "1234")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if other == acc || other.Client == acc.Client {
+		t.Error("Login() should give every session its own client")
+	}
+
+	m.Logout("s1")
+	if _, ok := m.Get("s1"); ok {
+		t.Error("Get() should not find a logged out session")
+	}
+	if m.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", m.Len())
+	}
+}
+
+func TestManager_LoginRateLimit(t *testing.T) {
+	newTestServer(t)
+	upstream := ratelimit.NewLimiter(config.Rate{Requests: 1, Per: time.Hour, Burst: 2})
+	m := NewManager(config.NewTestConfig(), upstream)
+
+	// Failed attempts take from the bucket shared with every account
+	for i := 0; i < 2; i++ {
+		if _, err := m.Login("s1", "code", "alice", "wrong"); err == nil {
+			t.Fatal("Login() should reject invalid credentials")
+		}
+	}
+	_, err := m.Login("s1", "code", "alice", "1234")
+	if _, ok := ratelimit.RetryAfter(err); !ok {
+		t.Errorf("Login() error = %v, want a rate limit error", err)
+	}
+}
+
+func TestManager_Evict(t *testing.T) {
+	newTestServer(t)
+	cfg := config.NewTestConfig()
+	cfg.Auth.IdleTimeout = 30 * time.Minute
+	m := NewManager(cfg, nil)
+
+	now := time.Date(2025, 2, 10, 8, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	for _, id := range []string{"idle", "active"} {
+		if _, err := m.Login(id, "code", "alice", "1234"); err != nil {
+			t.Fatalf("Login() error = %v", err)
+		}
+	}
+
+	now = now.Add(20 * time.Minute)
+	m.Get("active")
+	now = now.Add(20 * time.Minute)
+
+	if got := m.Evict(); got != 1 {
+		t.Errorf("Evict() = %d, want 1", got)
+	}
+	if _, ok := m.Get("idle"); ok {
+		t.Error("Evict() should forget the idle session")
+	}
+	if _, ok := m.Get("active"); !ok {
+		t.Error("Evict() should keep the active session")
+	}
+}
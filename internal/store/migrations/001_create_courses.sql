@@ -0,0 +1,29 @@
+CREATE TABLE courses (
+    id         INTEGER PRIMARY KEY,
+    name       TEXT NOT NULL,
+    date       TEXT NOT NULL,
+    start_time TEXT NOT NULL,
+    end_time   TEXT NOT NULL,
+    starts_at  TEXT NOT NULL,
+    code       TEXT NOT NULL,
+    first_seen TEXT NOT NULL,
+    last_seen  TEXT NOT NULL
+);
+
+CREATE INDEX idx_courses_starts_at ON courses (starts_at);
+
+CREATE TABLE course_changes (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    course_id   INTEGER NOT NULL REFERENCES courses (id),
+    detected_at TEXT NOT NULL,
+    old_name    TEXT NOT NULL,
+    old_date    TEXT NOT NULL,
+    old_start   TEXT NOT NULL,
+    old_end     TEXT NOT NULL,
+    new_name    TEXT NOT NULL,
+    new_date    TEXT NOT NULL,
+    new_start   TEXT NOT NULL,
+    new_end     TEXT NOT NULL
+);
+
+CREATE INDEX idx_course_changes_course_id ON course_changes (course_id);
@@ -0,0 +1,11 @@
+ALTER TABLE course_changes ADD COLUMN kind TEXT NOT NULL DEFAULT 'moved';
+
+UPDATE course_changes SET kind = 'renamed'
+WHERE old_name <> new_name AND old_date = new_date AND old_start = new_start AND old_end = new_end;
+
+CREATE INDEX idx_course_changes_detected_at ON course_changes (detected_at);
+
+CREATE TABLE state (
+    key   TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
@@ -0,0 +1,6 @@
+CREATE TABLE reminders (
+    course_id      INTEGER NOT NULL,
+    offset_seconds INTEGER NOT NULL,
+    sent_at        TEXT NOT NULL,
+    PRIMARY KEY (course_id, offset_seconds)
+);
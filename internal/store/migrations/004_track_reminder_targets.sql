@@ -0,0 +1,17 @@
+-- Reminders are claimed per notification target, so that a failing target
+-- is retried without notifying the others again. Reminders claimed before
+-- have no target and count as sent to every target
+CREATE TABLE reminder_targets (
+    course_id      INTEGER NOT NULL,
+    offset_seconds INTEGER NOT NULL,
+    target         TEXT NOT NULL,
+    sent_at        TEXT NOT NULL,
+    PRIMARY KEY (course_id, offset_seconds, target)
+);
+
+INSERT INTO reminder_targets (course_id, offset_seconds, target, sent_at)
+SELECT course_id, offset_seconds, '', sent_at FROM reminders;
+
+DROP TABLE reminders;
+
+ALTER TABLE reminder_targets RENAME TO reminders;
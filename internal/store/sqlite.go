@@ -0,0 +1,380 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/diff"
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/service"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	timeFormat   = "2006-01-02T15:04:05Z"
+	defaultLimit = 500
+	// feedTokenBytes is the size of a random feed token, 128 bits
+	feedTokenBytes = 16
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// SQLiteStore is a Repository backed by a SQLite database
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+var _ Repository = (*SQLiteStore)(nil)
+
+// OpenSQLite opens the SQLite database at path, creating it if needed, and
+// applies pending migrations
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %v", err)
+	}
+	// SQLite only supports a single writer
+	db.SetMaxOpenConns(1)
+
+	s := &SQLiteStore{db: db}
+	if err := s.migrate(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate applies the embedded migrations newer than the schema version
+// tracked in PRAGMA user_version
+func (s *SQLiteStore) migrate(ctx context.Context) error {
+	var version int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %v", err)
+	}
+
+	names, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return fmt.Errorf("failed to list migrations: %v", err)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		if i < version {
+			continue
+		}
+
+		script, err := migrations.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %v", name, err)
+		}
+
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %s: %v", name, err)
+		}
+		if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %s: %v", name, err)
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to update schema version: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) RecordCourses(ctx context.Context, courses []models.Course, seenAt time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	seen := seenAt.UTC().Format(timeFormat)
+	for _, course := range courses {
+		if course.ID == 0 {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO courses (id, name, date, start_time, end_time, starts_at, code, first_seen, last_seen)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET
+				name = excluded.name,
+				date = excluded.date,
+				start_time = excluded.start_time,
+				end_time = excluded.end_time,
+				starts_at = excluded.starts_at,
+				code = excluded.code,
+				last_seen = excluded.last_seen`,
+			course.ID, course.Name, course.Date, course.Start, course.End,
+			formatTime(service.CourseStart(course)), generateCode(course), seen, seen); err != nil {
+			return fmt.Errorf("failed to record course %d: %v", course.ID, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO state (key, value) VALUES ('last_refresh', ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value`, seen); err != nil {
+		return fmt.Errorf("failed to record refresh time: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit courses: %v", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) LatestSnapshot(ctx context.Context) ([]models.Course, bool, error) {
+	var lastRefresh string
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM state WHERE key = 'last_refresh'").Scan(&lastRefresh)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read last refresh time: %v", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT id, name, date, start_time, end_time FROM courses WHERE last_seen = ? ORDER BY starts_at, id", lastRefresh)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to query snapshot: %v", err)
+	}
+	defer rows.Close()
+
+	courses := []models.Course{}
+	for rows.Next() {
+		var c models.Course
+		if err := rows.Scan(&c.ID, &c.Name, &c.Date, &c.Start, &c.End); err != nil {
+			return nil, false, fmt.Errorf("failed to scan course: %v", err)
+		}
+		courses = append(courses, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to read snapshot: %v", err)
+	}
+	return courses, true, nil
+}
+
+func (s *SQLiteStore) ListCourses(ctx context.Context, filter Filter) ([]CourseRecord, error) {
+	var where []string
+	var args []any
+
+	if filter.ID != 0 {
+		where = append(where, "id = ?")
+		args = append(args, filter.ID)
+	}
+	if !filter.From.IsZero() {
+		where = append(where, "starts_at >= ?")
+		args = append(args, formatTime(filter.From))
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "starts_at < ?")
+		args = append(args, formatTime(filter.To))
+	}
+	if filter.Name != "" {
+		where = append(where, `name LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(filter.Name)+"%")
+	}
+
+	query := "SELECT id, name, date, start_time, end_time, starts_at, code, first_seen, last_seen FROM courses"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY starts_at DESC, id DESC LIMIT ?"
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query courses: %v", err)
+	}
+	defer rows.Close()
+
+	var records []CourseRecord
+	for rows.Next() {
+		var r CourseRecord
+		var startsAt, firstSeen, lastSeen string
+		if err := rows.Scan(&r.ID, &r.Name, &r.Date, &r.Start, &r.End, &startsAt, &r.Code, &firstSeen, &lastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan course: %v", err)
+		}
+		r.StartsAt = parseTime(startsAt)
+		r.FirstSeen = parseTime(firstSeen)
+		r.LastSeen = parseTime(lastSeen)
+		records = append(records, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read courses: %v", err)
+	}
+	return records, nil
+}
+
+func (s *SQLiteStore) RecordChanges(ctx context.Context, changes []diff.Change) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for _, c := range changes {
+		var before, after models.Course
+		if c.Before != nil {
+			before = *c.Before
+		}
+		if c.After != nil {
+			after = *c.After
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO course_changes (course_id, kind, detected_at, old_name, old_date, old_start, old_end, new_name, new_date, new_start, new_end)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			c.CourseID, string(c.Kind), c.DetectedAt.UTC().Format(timeFormat),
+			before.Name, before.Date, before.Start, before.End,
+			after.Name, after.Date, after.Start, after.End); err != nil {
+			return fmt.Errorf("failed to record change of course %d: %v", c.CourseID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit changes: %v", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) ListChanges(ctx context.Context, limit int) ([]diff.Change, error) {
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	return s.queryChanges(ctx, changeColumns+" ORDER BY detected_at DESC, id DESC LIMIT ?", limit)
+}
+
+func (s *SQLiteStore) CourseChanges(ctx context.Context, courseID int) ([]diff.Change, error) {
+	return s.queryChanges(ctx, changeColumns+" WHERE course_id = ? ORDER BY detected_at, id", courseID)
+}
+
+const changeColumns = `SELECT course_id, kind, detected_at, old_name, old_date, old_start, old_end, new_name, new_date, new_start, new_end
+	FROM course_changes`
+
+func (s *SQLiteStore) queryChanges(ctx context.Context, query string, args ...any) ([]diff.Change, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query changes: %v", err)
+	}
+	defer rows.Close()
+
+	var changes []diff.Change
+	for rows.Next() {
+		var c diff.Change
+		var kind, detectedAt string
+		var before, after models.Course
+		if err := rows.Scan(&c.CourseID, &kind, &detectedAt,
+			&before.Name, &before.Date, &before.Start, &before.End,
+			&after.Name, &after.Date, &after.Start, &after.End); err != nil {
+			return nil, fmt.Errorf("failed to scan change: %v", err)
+		}
+
+		c.Kind = diff.Kind(kind)
+		c.DetectedAt = parseTime(detectedAt)
+		before.ID, after.ID = c.CourseID, c.CourseID
+		if c.Kind != diff.Added {
+			c.Before = &before
+		}
+		if c.Kind != diff.Removed {
+			c.After = &after
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read changes: %v", err)
+	}
+	return changes, nil
+}
+
+func (s *SQLiteStore) ClaimReminder(ctx context.Context, courseID int, offset time.Duration, target string, at time.Time) (bool, error) {
+	// Reminders claimed without a target were sent to every target
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO reminders (course_id, offset_seconds, target, sent_at)
+		SELECT ?1, ?2, ?3, ?4 WHERE NOT EXISTS (
+			SELECT 1 FROM reminders WHERE course_id = ?1 AND offset_seconds = ?2 AND target IN (?3, '')
+		)`,
+		courseID, int64(offset.Seconds()), target, at.UTC().Format(timeFormat))
+	if err != nil {
+		return false, fmt.Errorf("failed to claim reminder: %v", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim reminder: %v", err)
+	}
+	return n == 1, nil
+}
+
+func (s *SQLiteStore) ReleaseReminder(ctx context.Context, courseID int, offset time.Duration, target string) error {
+	if _, err := s.db.ExecContext(ctx,
+		"DELETE FROM reminders WHERE course_id = ? AND offset_seconds = ? AND target = ?",
+		courseID, int64(offset.Seconds()), target); err != nil {
+		return fmt.Errorf("failed to release reminder: %v", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) FeedToken(ctx context.Context) (string, error) {
+	b := make([]byte, feedTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate feed token: %v", err)
+	}
+
+	// Keeps the token of a concurrent first call
+	if _, err := s.db.ExecContext(ctx,
+		"INSERT OR IGNORE INTO state (key, value) VALUES ('feed_token', ?)", hex.EncodeToString(b)); err != nil {
+		return "", fmt.Errorf("failed to store feed token: %v", err)
+	}
+
+	var token string
+	if err := s.db.QueryRowContext(ctx, "SELECT value FROM state WHERE key = 'feed_token'").Scan(&token); err != nil {
+		return "", fmt.Errorf("failed to read feed token: %v", err)
+	}
+	return token, nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func generateCode(course models.Course) string {
+	_, _, _, code := service.GenerateFixedCode(course)
+	return code
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(timeFormat)
+}
+
+func parseTime(s string) time.Time {
+	t, _ := time.Parse(timeFormat, s)
+	return t
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
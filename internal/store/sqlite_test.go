@@ -0,0 +1,349 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/diff"
+	"github.com/LaulauChau/sws/internal/models"
+)
+
+func openTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	return openStoreAt(t, filepath.Join(t.TempDir(), "sws.db"))
+}
+
+func openStoreAt(t *testing.T, path string) *SQLiteStore {
+	t.Helper()
+
+	s, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSQLiteStore_Migrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sws.db")
+
+	for i := 0; i < 2; i++ {
+		s, err := OpenSQLite(path)
+		if err != nil {
+			t.Fatalf("OpenSQLite() attempt %d error = %v", i+1, err)
+		}
+
+		var version int
+		if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+			t.Fatal(err)
+		}
+		if version == 0 {
+			t.Error("expected migrations to set the schema version")
+		}
+		s.Close()
+	}
+}
+
+func TestSQLiteStore_RecordCourses(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	course := models.Course{
+		ID:    137393,
+		Name:  "Innover et entreprendre [XDEV003-CTD / 2425S10-PAR1]",
+		Date:  "2025-02-10",
+		Start: "08:00:00+00:00",
+		End:   "12:00:00+00:00",
+	}
+	first := time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC)
+	if err := s.RecordCourses(ctx, []models.Course{course}, first); err != nil {
+		t.Fatalf("RecordCourses() error = %v", err)
+	}
+
+	moved := course
+	moved.Start, moved.End = "09:00:00+00:00", "13:00:00+00:00"
+	second := first.Add(time.Hour)
+	if err := s.RecordCourses(ctx, []models.Course{moved}, second); err != nil {
+		t.Fatalf("RecordCourses() error = %v", err)
+	}
+
+	records, err := s.ListCourses(ctx, Filter{})
+	if err != nil {
+		t.Fatalf("ListCourses() error = %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 course, got %d", len(records))
+	}
+
+	r := records[0]
+	if !r.FirstSeen.Equal(first) || !r.LastSeen.Equal(second) {
+		t.Errorf("got first/last seen %v/%v, want %v/%v", r.FirstSeen, r.LastSeen, first, second)
+	}
+	if r.Start != moved.Start || r.Code == "" {
+		t.Errorf("expected the stored course to be updated, got %+v", r)
+	}
+	if want := time.Date(2025, 2, 10, 9, 0, 0, 0, time.UTC); !r.StartsAt.Equal(want) {
+		t.Errorf("got starts at %v, want %v", r.StartsAt, want)
+	}
+}
+
+func TestSQLiteStore_LatestSnapshot(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	if _, ok, err := s.LatestSnapshot(ctx); err != nil || ok {
+		t.Fatalf("LatestSnapshot() = %v, %v, want no snapshot", ok, err)
+	}
+
+	first := []models.Course{
+		{ID: 1, Name: "First", Date: "2025-02-10", Start: "08:00:00+00:00", End: "12:00:00+00:00"},
+		{ID: 2, Name: "Second", Date: "2025-02-11", Start: "08:00:00+00:00", End: "12:00:00+00:00"},
+	}
+	now := time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC)
+	if err := s.RecordCourses(ctx, first, now); err != nil {
+		t.Fatalf("RecordCourses() error = %v", err)
+	}
+	if err := s.RecordCourses(ctx, first[1:], now.Add(time.Minute)); err != nil {
+		t.Fatalf("RecordCourses() error = %v", err)
+	}
+
+	snapshot, ok, err := s.LatestSnapshot(ctx)
+	if err != nil || !ok {
+		t.Fatalf("LatestSnapshot() = %v, %v, want a snapshot", ok, err)
+	}
+	if len(snapshot) != 1 || snapshot[0] != first[1] {
+		t.Errorf("LatestSnapshot() = %+v, want only the second course", snapshot)
+	}
+
+	// An empty refresh is a valid snapshot too
+	if err := s.RecordCourses(ctx, nil, now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("RecordCourses() error = %v", err)
+	}
+	snapshot, ok, err = s.LatestSnapshot(ctx)
+	if err != nil || !ok || len(snapshot) != 0 {
+		t.Errorf("LatestSnapshot() = %+v, %v, %v, want an empty snapshot", snapshot, ok, err)
+	}
+}
+
+func TestSQLiteStore_Changes(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	course := models.Course{ID: 1, Name: "First", Date: "2025-02-10", Start: "08:00:00+00:00", End: "12:00:00+00:00"}
+	other := models.Course{ID: 2, Name: "Second", Date: "2025-02-11", Start: "08:00:00+00:00", End: "12:00:00+00:00"}
+	if err := s.RecordCourses(ctx, []models.Course{course, other}, time.Now()); err != nil {
+		t.Fatalf("RecordCourses() error = %v", err)
+	}
+
+	moved := course
+	moved.Start = "09:00:00+00:00"
+	now := time.Date(2025, 2, 1, 10, 0, 0, 0, time.UTC)
+	changes := []diff.Change{
+		{Kind: diff.Added, CourseID: 1, After: &course, DetectedAt: now},
+		{Kind: diff.Moved, CourseID: 1, Before: &course, After: &moved, DetectedAt: now.Add(time.Hour)},
+		{Kind: diff.Removed, CourseID: 2, Before: &other, DetectedAt: now.Add(2 * time.Hour)},
+	}
+	if err := s.RecordChanges(ctx, changes); err != nil {
+		t.Fatalf("RecordChanges() error = %v", err)
+	}
+
+	recent, err := s.ListChanges(ctx, 2)
+	if err != nil {
+		t.Fatalf("ListChanges() error = %v", err)
+	}
+	if len(recent) != 2 || recent[0].Kind != diff.Removed || recent[1].Kind != diff.Moved {
+		t.Fatalf("ListChanges() = %+v, want removed then moved", recent)
+	}
+	if recent[0].After != nil || *recent[0].Before != other {
+		t.Errorf("removed change should only have a before state, got %+v", recent[0])
+	}
+
+	history, err := s.CourseChanges(ctx, 1)
+	if err != nil {
+		t.Fatalf("CourseChanges() error = %v", err)
+	}
+	if len(history) != 2 || history[0].Kind != diff.Added || history[1].Kind != diff.Moved {
+		t.Fatalf("CourseChanges() = %+v, want added then moved", history)
+	}
+	if history[0].Before != nil || *history[0].After != course {
+		t.Errorf("added change should only have an after state, got %+v", history[0])
+	}
+	if *history[1].Before != course || *history[1].After != moved || !history[1].DetectedAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("unexpected moved change %+v", history[1])
+	}
+}
+
+func TestSQLiteStore_ListCourses(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	courses := []models.Course{
+		{ID: 1, Name: "Innover et entreprendre", Date: "2025-02-10", Start: "08:00:00+00:00", End: "12:00:00+00:00"},
+		{ID: 2, Name: "Gestion de projet", Date: "2025-02-11", Start: "08:00:00+00:00", End: "12:00:00+00:00"},
+		{ID: 3, Name: "Innover 100%", Date: "2025-02-12", Start: "08:00:00+00:00", End: "12:00:00+00:00"},
+	}
+	if err := s.RecordCourses(ctx, courses, time.Now()); err != nil {
+		t.Fatalf("RecordCourses() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int
+	}{
+		{
+			name:   "no filter, most recent first",
+			filter: Filter{},
+			want:   []int{3, 2, 1},
+		},
+		{
+			name:   "name substring is case-insensitive",
+			filter: Filter{Name: "INNOVER"},
+			want:   []int{3, 1},
+		},
+		{
+			name:   "wildcards are matched literally",
+			filter: Filter{Name: "100%"},
+			want:   []int{3},
+		},
+		{
+			name: "date range",
+			filter: Filter{
+				From: time.Date(2025, 2, 11, 0, 0, 0, 0, time.UTC),
+				To:   time.Date(2025, 2, 12, 0, 0, 0, 0, time.UTC),
+			},
+			want: []int{2},
+		},
+		{
+			name:   "limit",
+			filter: Filter{Limit: 1},
+			want:   []int{3},
+		},
+		{
+			name:   "single course",
+			filter: Filter{ID: 2},
+			want:   []int{2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := s.ListCourses(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("ListCourses() error = %v", err)
+			}
+			var got []int
+			for _, r := range records {
+				got = append(got, r.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestSQLiteStore_ClaimReminder(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	claimed, err := s.ClaimReminder(ctx, 1, 15*time.Minute, "webhook", now)
+	if err != nil || !claimed {
+		t.Fatalf("ClaimReminder() = %v, %v, want first claim to succeed", claimed, err)
+	}
+	if claimed, _ := s.ClaimReminder(ctx, 1, 15*time.Minute, "webhook", now); claimed {
+		t.Error("a reminder must only be claimed once")
+	}
+	if claimed, _ := s.ClaimReminder(ctx, 1, time.Hour, "webhook", now); !claimed {
+		t.Error("each offset must be claimed independently")
+	}
+	if claimed, _ := s.ClaimReminder(ctx, 1, 15*time.Minute, "ntfy", now); !claimed {
+		t.Error("each target must be claimed independently")
+	}
+
+	if err := s.ReleaseReminder(ctx, 1, 15*time.Minute, "webhook"); err != nil {
+		t.Fatalf("ReleaseReminder() error = %v", err)
+	}
+	if claimed, _ := s.ClaimReminder(ctx, 1, 15*time.Minute, "webhook", now); !claimed {
+		t.Error("a released reminder can be claimed again")
+	}
+	if claimed, _ := s.ClaimReminder(ctx, 1, 15*time.Minute, "ntfy", now); claimed {
+		t.Error("releasing a target must keep the claims of the others")
+	}
+
+	// Reminders claimed before targets were tracked cover every target
+	if claimed, _ := s.ClaimReminder(ctx, 2, 15*time.Minute, "", now); !claimed {
+		t.Fatal("ClaimReminder() without a target should succeed")
+	}
+	if claimed, _ := s.ClaimReminder(ctx, 2, 15*time.Minute, "webhook", now); claimed {
+		t.Error("a reminder claimed without a target must not be sent again")
+	}
+}
+
+func TestSQLiteStore_MigrateReminders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sws.db")
+
+	// A database claiming reminders per course and offset only
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"001_create_courses.sql", "002_classify_changes.sql", "003_create_reminders.sql"} {
+		script, err := migrations.ReadFile("migrations/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(string(script)); err != nil {
+			t.Fatalf("failed to apply %s: %v", name, err)
+		}
+	}
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = 3; INSERT INTO reminders VALUES (1, 900, '%s')",
+		time.Now().UTC().Format(timeFormat))); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	s := openStoreAt(t, path)
+	if claimed, err := s.ClaimReminder(context.Background(), 1, 15*time.Minute, "webhook", time.Now()); err != nil || claimed {
+		t.Errorf("ClaimReminder() = %v, %v, want the reminder sent before the migration", claimed, err)
+	}
+}
+
+func TestSQLiteStore_FeedToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sws.db")
+	s, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	ctx := context.Background()
+
+	token, err := s.FeedToken(ctx)
+	if err != nil || len(token) != 2*feedTokenBytes {
+		t.Fatalf("FeedToken() = %q, %v, want a %d character token", token, err, 2*feedTokenBytes)
+	}
+	if again, _ := s.FeedToken(ctx); again != token {
+		t.Errorf("FeedToken() = %q, want the stored %q", again, token)
+	}
+	s.Close()
+
+	// The token survives restarts
+	s = openStoreAt(t, path)
+	if reopened, _ := s.FeedToken(ctx); reopened != token {
+		t.Errorf("FeedToken() after reopening = %q, want %q", reopened, token)
+	}
+
+	if other, _ := openTestStore(t).FeedToken(ctx); other == token {
+		t.Error("FeedToken() should differ between databases")
+	}
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/diff"
+	"github.com/LaulauChau/sws/internal/models"
+)
+
+// CourseRecord is a course as stored in the history, along with its generated code
+type CourseRecord struct {
+	models.Course
+	Code      string    `json:"code"`
+	StartsAt  time.Time `json:"startsAt"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// Filter restricts the courses returned from the history
+type Filter struct {
+	// ID restricts the results to one course, zero matching every course
+	ID int
+	// From and To bound the course start time, zero values leave the range open
+	From time.Time
+	To   time.Time
+	// Name matches courses whose name contains it, case-insensitively
+	Name string
+	// Limit caps the number of results, defaulting to 500
+	Limit int
+}
+
+// Repository persists every course ever seen and the changes of the schedule
+type Repository interface {
+	// RecordCourses upserts the courses seen at the given time and remembers
+	// them as the latest snapshot
+	RecordCourses(ctx context.Context, courses []models.Course, seenAt time.Time) error
+	// LatestSnapshot returns the courses of the last recorded refresh, and
+	// false if nothing was recorded yet
+	LatestSnapshot(ctx context.Context) ([]models.Course, bool, error)
+	// ListCourses returns the recorded courses matching the filter, most recent first
+	ListCourses(ctx context.Context, filter Filter) ([]CourseRecord, error)
+	// RecordChanges stores detected schedule changes
+	RecordChanges(ctx context.Context, changes []diff.Change) error
+	// ListChanges returns the most recent schedule changes first
+	ListChanges(ctx context.Context, limit int) ([]diff.Change, error)
+	// CourseChanges returns the schedule changes of a course, oldest first
+	CourseChanges(ctx context.Context, courseID int) ([]diff.Change, error)
+	// ClaimReminder marks the reminder of a course for an offset as sent to
+	// a notification target, returning false if it already was
+	ClaimReminder(ctx context.Context, courseID int, offset time.Duration, target string, at time.Time) (bool, error)
+	// ReleaseReminder forgets a claimed reminder so that it can be retried
+	ReleaseReminder(ctx context.Context, courseID int, offset time.Duration, target string) error
+	// FeedToken returns the secret token of the calendar feed, generated at
+	// random the first time it is needed
+	FeedToken(ctx context.Context) (string, error)
+	Close() error
+}
@@ -0,0 +1,42 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/LaulauChau/sws/internal/config"
+)
+
+func TestSetup(t *testing.T) {
+	var buf bytes.Buffer
+	shutdown, err := Setup(context.Background(), config.Tracing{Exporter: config.TracingStdout, SampleRatio: 1}, &buf)
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+
+	_, span := Tracer("test").Start(context.Background(), "test span")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown() error = %v", err)
+	}
+
+	if out := buf.String(); !strings.Contains(out, `"Name": "test span"`) || !strings.Contains(out, `"Value": "sws"`) {
+		t.Errorf("stdout exporter output = %s, want the span of the sws service", out)
+	}
+}
+
+func TestSetup_Disabled(t *testing.T) {
+	shutdown, err := Setup(context.Background(), config.Tracing{}, nil)
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+
+	if _, err := Setup(context.Background(), config.Tracing{Exporter: "jaeger"}, nil); err == nil {
+		t.Error("Setup() should reject an unknown exporter")
+	}
+}
@@ -12,6 +12,16 @@ type Cache[T any] struct {
 	lastUpdated   time.Time
 	updateTimeout time.Duration
 	initialized   bool
+	hits, misses  uint64
+}
+
+// Stats describes the use of a cache
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+	// Age is how long ago the data was set, when Initialized
+	Age         time.Duration
+	Initialized bool
 }
 
 // NewCache creates a new cache instance with the specified timeout
@@ -23,17 +33,31 @@ func NewCache[T any](updateTimeout time.Duration) *Cache[T] {
 
 // Get retrieves the cached data and whether it's valid
 func (c *Cache[T]) Get() (T, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
 	var zero T
 	if !c.initialized || time.Since(c.lastUpdated) > c.updateTimeout {
+		c.misses++
 		return zero, false
 	}
 
+	c.hits++
 	return c.data, true
 }
 
+// Stats returns the hits and misses of Get and the age of the data
+func (c *Cache[T]) Stats() Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	s := Stats{Hits: c.hits, Misses: c.misses, Initialized: c.initialized}
+	if c.initialized {
+		s.Age = time.Since(c.lastUpdated)
+	}
+	return s
+}
+
 // Set updates the cached data
 func (c *Cache[T]) Set(data T) {
 	c.mu.Lock()
@@ -43,3 +67,21 @@ func (c *Cache[T]) Set(data T) {
 	c.lastUpdated = time.Now()
 	c.initialized = true
 }
+
+// SetTimeout changes how long the cached data stays valid
+func (c *Cache[T]) SetTimeout(updateTimeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.updateTimeout = updateTimeout
+}
+
+// Invalidate discards the cached data
+func (c *Cache[T]) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero T
+	c.data = zero
+	c.initialized = false
+}
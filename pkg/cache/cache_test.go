@@ -165,3 +165,44 @@ func TestCache_Types(t *testing.T) {
 		}
 	})
 }
+
+func TestCache_SetTimeoutAndInvalidate(t *testing.T) {
+	cache := NewCache[string](time.Hour)
+	cache.Set("value")
+
+	cache.SetTimeout(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, ok := cache.Get(); ok {
+		t.Error("data should expire after shortening the timeout")
+	}
+
+	cache.SetTimeout(time.Hour)
+	if _, ok := cache.Get(); !ok {
+		t.Error("data should be valid again after extending the timeout")
+	}
+
+	cache.Invalidate()
+	if data, ok := cache.Get(); ok || data != "" {
+		t.Errorf("Get() = %q, %v after Invalidate(), want empty miss", data, ok)
+	}
+}
+
+func TestCache_Stats(t *testing.T) {
+	cache := NewCache[string](time.Hour)
+	if s := cache.Stats(); s.Initialized || s.Hits != 0 || s.Misses != 0 {
+		t.Errorf("Stats() = %+v, want an empty cache", s)
+	}
+
+	cache.Get()
+	cache.Set("value")
+	cache.Get()
+	cache.Get()
+
+	s := cache.Stats()
+	if s.Hits != 2 || s.Misses != 1 {
+		t.Errorf("Stats() = %+v, want 2 hits and 1 miss", s)
+	}
+	if !s.Initialized || s.Age < 0 || s.Age > time.Minute {
+		t.Errorf("Stats().Age = %v, want the time since Set()", s.Age)
+	}
+}
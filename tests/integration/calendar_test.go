@@ -0,0 +1,102 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/middleware"
+	"github.com/LaulauChau/sws/internal/models"
+	static "github.com/LaulauChau/sws/web"
+)
+
+func TestCalendarView(t *testing.T) {
+	web := newWebServer(t, nil)
+
+	day := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	web.sowesign.SetCourses([]models.Course{
+		{ID: 1, Name: "Innover et entreprendre [XDEV003-CTD / 2425S10-PAR1]", Date: day, Start: "08:00:00+00:00", End: "11:00:00+00:00"},
+		{ID: 2, Name: "Gestion de projet [XMAN001-CM / 2425S10-PAR1]", Date: day, Start: "12:30:00+00:00", End: "15:30:00+00:00"},
+	})
+
+	for _, view := range []string{"week", "month"} {
+		rec := web.get("/calendar?view=" + view + "&date=" + day)
+		body := rec.Body.String()
+		if rec.Code != http.StatusOK || !strings.Contains(body, "<html") ||
+			!strings.Contains(body, "Innover et entreprendre") || !strings.Contains(body, "Gestion de projet") {
+			t.Errorf("GET /calendar?view=%s = %d, want the page with both courses", view, rec.Code)
+		}
+	}
+
+	// Navigation only swaps the calendar, keeping the filters
+	req := httptest.NewRequest(http.MethodGet, "/calendar?date="+day+"&module=xman001", nil)
+	req.Header.Set("HX-Request", "true")
+	rec := web.serve(req)
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || strings.Contains(body, "<html") {
+		t.Errorf("GET /calendar partial = %d, want the calendar alone", rec.Code)
+	}
+	if strings.Contains(body, "Innover et entreprendre") || !strings.Contains(body, "module=xman001") {
+		t.Errorf("GET /calendar?module=xman001 = %q, want the filtered calendar", body)
+	}
+
+	for _, path := range []string{"/calendar?view=year", "/calendar?date=demain", "/calendar?time=night"} {
+		if rec := web.get(path); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want 400", path, rec.Code)
+		}
+	}
+}
+
+func TestCalendarView_ContentSecurityPolicy(t *testing.T) {
+	web := newWebServer(t, nil)
+	web.mux.Handle("/static/", http.StripPrefix("/static/", http.FileServerFS(static.Static())))
+	h := middleware.Chain(web.mux, middleware.RequestID, middleware.SecurityHeaders)
+
+	// Overlapping courses are placed side by side
+	day := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	web.sowesign.SetCourses([]models.Course{
+		{ID: 1, Name: "Innover et entreprendre [XDEV003-CTD / 2425S10-PAR1]", Date: day, Start: "08:00:00+00:00", End: "11:00:00+00:00"},
+		{ID: 2, Name: "Gestion de projet [XMAN001-CM / 2425S10-PAR1]", Date: day, Start: "09:35:00+00:00", End: "12:10:00+00:00"},
+	})
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/calendar?date=" + day)
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /calendar = %d, want 200", rec.Code)
+	}
+	csp := rec.Header().Get("Content-Security-Policy")
+	if !strings.Contains(csp, "style-src 'self';") {
+		t.Fatalf("Content-Security-Policy = %q, want styles from the server only", csp)
+	}
+	if strings.Contains(body, " style=") {
+		t.Errorf("GET /calendar has inline styles, which %q blocks", csp)
+	}
+
+	// Every placement class of the calendar comes from its stylesheet
+	if !strings.Contains(body, `href="/static/css/calendar.css"`) {
+		t.Fatalf("GET /calendar doesn't link css/calendar.css")
+	}
+	rec = get("/static/css/calendar.css")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /static/css/calendar.css = %d, want 200", rec.Code)
+	}
+	css := rec.Body.String()
+	classes := regexp.MustCompile(`\bcal-[a-z]+(-\d+)?\b`).FindAllString(body, -1)
+	if !slices.Contains(classes, "cal-lanes-2") {
+		t.Errorf("GET /calendar classes = %v, want the courses in two lanes", classes)
+	}
+	for _, class := range classes {
+		if !strings.Contains(css, "."+class+" {") {
+			t.Errorf("css/calendar.css has no rule for %s", class)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package integration
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/client"
+	"github.com/LaulauChau/sws/internal/config"
+	"github.com/LaulauChau/sws/internal/diff"
+	"github.com/LaulauChau/sws/internal/mock"
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/poller"
+	"github.com/LaulauChau/sws/internal/store"
+)
+
+// newChangeTracker wires a poller to the store the same way the server does
+func newChangeTracker(t *testing.T, repo store.Repository) *poller.Poller {
+	t.Helper()
+
+	p := poller.NewPoller(client.NewClient(config.NewTestConfig(), nil), time.Hour)
+	if snapshot, ok, err := repo.LatestSnapshot(context.Background()); err != nil {
+		t.Fatalf("LatestSnapshot() error = %v", err)
+	} else if ok {
+		p.Seed(snapshot)
+	}
+
+	p.OnRefresh(func(courses []models.Course) {
+		if err := repo.RecordCourses(context.Background(), courses, time.Now()); err != nil {
+			t.Errorf("RecordCourses() error = %v", err)
+		}
+	})
+	p.OnChange(func(prev, next []models.Course) {
+		if err := repo.RecordChanges(context.Background(), diff.Compare(prev, next, time.Now())); err != nil {
+			t.Errorf("RecordChanges() error = %v", err)
+		}
+	})
+	return p
+}
+
+func TestScheduleChangeDetection(t *testing.T) {
+	server := mock.NewServer()
+	defer server.Close()
+
+	client.SetBaseURLs(server.URL+"/api/portal/authentication/token",
+		server.URL+"/api/student-app/future-courses",
+		server.URL+"/api/trainer-app/current-courses")
+
+	path := filepath.Join(t.TempDir(), "sws.db")
+	repo, err := store.OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+
+	day := func(offset int) string {
+		return time.Now().AddDate(0, 0, offset).Format("2006-01-02")
+	}
+	course := func(id int, name string, offset int, start string) models.Course {
+		return models.Course{ID: id, Name: name, Date: day(offset), Start: start + ":00+00:00", End: "18:00:00+00:00"}
+	}
+
+	server.SetCourses([]models.Course{
+		course(1, "Innover et entreprendre", 2, "08:00"),
+		course(2, "Gestion de projet", 3, "08:00"),
+		course(3, "Anglais", 4, "08:00"),
+		course(4, "Droit du numérique", 5, "08:00"),
+	})
+
+	p := newChangeTracker(t, repo)
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+
+	changes, err := repo.ListChanges(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("ListChanges() error = %v", err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("expected the first poll to only record a baseline, got %+v", changes)
+	}
+
+	// Move the first course, rename the second, cancel the third and add a new one
+	server.SetCourses([]models.Course{
+		course(1, "Innover et entreprendre", 2, "13:00"),
+		course(2, "Gestion de projet agile", 3, "08:00"),
+		course(4, "Droit du numérique", 5, "08:00"),
+		course(5, "Cybersécurité", 5, "13:00"),
+	})
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+
+	assertKinds(t, repo, map[int]diff.Kind{1: diff.Moved, 2: diff.Renamed, 3: diff.Removed, 5: diff.Added})
+	repo.Close()
+
+	// After a restart, changes are detected against the persisted snapshot
+	repo, err = store.OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer repo.Close()
+
+	server.SetCourses([]models.Course{
+		course(1, "Innover et entreprendre", 2, "13:00"),
+		course(2, "Gestion de projet agile", 3, "08:00"),
+		course(4, "Droit du numérique", 6, "08:00"),
+		course(5, "Cybersécurité", 5, "13:00"),
+	})
+	p = newChangeTracker(t, repo)
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+
+	history, err := repo.CourseChanges(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("CourseChanges() error = %v", err)
+	}
+	if len(history) != 1 || history[0].Kind != diff.Moved || history[0].After.Date != day(6) {
+		t.Errorf("expected course 4 to be moved after the restart, got %+v", history)
+	}
+}
+
+func assertKinds(t *testing.T, repo store.Repository, want map[int]diff.Kind) {
+	t.Helper()
+
+	changes, err := repo.ListChanges(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("ListChanges() error = %v", err)
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), changes)
+	}
+	for _, c := range changes {
+		if want[c.CourseID] != c.Kind {
+			t.Errorf("course %d: got %s, want %s", c.CourseID, c.Kind, want[c.CourseID])
+		}
+	}
+}
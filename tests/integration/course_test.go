@@ -0,0 +1,81 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/diff"
+	"github.com/LaulauChau/sws/internal/export"
+	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/store"
+)
+
+func TestCourseDetail(t *testing.T) {
+	repo, err := store.OpenSQLite(filepath.Join(t.TempDir(), "sws.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite() error = %v", err)
+	}
+	defer repo.Close()
+	web := newWebServer(t, repo)
+
+	day := time.Now().AddDate(0, 0, 2).Format("2006-01-02")
+	web.sowesign.SetCourses([]models.Course{
+		{ID: 1, Name: "Innover et entreprendre", Date: day, Start: "08:00:00+00:00", End: "12:00:00+00:00"},
+		{ID: 2, Name: "Gestion de projet", Date: day, Start: "06:00:00+00:00", End: "07:30:00+00:00"},
+	})
+	p := newChangeTracker(t, repo)
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+
+	// Move the first course and cancel the second one
+	moved := models.Course{ID: 1, Name: "Innover et entreprendre", Date: day, Start: "09:00:00+00:00", End: "12:00:00+00:00"}
+	web.sowesign.SetCourses([]models.Course{moved})
+	if err := p.Poll(); err != nil {
+		t.Fatalf("Poll() error = %v", err)
+	}
+
+	rec := web.get("/api/courses/1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/courses/1 = %d, want 200", rec.Code)
+	}
+	var detail export.Detail
+	if err := json.NewDecoder(rec.Body).Decode(&detail); err != nil {
+		t.Fatalf("failed to decode course: %v", err)
+	}
+	if !detail.Upcoming || detail.Code != export.NewRecord(moved).Code || detail.DurationMinutes != 180 {
+		t.Errorf("GET /api/courses/1 = %+v, want the upcoming moved course", detail)
+	}
+	if len(detail.Changes) != 1 || detail.Changes[0].Kind != diff.Moved || detail.FirstSeen == nil {
+		t.Errorf("GET /api/courses/1 changes = %+v, first seen %v, want one move", detail.Changes, detail.FirstSeen)
+	}
+
+	// Cancelled courses are still known from the history
+	rec = web.get("/api/courses/2")
+	detail = export.Detail{}
+	if err := json.NewDecoder(rec.Body).Decode(&detail); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/courses/2 = %d, %v", rec.Code, err)
+	}
+	if detail.Upcoming || detail.Name != "Gestion de projet" || len(detail.Changes) != 1 || detail.Changes[0].Kind != diff.Removed {
+		t.Errorf("GET /api/courses/2 = %+v, want the cancelled course", detail)
+	}
+
+	rec = web.get("/courses/1")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), export.NewRecord(moved).Code) {
+		t.Errorf("GET /courses/1 = %d, want the page with the code", rec.Code)
+	}
+
+	for path, want := range map[string]int{
+		"/api/courses/3":    http.StatusNotFound,
+		"/courses/3":        http.StatusNotFound,
+		"/api/courses/nope": http.StatusBadRequest,
+	} {
+		if rec := web.get(path); rec.Code != want {
+			t.Errorf("GET %s = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
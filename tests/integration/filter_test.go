@@ -0,0 +1,66 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/export"
+	"github.com/LaulauChau/sws/internal/models"
+)
+
+func TestCourseFilters(t *testing.T) {
+	web := newWebServer(t, nil)
+
+	today := time.Now()
+	day1, day2 := today.AddDate(0, 0, 1).Format("2006-01-02"), today.AddDate(0, 0, 2).Format("2006-01-02")
+	web.sowesign.SetCourses([]models.Course{
+		{ID: 1, Name: "Innover et entreprendre [XDEV003-CTD / 2425S10-PAR1]", Date: day1, Start: "08:00:00+00:00", End: "11:00:00+00:00"},
+		{ID: 2, Name: "Gestion de projet [XMAN001-CM / 2425S10-PAR1]", Date: day1, Start: "12:30:00+00:00", End: "15:30:00+00:00"},
+		{ID: 3, Name: "Innover et entreprendre [XDEV003-CTD / 2425S10-PAR1]", Date: day2, Start: "08:00:00+00:00", End: "11:00:00+00:00"},
+	})
+
+	var records []export.Record
+	rec := web.get("/api/courses?module=xdev003&from=" + day2)
+	if err := json.NewDecoder(rec.Body).Decode(&records); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/courses = %d, %v", rec.Code, err)
+	}
+	if len(records) != 1 || records[0].ID != 3 {
+		t.Errorf("GET /api/courses = %+v, want course 3", records)
+	}
+
+	var days []export.Day
+	rec = web.get("/api/courses?group=day&name=innover")
+	if err := json.NewDecoder(rec.Body).Decode(&days); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/courses?group=day = %d, %v", rec.Code, err)
+	}
+	if len(days) != 2 || days[0].Date != day1 || len(days[0].Courses) != 1 || days[1].Date != day2 {
+		t.Errorf("GET /api/courses?group=day = %+v, want one course on each day", days)
+	}
+
+	var modules []export.Module
+	rec = web.get("/api/courses?group=module&format=ctd")
+	if err := json.NewDecoder(rec.Body).Decode(&modules); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/courses?group=module = %d, %v", rec.Code, err)
+	}
+	if len(modules) != 1 || modules[0].Code != "XDEV003" || len(modules[0].Courses) != 2 {
+		t.Errorf("GET /api/courses?group=module = %+v, want the two XDEV003 courses", modules)
+	}
+
+	rec = web.get("/table?time=afternoon&group=day")
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, "Gestion de projet") || strings.Contains(body, "Innover") {
+		t.Errorf("GET /table = %d, want only the afternoon course", rec.Code)
+	}
+	if got, want := rec.Header().Get("HX-Replace-Url"), "/?group=day&time=afternoon"; got != want {
+		t.Errorf("GET /table HX-Replace-Url = %q, want %q", got, want)
+	}
+
+	for _, path := range []string{"/api/courses?from=demain", "/table?time=night", "/api/courses?group=week"} {
+		if rec := web.get(path); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want 400", path, rec.Code)
+		}
+	}
+}
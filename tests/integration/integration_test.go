@@ -7,9 +7,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/LaulauChau/sws/internal/account"
+	"github.com/LaulauChau/sws/internal/auth"
 	"github.com/LaulauChau/sws/internal/client"
 	"github.com/LaulauChau/sws/internal/config"
+	"github.com/LaulauChau/sws/internal/handler"
+	"github.com/LaulauChau/sws/internal/mock"
 	"github.com/LaulauChau/sws/internal/models"
+	"github.com/LaulauChau/sws/internal/store"
 )
 
 type testServer struct {
@@ -98,6 +103,51 @@ func (ts *testServer) handleCoursesRequest(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(courses)
 }
 
+// webServer serves the course pages of the test config, read from a mock
+// Sowesign server
+type webServer struct {
+	sowesign *mock.Server
+	mux      *http.ServeMux
+}
+
+// newWebServer starts a mock Sowesign server and routes the course pages to
+// a handler for it, keeping the course history in repo when it isn't nil
+func newWebServer(t *testing.T, repo store.Repository) *webServer {
+	t.Helper()
+
+	sowesign := mock.NewServer()
+	t.Cleanup(sowesign.Close)
+	client.SetBaseURLs(sowesign.URL+"/api/portal/authentication/token",
+		sowesign.URL+"/api/student-app/future-courses",
+		sowesign.URL+"/api/trainer-app/current-courses")
+
+	cfg := config.NewTestConfig()
+	authenticator, err := auth.New(cfg.Auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := handler.NewWebHandler(account.NewRegistry(account.New(cfg, repo, nil)), authenticator)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/table", h.HandleTable)
+	mux.HandleFunc("/calendar", h.HandleCalendarView)
+	mux.HandleFunc("/courses/{id}", h.HandleCourse)
+	mux.HandleFunc("/api/courses", h.HandleAPICourses)
+	mux.HandleFunc("/api/courses/{id}", h.HandleAPICourse)
+	return &webServer{sowesign: sowesign, mux: mux}
+}
+
+// serve returns the response to r
+func (s *webServer) serve(r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.mux.ServeHTTP(rec, r)
+	return rec
+}
+
+// get returns the response to a GET of path
+func (s *webServer) get(path string) *httptest.ResponseRecorder {
+	return s.serve(httptest.NewRequest(http.MethodGet, path, nil))
+}
+
 func TestEndToEndFlow(t *testing.T) {
 	// Start test server
 	ts := newTestServer()
@@ -115,7 +165,7 @@ func TestEndToEndFlow(t *testing.T) {
 		PIN:               "test-pin",
 	}
 
-	c := client.NewClient(cfg)
+	c := client.NewClient(cfg, nil)
 
 	// Test authentication flow
 	err := c.GetToken()
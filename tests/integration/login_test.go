@@ -0,0 +1,118 @@
+package integration
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LaulauChau/sws/internal/account"
+	"github.com/LaulauChau/sws/internal/auth"
+	"github.com/LaulauChau/sws/internal/client"
+	"github.com/LaulauChau/sws/internal/config"
+	"github.com/LaulauChau/sws/internal/handler"
+	"github.com/LaulauChau/sws/internal/mock"
+	"github.com/LaulauChau/sws/internal/ratelimit"
+	"github.com/LaulauChau/sws/internal/session"
+)
+
+func TestLoginRateLimit(t *testing.T) {
+	server := mock.NewServer()
+	defer server.Close()
+
+	client.SetBaseURLs(server.URL+"/api/portal/authentication/token",
+		server.URL+"/api/student-app/future-courses",
+		server.URL+"/api/trainer-app/current-courses")
+
+	cfg := config.NewTestConfig()
+	cfg.Auth.Mode = config.AuthModeSowesign
+	authenticator, err := auth.New(cfg.Auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The login form carries a CSRF token bound to a cookie
+	rec := httptest.NewRecorder()
+	token, err := authenticator.LoginCSRFToken(rec, httptest.NewRequest(http.MethodGet, "/login", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookie := rec.Result().Cookies()[0]
+
+	newHandler := func(upstream config.Rate) http.Handler {
+		h := handler.NewWebHandler(account.NewRegistry(), authenticator)
+		h.SetRateLimiter(ratelimit.NewKeyed())
+		h.SetSessions(session.NewManager(cfg, ratelimit.NewLimiter(upstream)))
+		return h.LoginRateLimit(h.HandleLogin)
+	}
+	login := func(h http.Handler, ip string) *httptest.ResponseRecorder {
+		form := url.Values{"code_etablissement": {"code"}, "identifiant": {"alice"}, "pin": {"1234"}, "csrf_token": {token}}
+		r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(cookie)
+		r.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	// Each client IP gets a few attempts
+	h := newHandler(config.Rate{})
+	for i := 0; i < 5; i++ {
+		if rec := login(h, "192.0.2.1"); rec.Code != http.StatusSeeOther {
+			t.Fatalf("POST /login #%d = %d, want 303", i+1, rec.Code)
+		}
+	}
+	rec = login(h, "192.0.2.1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("POST /login = %d with Retry-After %q, want 429 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := login(h, "192.0.2.2"); rec.Code != http.StatusSeeOther {
+		t.Errorf("POST /login from another IP = %d, want 303", rec.Code)
+	}
+
+	// Attempts from every IP count against the calls to Sowesign
+	requests := server.TokenRequests
+	h = newHandler(config.Rate{Requests: 1, Per: time.Hour, Burst: 2})
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2"} {
+		if rec := login(h, ip); rec.Code != http.StatusSeeOther {
+			t.Fatalf("POST /login from %s = %d, want 303", ip, rec.Code)
+		}
+	}
+	if rec := login(h, "192.0.2.3"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("POST /login = %d, want 429 once the upstream bucket is empty", rec.Code)
+	}
+	if got := server.TokenRequests - requests; got != 2 {
+		t.Errorf("Sowesign got %d token requests, want 2", got)
+	}
+
+	// Forms without the token of their cookie are rejected before Sowesign
+	requests = server.TokenRequests
+	h = newHandler(config.Rate{})
+	for _, tt := range []struct {
+		name   string
+		token  string
+		cookie bool
+	}{
+		{name: "no token", cookie: true},
+		{name: "no cookie", token: token},
+		{name: "another token", token: "forged", cookie: true},
+	} {
+		form := url.Values{"code_etablissement": {"code"}, "identifiant": {"alice"}, "pin": {"1234"}, "csrf_token": {tt.token}}
+		r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if tt.cookie {
+			r.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("POST /login with %s = %d, want 403", tt.name, rec.Code)
+		}
+	}
+	if server.TokenRequests != requests {
+		t.Errorf("Sowesign got %d token requests, want none", server.TokenRequests-requests)
+	}
+}
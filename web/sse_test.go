@@ -0,0 +1,78 @@
+package web
+
+import (
+	"io/fs"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// sseHarness runs js/sse.js against fake htmx, EventSource and timers, and
+// prints what the extension does
+const sseHarness = `
+var log = [];
+var timers = [];
+var sources = [];
+function setTimeout(fn, delay) { timers.push(fn); log.push("wait " + delay); return timers.length; }
+function clearTimeout(id) { if (id) { timers[id - 1] = null; } }
+function EventSource(url) { this.url = url; this.readyState = 0; sources.push(this); log.push("connect " + url); }
+EventSource.CLOSED = 2;
+EventSource.prototype.addEventListener = function () {};
+EventSource.prototype.close = function () { this.readyState = EventSource.CLOSED; log.push("close"); };
+var ext;
+var htmx = {
+	defineExtension: function (name, e) { ext = e; },
+	trigger: function (elt, name) { log.push(name); },
+};
+var elt = {
+	isConnected: true,
+	hasAttribute: function (name) { return name === "sse-connect"; },
+	getAttribute: function () { return "/events"; },
+	querySelectorAll: function () { return []; },
+};
+function last() { return sources[sources.length - 1]; }
+function fail(state) { var s = last(); s.readyState = state; s.onerror(); }
+function tick() { var fn = timers.shift(); if (fn) { fn(); } }
+
+eval(SCRIPT);
+ext.onEvent("htmx:afterProcessNode", { target: elt });
+fail(0);            // the browser reconnects by itself
+fail(2); tick();    // 1s
+fail(2); tick();    // 2s
+last().onopen();
+fail(2);            // back to 1s
+ext.onEvent("htmx:beforeCleanupElement", { target: elt });
+tick();             // the cancelled retry doesn't connect
+console.log(log.join("\n"));
+`
+
+func TestSSEReconnect(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node is not installed")
+	}
+	script, err := fs.ReadFile(Static(), "js/sse.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(node, "-e", "var SCRIPT = process.env.SCRIPT;"+sseHarness)
+	cmd.Env = append(cmd.Environ(), "SCRIPT="+string(script))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("node error = %v: %s", err, out)
+	}
+
+	want := []string{
+		"connect /events",
+		"htmx:sseError",
+		"htmx:sseError", "wait 1000", "connect /events",
+		"htmx:sseError", "wait 2000", "connect /events",
+		"htmx:sseOpen",
+		"htmx:sseError", "wait 1000",
+		"close",
+	}
+	if got := strings.TrimSpace(string(out)); got != strings.Join(want, "\n") {
+		t.Errorf("sse.js did\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
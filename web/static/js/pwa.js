@@ -0,0 +1,79 @@
+/*
+ * Registers the service worker and shows the offline banner of the layout
+ * with the time of the displayed courses, taken from the data-fetched-at
+ * attribute of the courses table. Elements triggered by sws:revalidate are
+ * reloaded when the connection comes back:
+ *
+ *   <div hx-get="/table" hx-trigger="sse:tick, sws:revalidate"></div>
+ */
+(function () {
+    "use strict";
+
+    var RETRY_INTERVAL = 30000;
+    var offline = false;
+    var retry = null;
+
+    if ("serviceWorker" in navigator) {
+        navigator.serviceWorker.register("/sw.js").catch(function () {});
+    }
+
+    function render() {
+        var banner = document.getElementById("offline-banner");
+        if (!banner) {
+            return;
+        }
+        banner.hidden = !offline;
+
+        var tables = document.querySelectorAll("[data-fetched-at]");
+        var since = banner.querySelector("[data-offline-since]");
+        var label = tables.length ? tables[tables.length - 1].getAttribute("data-fetched-at") : "";
+        since.hidden = label === "";
+        since.querySelector("time").textContent = label;
+    }
+
+    function revalidate() {
+        document.querySelectorAll("[hx-trigger*='sws:revalidate']").forEach(function (elt) {
+            htmx.trigger(elt, "sws:revalidate");
+        });
+    }
+
+    // check probes the server, the service worker never caching /healthz
+    function check() {
+        clearTimeout(retry);
+        return fetch("/healthz", { cache: "no-store" })
+            .then(function (response) {
+                return response.ok;
+            })
+            .catch(function () {
+                return false;
+            })
+            .then(function (online) {
+                var wasOffline = offline;
+                offline = !online;
+                render();
+                if (offline) {
+                    retry = setTimeout(check, RETRY_INTERVAL);
+                } else if (wasOffline) {
+                    revalidate();
+                }
+                return online;
+            });
+    }
+
+    document.addEventListener("DOMContentLoaded", function () {
+        check().then(function (online) {
+            // The cached page may be older than the last cached table
+            if (!online) {
+                revalidate();
+            }
+        });
+    });
+    window.addEventListener("online", check);
+    window.addEventListener("offline", function () {
+        offline = true;
+        render();
+        check();
+    });
+    document.addEventListener("htmx:sendError", check);
+    document.addEventListener("htmx:afterSwap", render);
+})();
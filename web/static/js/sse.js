@@ -0,0 +1,94 @@
+/*
+ * Minimal Server-Sent Events extension for htmx 2, standing in for the
+ * htmx-ext-sse package.
+ *
+ *   <div hx-ext="sse" sse-connect="/events">
+ *     <div sse-swap="courses"></div>             swaps the event data into the element
+ *     <div hx-get="/table" hx-trigger="sse:tick"> triggers a request on each event
+ *   </div>
+ *
+ * The browser reconnects by itself after a dropped connection. When it gives
+ * up, e.g. on an error response while the server restarts, the extension
+ * reconnects after 1s, doubling the delay up to a minute until a connection
+ * opens again.
+ *
+ * Unlike htmx-ext-sse, it does not support sse-close, hx-swap modifiers other
+ * than the swap style, nor sse-swap and sse: triggers on elements added
+ * inside the sse-connect element after it connected.
+ */
+(function () {
+    "use strict";
+
+    var minRetryDelay = 1000;
+    var maxRetryDelay = 60000;
+
+    function listen(source, root) {
+        root.querySelectorAll("[sse-swap]").forEach(function (elt) {
+            elt.getAttribute("sse-swap").split(",").forEach(function (name) {
+                source.addEventListener(name.trim(), function (event) {
+                    htmx.swap(elt, event.data, {
+                        swapStyle: elt.getAttribute("hx-swap") || "innerHTML",
+                    });
+                });
+            });
+        });
+
+        root.querySelectorAll("[hx-trigger*='sse:']").forEach(function (elt) {
+            var matches = elt.getAttribute("hx-trigger").match(/sse:[\w-]+/g) || [];
+            matches.forEach(function (trigger) {
+                source.addEventListener(trigger.slice(4), function () {
+                    htmx.trigger(elt, trigger);
+                });
+            });
+        });
+    }
+
+    function connect(elt) {
+        var source = new EventSource(elt.getAttribute("sse-connect"));
+        source.onerror = function () {
+            htmx.trigger(elt, "htmx:sseError", { source: source });
+            if (source.readyState === EventSource.CLOSED && elt.sseSource === source) {
+                reconnect(elt);
+            }
+        };
+        source.onopen = function () {
+            elt.sseRetries = 0;
+            htmx.trigger(elt, "htmx:sseOpen", { source: source });
+        };
+
+        elt.sseSource = source;
+        listen(source, elt);
+    }
+
+    function reconnect(elt) {
+        var delay = Math.min(minRetryDelay * Math.pow(2, elt.sseRetries || 0), maxRetryDelay);
+        elt.sseRetries = (elt.sseRetries || 0) + 1;
+        elt.sseRetry = setTimeout(function () {
+            delete elt.sseRetry;
+            if (elt.sseSource && elt.isConnected) {
+                connect(elt);
+            }
+        }, delay);
+    }
+
+    function disconnect(elt) {
+        clearTimeout(elt.sseRetry);
+        elt.sseSource.close();
+        delete elt.sseSource;
+        delete elt.sseRetry;
+    }
+
+    htmx.defineExtension("sse", {
+        onEvent: function (name, evt) {
+            var elt = evt.target || evt.detail.elt;
+
+            if (name === "htmx:afterProcessNode" && elt.hasAttribute && elt.hasAttribute("sse-connect") && !elt.sseSource) {
+                connect(elt);
+            }
+
+            if (name === "htmx:beforeCleanupElement" && elt.sseSource) {
+                disconnect(elt);
+            }
+        },
+    });
+})();
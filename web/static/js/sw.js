@@ -0,0 +1,167 @@
+/*
+ * Service worker keeping sws usable offline, served at /sw.js after the
+ * list of static files to precache:
+ *
+ *   var ASSETS = ["/static/js/htmx.min.0123456789.js", ...];
+ *
+ * Static files are served from the cache, hashed ones never change. Pages
+ * and courses table fragments come from the network and fall back to the
+ * last response cached when it is unreachable.
+ */
+"use strict";
+
+var STATIC_CACHE = "sws-static-v1";
+var PAGES_CACHE = "sws-pages-v1";
+var PAGES = ["/", "/table"];
+var HASHED = /\.[0-9a-f]{10}(\.\w+)$/;
+
+self.addEventListener("install", function (event) {
+    event.waitUntil(
+        caches.open(STATIC_CACHE).then(function (cache) {
+            // A missing file must not prevent the others from being cached
+            return Promise.all(
+                ASSETS.map(function (url) {
+                    return cache.add(url).catch(function () {});
+                })
+            );
+        })
+    );
+    self.skipWaiting();
+});
+
+self.addEventListener("activate", function (event) {
+    event.waitUntil(
+        caches
+            .keys()
+            .then(function (names) {
+                return Promise.all(
+                    names
+                        .filter(function (name) {
+                            return name !== STATIC_CACHE && name !== PAGES_CACHE;
+                        })
+                        .map(function (name) {
+                            return caches.delete(name);
+                        })
+                );
+            })
+            .then(pruneStatic)
+            .then(function () {
+                return self.clients.claim();
+            })
+    );
+});
+
+self.addEventListener("fetch", function (event) {
+    var request = event.request;
+    var url = new URL(request.url);
+    if (url.origin !== self.location.origin) {
+        return;
+    }
+
+    // Courses of a signed out user must not stay on the device
+    if (request.method === "POST" && url.pathname === "/logout") {
+        event.waitUntil(caches.delete(PAGES_CACHE));
+        return;
+    }
+    if (request.method !== "GET") {
+        return;
+    }
+
+    if (url.pathname.startsWith("/static/")) {
+        event.respondWith(HASHED.test(url.pathname) ? cacheFirst(request) : staleWhileRevalidate(request));
+    } else if (PAGES.indexOf(url.pathname) >= 0) {
+        event.respondWith(networkFirst(request));
+    }
+});
+
+// pruneStatic removes the static files of previous versions
+function pruneStatic() {
+    if (ASSETS.length === 0) {
+        return Promise.resolve();
+    }
+    return caches.open(STATIC_CACHE).then(function (cache) {
+        return cache.keys().then(function (requests) {
+            return Promise.all(
+                requests
+                    .filter(function (request) {
+                        var path = new URL(request.url).pathname;
+                        return HASHED.test(path) && ASSETS.indexOf(path) < 0;
+                    })
+                    .map(function (request) {
+                        return cache.delete(request);
+                    })
+            );
+        });
+    });
+}
+
+function cacheFirst(request) {
+    return caches.open(STATIC_CACHE).then(function (cache) {
+        return cache.match(request).then(function (cached) {
+            return (
+                cached ||
+                fetch(request).then(function (response) {
+                    if (response.ok) {
+                        cache.put(request, response.clone());
+                    }
+                    return response;
+                })
+            );
+        });
+    });
+}
+
+function staleWhileRevalidate(request) {
+    return caches.open(STATIC_CACHE).then(function (cache) {
+        return cache.match(request).then(function (cached) {
+            var fetched = fetch(request)
+                .then(function (response) {
+                    if (response.ok) {
+                        cache.put(request, response.clone());
+                    }
+                    return response;
+                })
+                .catch(function (error) {
+                    if (cached) {
+                        return cached;
+                    }
+                    throw error;
+                });
+            return cached || fetched;
+        });
+    });
+}
+
+// networkFirst keeps the last successful response of a page, signed out
+// users being redirected to the login page drop every cached page
+function networkFirst(request) {
+    return caches.open(PAGES_CACHE).then(function (cache) {
+        return fetch(request)
+            .then(function (response) {
+                if (response.redirected || response.headers.has("HX-Redirect")) {
+                    caches.delete(PAGES_CACHE);
+                } else if (response.status === 200) {
+                    cache.put(request, response.clone());
+                }
+                return response;
+            })
+            .catch(function () {
+                return cache.match(request).then(function (cached) {
+                    return cached || offline(request);
+                });
+            });
+    });
+}
+
+// offline answers a request that was never cached, htmx leaves the page
+// untouched on 503
+function offline(request) {
+    var body =
+        request.mode === "navigate"
+            ? '<!DOCTYPE html><html lang="fr"><meta charset="UTF-8"><title>Hors ligne</title><p>Hors ligne, aucune donnée enregistrée.</p></html>'
+            : "";
+    return new Response(body, {
+        status: 503,
+        headers: { "Content-Type": "text/html; charset=utf-8" },
+    });
+}
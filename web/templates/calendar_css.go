@@ -0,0 +1,41 @@
+package templates
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// slotHeight is the height of a slot of the week calendar in rem
+const slotHeight = 0.25
+
+// CalendarCSS returns css/calendar.css, which defines every class placing
+// the week calendar, generated by go generate
+func CalendarCSS() string {
+	var b strings.Builder
+	b.WriteString(`/* Code generated by go generate ./web/templates; DO NOT EDIT.
+
+   Placement of the week calendar on a grid of 5 minute slots, 0.25rem high
+   so 3rem an hour, for the classes of web/templates/helpers.go. The calendar
+   needs no inline style, which the Content-Security-Policy blocks */
+.cal-block { left: calc(100% * var(--cal-lane, 0) / var(--cal-lanes, 1)); width: calc(100% / var(--cal-lanes, 1)); }
+`)
+	for lane := 0; lane < maxLanes; lane++ {
+		fmt.Fprintf(&b, ".cal-lane-%d { --cal-lane: %d; }\n", lane, lane)
+	}
+	for lanes := 1; lanes <= maxLanes; lanes++ {
+		fmt.Fprintf(&b, ".cal-lanes-%d { --cal-lanes: %d; }\n", lanes, lanes)
+	}
+	for slot := 0; slot <= maxSlots; slot++ {
+		fmt.Fprintf(&b, ".cal-top-%d { top: %srem; }\n", slot, rem(slot))
+	}
+	for slot := 1; slot <= maxSlots; slot++ {
+		fmt.Fprintf(&b, ".cal-h-%d { height: %srem; }\n", slot, rem(slot))
+	}
+	return b.String()
+}
+
+// rem formats the height of a number of slots
+func rem(slots int) string {
+	return strconv.FormatFloat(float64(slots)*slotHeight, 'f', -1, 64)
+}
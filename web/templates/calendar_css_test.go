@@ -0,0 +1,54 @@
+package templates
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/LaulauChau/sws/internal/calendar"
+)
+
+func TestCalendarCSS_UpToDate(t *testing.T) {
+	got, err := os.ReadFile("../static/css/calendar.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != CalendarCSS() {
+		t.Error("css/calendar.css is out of date, run go generate ./web/templates")
+	}
+}
+
+func TestCalendarCSS_Classes(t *testing.T) {
+	defined := map[string]bool{}
+	for _, m := range regexp.MustCompile(`(?m)^\.(cal-[\w-]+) `).FindAllStringSubmatch(CalendarCSS(), -1) {
+		defined[m[1]] = true
+	}
+	check := func(name, classes string) {
+		t.Helper()
+		for _, class := range strings.Fields(classes) {
+			if !defined[class] {
+				t.Errorf("%s = %q, %s is not in css/calendar.css", name, classes, class)
+			}
+		}
+	}
+
+	for hours := 1; hours <= 24; hours++ {
+		week := calendar.Week{FirstHour: 0, LastHour: hours - 1}
+		check("hoursClass()", hoursClass(week))
+		for i := range week.Hours() {
+			check("hourClass()", hourClass(i))
+		}
+		for top := 0.0; top <= 100; top++ {
+			check("topClass()", topClass(week, top))
+			for _, height := range []float64{0, 0.1, 1, 100 - top} {
+				for lanes := 0; lanes <= maxLanes+2; lanes++ {
+					for lane := 0; lane < max(lanes, 1); lane++ {
+						block := calendar.Block{Top: top, Height: height, Lane: lane, Lanes: lanes}
+						check("blockClass()", blockClass(week, block))
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,21 @@
+// Command calendarcss writes the stylesheet placing the week calendar to the
+// path given as argument, run by go generate ./web/templates
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/LaulauChau/sws/web/templates"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: calendarcss <path>")
+		os.Exit(2)
+	}
+	if err := os.WriteFile(os.Args[1], []byte(templates.CalendarCSS()), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing calendar stylesheet: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,105 @@
+package templates
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type profilesKey struct{}
+
+// profiles describes the configured profiles and the one being displayed
+type profiles struct {
+	names   []string
+	current string
+}
+
+// WithProfiles stores the profile names and the selected profile in the
+// context, so that the layout can render the profile switcher
+func WithProfiles(ctx context.Context, names []string, current string) context.Context {
+	return context.WithValue(ctx, profilesKey{}, profiles{names: names, current: current})
+}
+
+// profilesFromContext returns the profiles stored by WithProfiles
+func profilesFromContext(ctx context.Context) profiles {
+	p, _ := ctx.Value(profilesKey{}).(profiles)
+	return p
+}
+
+type userKey struct{}
+
+// user describes the logged in user of a page
+type user struct {
+	name      string
+	csrfToken string
+}
+
+// WithUser stores the logged in user and the CSRF token of their session in
+// the context, so that the layout can render the logout button and send the
+// token with htmx requests
+func WithUser(ctx context.Context, name, csrfToken string) context.Context {
+	return context.WithValue(ctx, userKey{}, user{name: name, csrfToken: csrfToken})
+}
+
+// userFromContext returns the user stored by WithUser
+func userFromContext(ctx context.Context) user {
+	u, _ := ctx.Value(userKey{}).(user)
+	return u
+}
+
+// csrfHeaders returns the hx-headers value sending the CSRF token with every
+// htmx request of the page
+func csrfHeaders(ctx context.Context) string {
+	token := userFromContext(ctx).csrfToken
+	if token == "" {
+		return ""
+	}
+	headers, _ := json.Marshal(map[string]string{"X-CSRF-Token": token})
+	return string(headers)
+}
+
+type noHistoryKey struct{}
+
+// WithoutHistory hides the links to the history pages, for accounts whose
+// courses are not recorded
+func WithoutHistory(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noHistoryKey{}, true)
+}
+
+// historyEnabled reports whether the history pages are available
+func historyEnabled(ctx context.Context) bool {
+	hidden, _ := ctx.Value(noHistoryKey{}).(bool)
+	return !hidden
+}
+
+type fetchedAtKey struct{}
+
+// WithFetchedAt stores when the displayed courses were fetched from
+// Sowesign, shown by the offline banner
+func WithFetchedAt(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, fetchedAtKey{}, t)
+}
+
+// fetchedAtLabel returns when the displayed courses were fetched, as HH:MM
+// on the same day, empty when unknown
+func fetchedAtLabel(ctx context.Context) string {
+	t, _ := ctx.Value(fetchedAtKey{}).(time.Time)
+	if t.IsZero() {
+		return ""
+	}
+	if formatDate(t) == formatDate(time.Now()) {
+		return formatTime(t)
+	}
+	return formatDateTime(t)
+}
+
+// assetURL returns the URL of a static file, see SetAssetURL
+var assetURL = func(name string) string {
+	return "/static/" + name
+}
+
+// SetAssetURL sets how the URL of a static file is built, e.g. with its
+// content hash. It must be called before serving pages
+func SetAssetURL(fn func(name string) string) {
+	assetURL = fn
+}
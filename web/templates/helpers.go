@@ -1,6 +1,20 @@
 package templates
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+
+	"github.com/LaulauChau/sws/internal/calendar"
+	"github.com/LaulauChau/sws/internal/diff"
+	"github.com/LaulauChau/sws/internal/filter"
 	"github.com/LaulauChau/sws/internal/models"
 	"github.com/LaulauChau/sws/internal/service"
 )
@@ -9,3 +23,228 @@ func generateCode(course models.Course) string {
 	_, _, _, code := service.GenerateFixedCode(course)
 	return code
 }
+
+func rowClass(course models.Course) string {
+	if service.GetCourseStatus(course, time.Now()) == service.StatusInProgress {
+		return "bg-green-50 hover:bg-green-100"
+	}
+	return "hover:bg-gray-50"
+}
+
+func courseStatus(course models.Course) string {
+	now := time.Now()
+	switch service.GetCourseStatus(course, now) {
+	case service.StatusUpcoming:
+		return "dans " + formatDuration(service.CourseStart(course).Sub(now))
+	case service.StatusInProgress:
+		return "en cours"
+	case service.StatusFinished:
+		return "terminé"
+	default:
+		return ""
+	}
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(service.ParisLocation()).Format("02/01/2006")
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(service.ParisLocation()).Format("15:04")
+}
+
+func formatDateTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(service.ParisLocation()).Format("02/01/2006 15:04")
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%d j %d h", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%d h %02d min", hours, minutes)
+	default:
+		return fmt.Sprintf("%d min", minutes)
+	}
+}
+
+func formatSlot(course models.Course) string {
+	start, end := service.CourseStart(course), service.CourseEnd(course)
+	if start.IsZero() {
+		return ""
+	}
+	if end.IsZero() {
+		return formatDateTime(start)
+	}
+	return formatDateTime(start) + " – " + formatTime(end)
+}
+
+func changeLabel(kind diff.Kind) string {
+	switch kind {
+	case diff.Added:
+		return "Nouveau"
+	case diff.Removed:
+		return "Annulé"
+	case diff.Moved:
+		return "Déplacé"
+	case diff.Renamed:
+		return "Renommé"
+	default:
+		return string(kind)
+	}
+}
+
+func changeBadgeClass(kind diff.Kind) string {
+	switch kind {
+	case diff.Added:
+		return "bg-green-100 text-green-800"
+	case diff.Removed:
+		return "bg-red-100 text-red-800"
+	case diff.Moved:
+		return "bg-yellow-100 text-yellow-800"
+	default:
+		return "bg-gray-100 text-gray-800"
+	}
+}
+
+// courseURL returns the URL of the page of a course
+func courseURL(id int) templ.SafeURL {
+	return templ.SafeURL("/courses/" + strconv.Itoa(id))
+}
+
+// extraValue formats a field Sowesign sent, strings without their quotes and
+// anything else as compact JSON
+func extraValue(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
+
+// dayLabel formats the header of a day in the courses table, e.g.
+// "Lundi 10 février 2025"
+func dayLabel(date time.Time) string {
+	if date.IsZero() {
+		return "Date inconnue"
+	}
+	label := service.FormatLongDate(date)
+	return strings.ToUpper(label[:1]) + label[1:]
+}
+
+// dateValue formats a date for a date input, empty for the zero time
+func dateValue(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// moduleLabel formats the header of a module in the courses table
+func moduleLabel(module filter.Module) string {
+	if module.Code == "" {
+		return "Sans module"
+	}
+	return module.Code + " – " + module.Title
+}
+
+//go:generate go run ./calendarcss ../static/css/calendar.css
+
+// slotsPerHour splits the hours of the week calendar into the 5 minute
+// slots of css/calendar.css
+const slotsPerHour = 12
+
+// maxSlots is the number of slots of a week showing every hour of the day
+const maxSlots = 24 * slotsPerHour
+
+// maxLanes is the number of overlapping courses css/calendar.css can place
+// side by side, more share the last lane
+const maxLanes = 12
+
+// calendarURL returns the URL of the calendar showing the day of date, today
+// for the zero time, keeping the query parameters of base
+func calendarURL(base url.Values, view string, date time.Time) templ.SafeURL {
+	values := url.Values{}
+	for key, value := range base {
+		values[key] = value
+	}
+	values.Set("view", view)
+	if !date.IsZero() {
+		values.Set("date", date.Format("2006-01-02"))
+	}
+	return templ.SafeURL("/calendar?" + values.Encode())
+}
+
+// hoursClass returns the class giving a column of the week the height of
+// its hours
+func hoursClass(week calendar.Week) string {
+	return "cal-h-" + strconv.Itoa(weekSlots(week))
+}
+
+// hourClass returns the class placing the i-th hour shown by the week
+func hourClass(i int) string {
+	return "cal-top-" + strconv.Itoa(i*slotsPerHour)
+}
+
+// topClass returns the class placing an element at a percentage of the
+// height of its column
+func topClass(week calendar.Week, top float64) string {
+	return "cal-top-" + strconv.Itoa(slot(week, top))
+}
+
+// blockClass returns the classes placing a course in its day column
+func blockClass(week calendar.Week, block calendar.Block) string {
+	lanes := min(max(block.Lanes, 1), maxLanes)
+	top := slot(week, block.Top)
+	height := max(slot(week, block.Top+block.Height)-top, 1)
+	return fmt.Sprintf("cal-block cal-top-%d cal-h-%d cal-lane-%d cal-lanes-%d", top, height, min(block.Lane, lanes-1), lanes)
+}
+
+// weekSlots returns the number of slots of the hours shown by the week
+func weekSlots(week calendar.Week) int {
+	return len(week.Hours()) * slotsPerHour
+}
+
+// slot returns the slot at a percentage of the height of the week's hours
+func slot(week calendar.Week, percent float64) int {
+	return int(math.Round(percent / 100 * float64(weekSlots(week))))
+}
+
+// weekdayShort returns the abbreviated French day of the week, e.g. "lun."
+func weekdayShort(date time.Time) string {
+	return service.FrenchWeekday(date)[:3] + "."
+}
+
+// weekdayLabel formats the header of a day of the week, e.g. "lun. 10"
+func weekdayLabel(date time.Time) string {
+	return weekdayShort(date) + " " + strconv.Itoa(date.Day())
+}
+
+// weekLabel formats the title of a week, e.g. "Semaine du 10 février 2025"
+func weekLabel(week calendar.Week) string {
+	return fmt.Sprintf("Semaine du %d %s %d", week.Start.Day(), service.FrenchMonth(week.Start), week.Start.Year())
+}
+
+// monthLabel formats the title of a month, e.g. "Février 2025"
+func monthLabel(date time.Time) string {
+	month := service.FrenchMonth(date)
+	return strings.ToUpper(month[:1]) + month[1:] + " " + strconv.Itoa(date.Year())
+}
@@ -0,0 +1,24 @@
+// Package web embeds the static files served by sws, so that the binary
+// runs from any working directory. The templates are compiled by templ
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// StaticDir is where the static files are read from in dev mode
+const StaticDir = "web/static"
+
+//go:embed static
+var static embed.FS
+
+// Static returns the embedded static files, css/output.css included when
+// it was built before the binary
+func Static() fs.FS {
+	sub, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}